@@ -7,7 +7,11 @@ package benchfmt
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"golang.org/x/perf/v2/benchfmt/internal/bytesconv"
 )
 
 // An Extractor returns some component of a benchmark result. The
@@ -29,7 +33,27 @@ type Extractor func(*Result) []byte
 // - "/{key}" for a benchmark name key. This may be "/gomaxprocs" and
 // the extractor will normalize the name as needed.
 //
+// - ".gomaxprocs" for the GOMAXPROCS of the benchmark. This is like
+// "/gomaxprocs", except that if the benchmark name does not specify
+// GOMAXPROCS, it returns the implicit value "1", since the testing
+// package omits the "-N" suffix when GOMAXPROCS is 1.
+//
+// - ".parallel" for whether the benchmark ran with GOMAXPROCS greater
+// than 1, as determined by ".gomaxprocs". This is "true" or "false".
+//
+// - ".suspicious" for whether the benchmark result is implausible
+// according to DefaultSuspicionRule as of when the Extractor was
+// created. This is "true" or "false".
+//
+// - ".totaltime" for the total running time of the benchmark in
+// seconds, as computed by Result.TotalTime. This is missing if the
+// result has no "sec/op" or "ns/op" value.
+//
 // - Any other string is a file configuration key.
+//
+// If key is not present in a Result, the extractor returns nil. If a
+// name key is present with an empty value, the extractor returns a
+// non-nil empty slice, so callers can distinguish these cases.
 func NewExtractor(key string) (Extractor, error) {
 	if len(key) == 0 {
 		return nil, fmt.Errorf("key must not be empty")
@@ -42,12 +66,20 @@ func NewExtractor(key string) (Extractor, error) {
 	case key == ".fullname":
 		return extractFull, nil
 
+	case key == ".gomaxprocs":
+		return extractGomaxprocs, nil
+
+	case key == ".parallel":
+		return extractParallel, nil
+
+	case key == ".suspicious":
+		return NewExtractorSuspicious(DefaultSuspicionRule), nil
+
+	case key == ".totaltime":
+		return extractTotalTime, nil
+
 	case strings.HasPrefix(key, "/"):
-		// Construct the byte prefix to search for.
-		prefix := make([]byte, len(key)+1)
-		copy(prefix, key)
-		prefix[len(prefix)-1] = '='
-		isGomaxprocs := key == "/gomaxprocs"
+		prefix, isGomaxprocs := namePartPrefix(key)
 		return func(res *Result) []byte {
 			return extractNamePart(res, prefix, isGomaxprocs)
 		}, nil
@@ -58,6 +90,52 @@ func NewExtractor(key string) (Extractor, error) {
 	}, nil
 }
 
+// A NameExtractor is like an Extractor, but extracts a component of a
+// benchmark name from the base name and sub-benchmark parts returned
+// by NameParts. This lets a caller that extracts several name keys
+// from the same Result split its name only once.
+type NameExtractor func(base []byte, parts [][]byte) []byte
+
+// NewNameExtractor returns a NameExtractor for key if key is one of
+// the keys accepted by NewExtractor that depends only on the benchmark
+// name: ".name", ".gomaxprocs", ".parallel", or "/{key}". For any
+// other key, it returns nil, false. The NameExtractor returns the same
+// value as the Extractor returned by NewExtractor(key).
+func NewNameExtractor(key string) (NameExtractor, bool) {
+	switch {
+	case key == ".name":
+		return func(base []byte, parts [][]byte) []byte {
+			return base
+		}, true
+
+	case key == ".gomaxprocs":
+		return func(base []byte, parts [][]byte) []byte {
+			return gomaxprocsOf(parts)
+		}, true
+
+	case key == ".parallel":
+		return func(base []byte, parts [][]byte) []byte {
+			return parallelOf(gomaxprocsOf(parts))
+		}, true
+
+	case strings.HasPrefix(key, "/"):
+		prefix, isGomaxprocs := namePartPrefix(key)
+		return func(base []byte, parts [][]byte) []byte {
+			return namePartValue(parts, prefix, isGomaxprocs)
+		}, true
+	}
+	return nil, false
+}
+
+// namePartPrefix returns the prefix of the sub-benchmark name part for
+// name key key, which must start with "/".
+func namePartPrefix(key string) (prefix []byte, isGomaxprocs bool) {
+	prefix = make([]byte, len(key)+1)
+	copy(prefix, key)
+	prefix[len(prefix)-1] = '='
+	return prefix, key == "/gomaxprocs"
+}
+
 // NewExtractorFullName returns an extractor for the full name of a
 // benchmark, but optionally with the base name or name configuration
 // keys excluded. Any excluded name configuration keys will be
@@ -91,6 +169,59 @@ func NewExtractorFullName(exclude []string) Extractor {
 	}
 }
 
+// NewExtractorFullNameSorted returns an extractor for the full name
+// of a benchmark with its name configuration keys sorted into a
+// canonical order. This way, benchmarks such as "Foo/a=1/b=2" and
+// "Foo/b=2/a=1", which are logically the same configuration, extract
+// the same value. Positional name parts and the GOMAXPROCS suffix stay
+// where they are; only the "/key=value" parts are reordered among
+// themselves. Parts with the same key keep their relative order.
+func NewExtractorFullNameSorted() Extractor {
+	return extractFullSorted
+}
+
+// NewExtractorBool returns an extractor for key that normalizes
+// boolean values to "true" or "false", so results group by the
+// logical value regardless of how it was spelled. It recognizes "1",
+// "t", "true", "y", "yes", and "on" as true, and "0", "f", "false",
+// "n", "no", and "off" as false, ignoring case. key may be any key
+// accepted by NewExtractor.
+//
+// If key is not present in a Result, the extractor returns nil. If
+// key's value is not a recognized boolean, it returns a non-nil empty
+// slice.
+func NewExtractorBool(key string) (Extractor, error) {
+	ext, err := NewExtractor(key)
+	if err != nil {
+		return nil, err
+	}
+	return func(res *Result) []byte {
+		val := ext(res)
+		if val == nil {
+			return nil
+		}
+		for _, b := range boolSpellings {
+			if bytes.EqualFold(val, b.spelling) {
+				return b.val
+			}
+		}
+		return boolUnknown
+	}, nil
+}
+
+var boolSpellings = []struct {
+	spelling, val []byte
+}{
+	{[]byte("1"), boolTrue}, {[]byte("t"), boolTrue}, {[]byte("true"), boolTrue},
+	{[]byte("y"), boolTrue}, {[]byte("yes"), boolTrue}, {[]byte("on"), boolTrue},
+	{[]byte("0"), boolFalse}, {[]byte("f"), boolFalse}, {[]byte("false"), boolFalse},
+	{[]byte("n"), boolFalse}, {[]byte("no"), boolFalse}, {[]byte("off"), boolFalse},
+}
+
+var boolTrue = []byte("true")
+var boolFalse = []byte("false")
+var boolUnknown = []byte{}
+
 func extractName(res *Result) []byte {
 	return BaseName(res.FullName)
 }
@@ -146,8 +277,89 @@ outer:
 	return newName
 }
 
+func extractFullSorted(res *Result) []byte {
+	base, parts := NameParts(res.FullName)
+	// Collect the indexes of the key/value parts.
+	var keyed []int
+	for i, part := range parts {
+		if part[0] == '/' && bytes.IndexByte(part, '=') >= 0 {
+			keyed = append(keyed, i)
+		}
+	}
+	partKey := func(part []byte) []byte {
+		return part[:bytes.IndexByte(part, '=')]
+	}
+	sorted := sort.SliceIsSorted(keyed, func(i, j int) bool {
+		return bytes.Compare(partKey(parts[keyed[i]]), partKey(parts[keyed[j]])) < 0
+	})
+	if sorted {
+		// No need to transform name.
+		return res.FullName
+	}
+
+	kvs := make([][]byte, len(keyed))
+	for i, idx := range keyed {
+		kvs[i] = parts[idx]
+	}
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return bytes.Compare(partKey(kvs[i]), partKey(kvs[j])) < 0
+	})
+	for i, idx := range keyed {
+		parts[idx] = kvs[i]
+	}
+
+	newName := append([]byte(nil), base...)
+	for _, part := range parts {
+		newName = append(newName, part...)
+	}
+	return newName
+}
+
+var gomaxprocsPrefix = []byte("/gomaxprocs=")
+var gomaxprocsDefault = []byte("1")
+
+func extractGomaxprocs(res *Result) []byte {
+	_, parts := NameParts(res.FullName)
+	return gomaxprocsOf(parts)
+}
+
+func gomaxprocsOf(parts [][]byte) []byte {
+	val := namePartValue(parts, gomaxprocsPrefix, true)
+	if val == nil {
+		return gomaxprocsDefault
+	}
+	return val
+}
+
+var parallelTrue = []byte("true")
+var parallelFalse = []byte("false")
+
+func extractParallel(res *Result) []byte {
+	return parallelOf(extractGomaxprocs(res))
+}
+
+func parallelOf(gomaxprocs []byte) []byte {
+	n, err := bytesconv.Atoi(gomaxprocs)
+	if err == nil && n > 1 {
+		return parallelTrue
+	}
+	return parallelFalse
+}
+
+func extractTotalTime(res *Result) []byte {
+	total, ok := res.TotalTime()
+	if !ok {
+		return nil
+	}
+	return strconv.AppendFloat(nil, total, 'g', -1, 64)
+}
+
 func extractNamePart(res *Result, prefix []byte, isGomaxprocs bool) []byte {
 	_, parts := NameParts(res.FullName)
+	return namePartValue(parts, prefix, isGomaxprocs)
+}
+
+func namePartValue(parts [][]byte, prefix []byte, isGomaxprocs bool) []byte {
 	if isGomaxprocs && len(parts) > 0 {
 		last := parts[len(parts)-1]
 		if last[0] == '-' {
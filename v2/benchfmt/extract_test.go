@@ -70,6 +70,22 @@ func TestExtractFullName(t *testing.T) {
 	})
 }
 
+func TestExtractFullNameSorted(t *testing.T) {
+	check := checkNameExtractor
+
+	x := NewExtractorFullNameSorted()
+	check(t, x, "Test", "Test")
+	check(t, x, "Test-4", "Test-4")
+	check(t, x, "Test/a=1/b=2", "Test/a=1/b=2")
+	check(t, x, "Test/b=2/a=1", "Test/a=1/b=2")
+	check(t, x, "Test/b=2/a=1-4", "Test/a=1/b=2-4")
+	// Duplicate keys keep their order.
+	check(t, x, "Test/b=2/a=2/a=1", "Test/a=2/a=1/b=2")
+	// Positional parts stay in place.
+	check(t, x, "Test/x/b=2/y/a=1", "Test/x/a=1/y/b=2")
+	check(t, x, "Test/y/x", "Test/y/x")
+}
+
 func TestExtractNameKey(t *testing.T) {
 	check := checkNameExtractor
 
@@ -96,6 +112,31 @@ func TestExtractNameKey(t *testing.T) {
 		check(t, x, "Test-4", "4")
 		check(t, x, "Test/a-4", "4")
 	})
+
+	t.Run(".gomaxprocs", func(t *testing.T) {
+		x, err := NewExtractor(".gomaxprocs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, x, "Test", "1")
+		check(t, x, "Test/a=2", "1")
+		check(t, x, "Test/gomaxprocs=4", "4")
+		check(t, x, "Test-4", "4")
+		check(t, x, "Test/a-4", "4")
+	})
+
+	t.Run(".parallel", func(t *testing.T) {
+		x, err := NewExtractor(".parallel")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, x, "Test", "false")
+		check(t, x, "Test-1", "false")
+		check(t, x, "Test/gomaxprocs=1", "false")
+		check(t, x, "Test-2", "true")
+		check(t, x, "Test/a=2-16", "true")
+		check(t, x, "Test/gomaxprocs=4", "true")
+	})
 }
 
 func TestExtractFileKey(t *testing.T) {
@@ -129,3 +170,106 @@ func TestExtractBadKey(t *testing.T) {
 	_, err := NewExtractor("")
 	check(t, err, "key must not be empty")
 }
+
+func TestExtractMissing(t *testing.T) {
+	x, err := NewExtractor("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := x(&Result{FullName: []byte("Test")}); got != nil {
+		t.Errorf("missing key: got %q, want nil", got)
+	}
+	if got := x(&Result{FullName: []byte("Test/a=")}); got == nil || len(got) != 0 {
+		t.Errorf("empty key: got %#v, want non-nil empty slice", got)
+	}
+	if got := x(&Result{FullName: []byte("Test/a=/b=1")}); got == nil || len(got) != 0 {
+		t.Errorf("empty key: got %#v, want non-nil empty slice", got)
+	}
+}
+
+func TestExtractTotalTime(t *testing.T) {
+	x, err := NewExtractor(".totaltime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(res *Result, want string) {
+		t.Helper()
+		if got := x(res); string(got) != want {
+			t.Errorf("%s %d %v: got %q, want %q", res.FullName, res.Iters, res.Values, got, want)
+		}
+	}
+	check(r(nil, "Ns", 20000000, []Value{{Value: 50, Unit: "ns/op"}}), "1")
+	check(r(nil, "Sec", 4, []Value{{Value: 16, Unit: "B/op"}, {Value: 0.25, Unit: "sec/op"}}), "1")
+	// ns/op takes precedence over sec/op.
+	check(r(nil, "Both", 2, []Value{{Value: 3e9, Unit: "ns/op"}, {Value: 1.5, Unit: "sec/op"}}), "6")
+	check(r(nil, "Fraction", 3, []Value{{Value: 0.5, Unit: "sec/op"}}), "1.5")
+
+	noTime := r(nil, "NoTime", 100, []Value{{Value: 16, Unit: "B/op"}})
+	if got := x(noTime); got != nil {
+		t.Errorf("no time unit: got %q, want nil", got)
+	}
+}
+
+func TestExtractBool(t *testing.T) {
+	x, err := NewExtractorBool("flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(val string, want string) {
+		t.Helper()
+		var res Result
+		res.SetFileConfig("flag", val)
+		if got := x(&res); got == nil || string(got) != want {
+			t.Errorf("%q: got %q, want %q", val, got, want)
+		}
+	}
+	for _, val := range []string{"true", "TRUE", "1", "yes", "On", "t", "y"} {
+		check(val, "true")
+	}
+	for _, val := range []string{"false", "False", "0", "no", "OFF", "f", "n"} {
+		check(val, "false")
+	}
+	for _, val := range []string{"2", "maybe", "enabled", "yes please"} {
+		check(val, "")
+	}
+	if got := x(&Result{}); got != nil {
+		t.Errorf("missing key: got %q, want nil", got)
+	}
+
+	// Name keys work, too.
+	x, err = NewExtractorBool("/opt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNameExtractor(t, x, "Test/opt=on", "true")
+
+	if _, err := NewExtractorBool(""); err == nil {
+		t.Errorf("empty key: want error")
+	}
+}
+
+func TestNameExtractor(t *testing.T) {
+	names := []string{"Test", "Test-4", "Test/a=1", "Test/a=1/b=-2-8", "Test/a", "Test/gomaxprocs=16", "Test/a=/b=2"}
+	for _, key := range []string{".name", ".gomaxprocs", ".parallel", "/a", "/b", "/gomaxprocs", "/missing"} {
+		ext, err := NewExtractor(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nameExt, ok := NewNameExtractor(key)
+		if !ok {
+			t.Fatalf("%s: want NameExtractor", key)
+		}
+		for _, name := range names {
+			want := ext(&Result{FullName: []byte(name)})
+			got := nameExt(NameParts([]byte(name)))
+			if string(got) != string(want) || (got == nil) != (want == nil) {
+				t.Errorf("%s of %s: want %q, got %q", key, name, want, got)
+			}
+		}
+	}
+	for _, key := range []string{".fullname", ".suspicious", "goos"} {
+		if _, ok := NewNameExtractor(key); ok {
+			t.Errorf("%s: want no NameExtractor", key)
+		}
+	}
+}
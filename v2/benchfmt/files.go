@@ -4,7 +4,11 @@
 
 package benchfmt
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"sync"
+)
 
 // Files reads benchmark results from a sequence of input files.
 //
@@ -23,6 +27,47 @@ type Files struct {
 	// comes from command-line flags.
 	AllowStdin bool
 
+	// BaseConfig is an alternating sequence of file configuration
+	// keys and values to add to every Result, for example, to
+	// label results with information that isn't recorded in the
+	// files themselves. These are installed as the initial
+	// file-level configuration of each file, so configuration
+	// lines in a file can override them. BaseConfig must have an
+	// even length and must not set the keys that Files sets
+	// itself, ".file", RoleKey, or RunKey; otherwise, Scan and
+	// ScanParallel fail with an error.
+	BaseConfig []string
+
+	// RoleKey, if non-empty, is a file configuration key in which
+	// to record the role of each file in a comparison: "baseline"
+	// for the first file and "experiment" for every other file.
+	// This is conventionally ".role". This makes it easy to
+	// compare a baseline file against other files by projecting
+	// on RoleKey, without adding configuration to the files.
+	RoleKey string
+
+	// RunKey, if non-empty, is a file configuration key in which
+	// to record the index of the run of each result within its
+	// file. See Reader.RunKey.
+	RunKey string
+
+	// MaxLineLength, if positive, is the maximum length in bytes of
+	// a line in any file. See Reader.MaxLineLength.
+	MaxLineLength int
+
+	// FieldSeparator, if non-zero, is an additional separator
+	// between the fields of benchmark result lines. See
+	// Reader.FieldSeparator.
+	FieldSeparator byte
+
+	// Uncertainty indicates that measurements may be followed by
+	// an uncertainty field. See Reader.Uncertainty.
+	Uncertainty bool
+
+	// ConfigOnly indicates that only the file configuration of
+	// each file should be read. See Reader.ConfigOnly.
+	ConfigOnly bool
+
 	// pos is the position of the next file to read from in Paths
 	// when the current file is exhausted.
 	pos int
@@ -44,6 +89,13 @@ func (f *Files) Scan() bool {
 		return false
 	}
 
+	if f.pos == 0 && f.file == nil {
+		if err := f.checkBaseConfig(); err != nil {
+			f.err = err
+			return false
+		}
+	}
+
 	for {
 		if f.file == nil {
 			// Open the next file.
@@ -58,22 +110,13 @@ func (f *Files) Scan() bool {
 			}
 			f.pos++
 			f.path = path
-			if f.AllowStdin && path == "-" {
-				f.isStdin, f.file = true, os.Stdin
-			} else {
-				file, err := os.Open(path)
-				if err != nil {
-					f.err = err
-					return false
-				}
-				f.isStdin, f.file = false, file
+			file, isStdin, err := f.open(path)
+			if err != nil {
+				f.err = err
+				return false
 			}
-
-			// Prepare the reader. Because ".file" is not
-			// valid syntax for file configuration keys in
-			// the file itself, there's no danger if it
-			// being overwritten.
-			f.reader.Reset(f.file, path, ".file", path)
+			f.isStdin, f.file = isStdin, file
+			f.resetReader(&f.reader, file, path, f.pos-1)
 		}
 
 		// Try to get the next result.
@@ -95,6 +138,144 @@ func (f *Files) Scan() bool {
 	return false
 }
 
+// checkBaseConfig returns an error if f.BaseConfig is malformed or
+// would override a key set by f itself.
+func (f *Files) checkBaseConfig() error {
+	if len(f.BaseConfig)%2 != 0 {
+		return fmt.Errorf("BaseConfig has odd length %d; it must alternate keys and values", len(f.BaseConfig))
+	}
+	for i := 0; i < len(f.BaseConfig); i += 2 {
+		key := f.BaseConfig[i]
+		if key == ".file" || (key != "" && (key == f.RoleKey || key == f.RunKey)) {
+			return fmt.Errorf("BaseConfig cannot set %s, which Files sets itself", key)
+		}
+	}
+	return nil
+}
+
+// open opens path, which may be "-" for stdin if f.AllowStdin.
+func (f *Files) open(path string) (file *os.File, isStdin bool, err error) {
+	if f.AllowStdin && path == "-" {
+		return os.Stdin, true, nil
+	}
+	file, err = os.Open(path)
+	return file, false, err
+}
+
+// Values of RoleKey.
+const (
+	RoleBaseline   = "baseline"
+	RoleExperiment = "experiment"
+)
+
+// resetReader prepares r to read file, which is called path and is
+// file index in the sequence of files.
+func (f *Files) resetReader(r *Reader, file *os.File, path string, index int) {
+	// Because ".file" is not valid syntax for file configuration
+	// keys in the file itself, there's no danger if it being
+	// overwritten.
+	initConfig := []string{".file", path}
+	if f.RoleKey != "" {
+		role := RoleExperiment
+		if index == 0 {
+			role = RoleBaseline
+		}
+		initConfig = append(initConfig, f.RoleKey, role)
+	}
+	initConfig = append(initConfig, f.BaseConfig...)
+	r.RunKey = f.RunKey
+	r.MaxLineLength = f.MaxLineLength
+	r.FieldSeparator = f.FieldSeparator
+	r.Uncertainty = f.Uncertainty
+	r.ConfigOnly = f.ConfigOnly
+	r.Reset(file, path, initConfig...)
+}
+
+// ScanParallel reads the files in f concurrently, using up to workers
+// goroutines, and calls fn with each result. For each malformed
+// result, it calls fn with a nil Result and the parse error. This is
+// an alternative to a loop over Scan for reading many files quickly.
+// ScanParallel must not be mixed with calls to Scan.
+//
+// Each file is read by its own Reader, so the file configuration of
+// one file, including its ".file" key, never affects the results of
+// another file, exactly as when files are read sequentially. fn is
+// called concurrently from multiple goroutines, but the results of
+// any one file are passed to fn in order, from a single goroutine.
+// As with Result, fn must not retain the Result, which will be
+// overwritten by the next result from the same file.
+//
+// ScanParallel returns the first I/O error encountered, if any. After
+// an I/O error, it stops opening more files, but finishes reading the
+// files it has already opened.
+func (f *Files) ScanParallel(workers int, fn func(res *Result, err error)) error {
+	paths := f.Paths
+	if f.AllowStdin && len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if err := f.checkBaseConfig(); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	work := make(chan int) // Index into paths
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker has its own Reader. A Reader
+			// retains state between results, so it must
+			// never be shared between goroutines.
+			var r Reader
+			for i := range work {
+				path := paths[i]
+				file, isStdin, err := f.open(path)
+				if err != nil {
+					setErr(err)
+					continue
+				}
+				f.resetReader(&r, file, path, i)
+				for r.Scan() {
+					fn(r.Result())
+				}
+				if err := r.Err(); err != nil {
+					setErr(err)
+				}
+				if !isStdin {
+					file.Close()
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		if failed() {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
+
 // Result returns the last result read, or an error if the result was
 // malformed.
 //
@@ -111,6 +292,12 @@ func (f *Files) Result() (*Result, error) {
 	return r, nil
 }
 
+// UnitMetadata returns the unit metadata declared so far in the
+// current file. See Reader.UnitMetadata.
+func (f *Files) UnitMetadata() map[UnitMetadataKey]string {
+	return f.reader.UnitMetadata()
+}
+
 // Err returns the first non-EOF I/O error that was encountered by the
 // Files.
 func (f *Files) Err() error {
@@ -0,0 +1,210 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFilesBaseConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var paths []string
+	for i, data := range []string{
+		"BenchmarkA 1 1 ns/op\n",
+		"dataset: local\nBenchmarkB 1 1 ns/op\ndataset:\nBenchmarkC 1 1 ns/op\n",
+		"BenchmarkD 1 1 ns/op\n",
+	} {
+		path := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	files := Files{Paths: paths, BaseConfig: []string{"dataset", "nightly", "machine", "m1"}}
+	var got []string
+	for files.Scan() {
+		res, err := files.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(res.FullName)+":"+res.GetFileConfig("dataset")+","+res.GetFileConfig("machine"))
+		if file := res.GetFileConfig(".file"); !strings.HasPrefix(file, dir) {
+			t.Errorf("%s: want .file in %s, got %q", res.FullName, dir, file)
+		}
+	}
+	if err := files.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// The base config appears in every file, even after a file
+	// overrides or deletes it.
+	want := "A:nightly,m1 B:local,m1 C:,m1 D:nightly,m1"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// Malformed base configs are reported by Err, not panics.
+	for _, base := range [][]string{{"dataset"}, {".file", "x"}, {".role", "x"}} {
+		files := Files{Paths: paths, BaseConfig: base, RoleKey: ".role"}
+		if files.Scan() {
+			t.Errorf("%q: want Scan to fail", base)
+		}
+		if files.Err() == nil {
+			t.Errorf("%q: want error", base)
+		}
+		files = Files{Paths: paths, BaseConfig: base, RoleKey: ".role"}
+		if err := files.ScanParallel(1, func(*Result, error) {}); err == nil {
+			t.Errorf("%q: want ScanParallel error", base)
+		}
+	}
+}
+
+func TestFilesScanParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each file sets a key that conflicts with its neighbors, and
+	// odd files set a key that no other file sets. If configuration
+	// leaked between concurrently read files, the results would
+	// pick up the wrong values.
+	const nFiles = 32
+	var paths []string
+	want := make(map[string]string)
+	for i := 0; i < nFiles; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+		data := fmt.Sprintf("key: %c\n", 'a'+i%2)
+		wantCfg := fmt.Sprintf(".file=%s base=x key=%c", path, 'a'+i%2)
+		if i%2 == 1 {
+			data += fmt.Sprintf("only%d: y\n", i)
+			wantCfg += fmt.Sprintf(" only%d=y", i)
+		}
+		for j := 0; j < 10; j++ {
+			data += fmt.Sprintf("BenchmarkF%dR%d 1 1 ns/op\n", i, j)
+			want[fmt.Sprintf("F%dR%d", i, j)] = wantCfg
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	files := Files{Paths: paths, BaseConfig: []string{"base", "x"}}
+	var mu sync.Mutex
+	got := make(map[string]string)
+	err = files.ScanParallel(4, func(res *Result, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var cfg []string
+		for _, c := range res.FileConfig {
+			cfg = append(cfg, c.Key+"="+string(c.Value))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		got[string(res.FullName)] = strings.Join(cfg, " ")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("want %d results, got %d", len(want), len(got))
+	}
+	for name, wantCfg := range want {
+		if got[name] != wantCfg {
+			t.Errorf("%s: want config %s, got %s", name, wantCfg, got[name])
+		}
+	}
+
+	// Missing files are reported as errors.
+	files = Files{Paths: []string{filepath.Join(dir, "missing.txt")}}
+	if err := files.ScanParallel(4, func(*Result, error) {}); err == nil {
+		t.Errorf("want error for missing file")
+	}
+}
+
+func TestFilesRoleKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var paths []string
+	for i, data := range []string{
+		"BenchmarkA 1 1 ns/op\nBenchmarkB 1 1 ns/op\n",
+		"BenchmarkC 1 1 ns/op\n",
+		"BenchmarkD 1 1 ns/op\n",
+	} {
+		path := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	const want = "A:baseline B:baseline C:experiment D:experiment"
+
+	files := Files{Paths: paths, RoleKey: ".role"}
+	var got []string
+	for files.Scan() {
+		res, err := files.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(res.FullName)+":"+res.GetFileConfig(".role"))
+	}
+	if err := files.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// ScanParallel assigns roles by position in Paths, not by
+	// the order files are read.
+	files = Files{Paths: paths, RoleKey: ".role"}
+	var mu sync.Mutex
+	roles := make(map[string]string)
+	err = files.ScanParallel(3, func(res *Result, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		roles[string(res.FullName)] = res.GetFileConfig(".role")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	for _, name := range []string{"A", "B", "C", "D"} {
+		got = append(got, name+":"+roles[name])
+	}
+	if strings.Join(got, " ") != want {
+		t.Errorf("ScanParallel: want %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// Without RoleKey, there's no role.
+	files = Files{Paths: paths}
+	for files.Scan() {
+		res, _ := files.Result()
+		if _, ok := res.FileConfigIndex(".role"); ok {
+			t.Errorf("%s: unexpected .role", res.FullName)
+		}
+	}
+}
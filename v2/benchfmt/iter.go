@@ -0,0 +1,50 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+// +build go1.23
+
+package benchfmt
+
+import "iter"
+
+// All returns an iterator over the results read by r. This is an
+// alternative to a loop over Scan, Result, and Err.
+//
+// For each malformed result, the iterator yields a nil Result and the
+// parse error. Parse errors are non-fatal, so iteration continues
+// after them. If an I/O error occurs, the iterator yields a nil Result
+// and that error, and then stops.
+//
+// As with Result, the caller must not retain a yielded Result, as it
+// will be overwritten when the iteration continues. Use Result.Clone
+// to retain a copy.
+func (r *Reader) All() iter.Seq2[*Result, error] {
+	return func(yield func(*Result, error) bool) {
+		for r.Scan() {
+			if !yield(r.Result()) {
+				return
+			}
+		}
+		if err := r.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// All returns an iterator over the results read from f. It behaves
+// like Reader.All, including that the caller must not retain a
+// yielded Result.
+func (f *Files) All() iter.Seq2[*Result, error] {
+	return func(yield func(*Result, error) bool) {
+		for f.Scan() {
+			if !yield(f.Result()) {
+				return
+			}
+		}
+		if err := f.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
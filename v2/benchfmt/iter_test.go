@@ -0,0 +1,106 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+// +build go1.23
+
+package benchfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const iterInput = `key: value
+BenchmarkOne 100 1 ns/op
+BenchmarkBad
+BenchmarkTwo 300 4.5 ns/op
+BenchmarkBad 1 1
+key: value2
+BenchmarkThree 1 2 ns/op
+`
+
+func TestReaderAll(t *testing.T) {
+	want := parseAll(t, iterInput)
+
+	r := NewReader(strings.NewReader(iterInput), "test")
+	var got []*Result
+	for res, err := range r.All() {
+		if err != nil {
+			got = append(got, errResult(err.Error()))
+		} else {
+			got = append(got, res.Clone())
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Stopping early is fine.
+	r = NewReader(strings.NewReader(iterInput), "test")
+	n := 0
+	for range r.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("want 1 iteration, got %d", n)
+	}
+}
+
+func TestFilesAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte(iterInput), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect the sequence with a manual Scan loop.
+	collect := func(f *Files) (out []*Result, err error) {
+		for f.Scan() {
+			res, err := f.Result()
+			if err != nil {
+				out = append(out, errResult(err.Error()))
+			} else {
+				out = append(out, res.Clone())
+			}
+		}
+		return out, f.Err()
+	}
+	paths := []string{path, filepath.Join(dir, "missing.txt")}
+	want, wantErr := collect(&Files{Paths: paths})
+	if wantErr == nil {
+		t.Fatal("want I/O error for missing file")
+	}
+
+	var got []*Result
+	var gotErr error
+	for res, err := range (&Files{Paths: paths}).All() {
+		if gotErr != nil {
+			t.Fatalf("iteration continued after I/O error")
+		}
+		switch {
+		case res != nil:
+			got = append(got, res.Clone())
+		case strings.Contains(err.Error(), "missing.txt"):
+			gotErr = err
+		default:
+			got = append(got, errResult(err.Error()))
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if gotErr == nil || gotErr.Error() != wantErr.Error() {
+		t.Errorf("want error %v, got %v", wantErr, gotErr)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import "container/heap"
+
+// A MergeReader merges the results of several Readers whose results
+// are each sorted into a single sorted sequence of results. For
+// example, this can combine per-machine result files that are each
+// sorted by benchmark name into one sorted stream. Since it only
+// holds the current result of each Reader, it can merge inputs that
+// are too large to fit in memory.
+//
+// Its API is modeled on Reader. Like Reader, a MergeReader retains
+// ownership of the results it returns, so the caller should copy
+// anything it needs to retain.
+type MergeReader struct {
+	readers []*Reader
+	h       mergeHeap
+
+	// pending is the indexes of readers that must be advanced
+	// before the next result can be chosen. Initially, this is
+	// all of the readers, and after that, it is the reader of the
+	// current result.
+	pending []int
+	// cur is the index of the reader of the current result, or -1.
+	cur int
+	err error
+}
+
+// NewMergeReader returns a MergeReader that merges the results of
+// readers into a single sequence ordered by less.
+//
+// Each Reader must produce results in the order given by less. This
+// is not checked: if an input is not sorted, the merged sequence will
+// not be sorted either, though it will still contain every result.
+// Results that are equal according to less are returned in the order
+// of readers, so merging is stable.
+//
+// Malformed results have nothing to compare, so the MergeReader
+// returns them as soon as their Reader reaches them.
+func NewMergeReader(readers []*Reader, less func(a, b *Result) bool) *MergeReader {
+	m := &MergeReader{readers: readers, cur: -1}
+	m.h.readers, m.h.less = readers, less
+	for i := len(readers) - 1; i >= 0; i-- {
+		m.pending = append(m.pending, i)
+	}
+	return m
+}
+
+// Scan advances the MergeReader to the next result and returns true
+// if a result was read. The caller should use the Result method to
+// get the result. If an I/O error occurs in any Reader, or all of the
+// Readers reach the end of their input, it returns false and the
+// caller should use the Err method to check for errors.
+func (m *MergeReader) Scan() bool {
+	if m.err != nil {
+		return false
+	}
+
+	for len(m.pending) > 0 {
+		i := m.pending[len(m.pending)-1]
+		m.pending = m.pending[:len(m.pending)-1]
+		r := m.readers[i]
+		if !r.Scan() {
+			if err := r.Err(); err != nil {
+				m.err = err
+				m.cur = -1
+				return false
+			}
+			// This reader is exhausted.
+			continue
+		}
+		if _, err := r.Result(); err != nil {
+			m.cur = i
+			m.pending = append(m.pending, i)
+			return true
+		}
+		heap.Push(&m.h, i)
+	}
+
+	if m.h.Len() == 0 {
+		m.cur = -1
+		return false
+	}
+	m.cur = heap.Pop(&m.h).(int)
+	m.pending = append(m.pending, m.cur)
+	return true
+}
+
+// Result returns the current result. The caller should not retain
+// the Result object, as it will be overwritten by the next call to
+// Scan. See Reader.Result.
+func (m *MergeReader) Result() (*Result, error) {
+	return m.readers[m.cur].Result()
+}
+
+// Err returns the first non-EOF I/O error that was encountered by any
+// of the Readers.
+func (m *MergeReader) Err() error {
+	return m.err
+}
+
+// mergeHeap is a min-heap of the indexes of readers, ordered by their
+// current results.
+type mergeHeap struct {
+	readers []*Reader
+	less    func(a, b *Result) bool
+	idx     []int
+}
+
+func (h *mergeHeap) Len() int {
+	return len(h.idx)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	ri, rj := h.idx[i], h.idx[j]
+	a, _ := h.readers[ri].Result()
+	b, _ := h.readers[rj].Result()
+	if h.less(a, b) {
+		return true
+	}
+	if h.less(b, a) {
+		return false
+	}
+	// Break ties by reader order.
+	return ri < rj
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.idx[i], h.idx[j] = h.idx[j], h.idx[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	h.idx = append(h.idx, x.(int))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	x := h.idx[len(h.idx)-1]
+	h.idx = h.idx[:len(h.idx)-1]
+	return x
+}
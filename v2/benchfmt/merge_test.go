@@ -0,0 +1,52 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeReader(t *testing.T) {
+	inputs := []string{
+		"machine: a\nBenchmarkA 1 1 ns/op\nBenchmarkC 1 1 ns/op\nBenchmarkD 1 1 ns/op\n",
+		"machine: b\nBenchmarkB 1 1 ns/op\nBenchmarkC 1 1 ns/op\nBenchmarkBad\nBenchmarkE 1 1 ns/op\n",
+		"machine: c\nBenchmarkA 1 1 ns/op\nBenchmarkF 1 1 ns/op\n",
+	}
+	var readers []*Reader
+	for i, input := range inputs {
+		readers = append(readers, NewReader(strings.NewReader(input), string(rune('a'+i))))
+	}
+	less := func(a, b *Result) bool {
+		return bytes.Compare(a.FullName, b.FullName) < 0
+	}
+	m := NewMergeReader(readers, less)
+
+	var got []string
+	for m.Scan() {
+		res, err := m.Result()
+		if err != nil {
+			got = append(got, "error")
+			continue
+		}
+		got = append(got, string(res.FullName)+"@"+res.GetFileConfig("machine"))
+	}
+	if err := m.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Equal names come out in input order, and the malformed
+	// result comes out as soon as its input reaches it.
+	want := []string{"A@a", "A@c", "B@b", "C@a", "C@b", "error", "D@a", "E@b", "F@c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Merging no inputs produces no results.
+	if m := NewMergeReader(nil, less); m.Scan() {
+		t.Errorf("empty merge returned a result")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A PatchOp is the operation of a PatchEntry.
+type PatchOp int
+
+const (
+	// PatchAdd adds a Result to a ResultSet.
+	PatchAdd PatchOp = iota
+	// PatchRemove removes a Result from a ResultSet.
+	PatchRemove
+)
+
+// A PatchEntry is a single change in a patch.
+type PatchEntry struct {
+	Op     PatchOp
+	Result *Result
+
+	// FileName and Line give the location of this entry in the
+	// patch, for error messages.
+	FileName string
+	Line     int
+}
+
+// ReadPatch reads a patch from r, which describes incremental changes
+// to a set of benchmark results. fileName is used in error messages.
+//
+// A patch uses the Go benchmark format, except that every result line
+// must be prefixed with "+" to add the result or "-" to remove it,
+// as in:
+//
+//	goos: linux
+//	+BenchmarkEncode 1000 1204 ns/op
+//	-BenchmarkDecode 1000 933 ns/op
+//
+// Configuration lines and other lines are not prefixed and apply to
+// the results that follow them as usual, so a removed result must be
+// preceded by the same file configuration it was added with.
+//
+// Unlike Reader, ReadPatch stops at the first malformed line, since
+// applying only part of a patch would leave the results inconsistent.
+func ReadPatch(r io.Reader, fileName string) ([]PatchEntry, error) {
+	// Strip the operations from result lines and record them by
+	// line number, so the rest can be parsed as usual.
+	var buf bytes.Buffer
+	ops := make(map[int]PatchOp)
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := s.Bytes()
+		if len(line) > 0 && (line[0] == '+' || line[0] == '-') && bytes.HasPrefix(line[1:], benchmarkPrefix) {
+			if line[0] == '+' {
+				ops[lineNum] = PatchAdd
+			} else {
+				ops[lineNum] = PatchRemove
+			}
+			line = line[1:]
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	var entries []PatchEntry
+	reader := NewReader(&buf, fileName)
+	for reader.Scan() {
+		res, err := reader.Result()
+		if err != nil {
+			return nil, err
+		}
+		op, ok := ops[reader.lineNum]
+		if !ok {
+			return nil, &SyntaxError{reader.fileName, reader.lineNum, "result line must begin with + or -"}
+		}
+		entries = append(entries, PatchEntry{op, res.Clone(), reader.fileName, reader.lineNum})
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// A ResultSet is an in-memory collection of Results that patches can
+// be applied to. Results are keyed by their identity: their full name
+// and file configuration, regardless of the order of file
+// configuration keys. A ResultSet may contain several Results with
+// the same identity, such as repeated runs of a benchmark. Note that
+// Files adds a ".file" configuration key, which is part of a Result's
+// identity.
+//
+// The zero value is an empty ResultSet.
+type ResultSet struct {
+	results map[string][]*Result
+	// keys is the identities in results in the order they were
+	// first added.
+	keys []string
+}
+
+// identityKey returns a string identifying r's full name and file
+// configuration.
+func identityKey(r *Result) string {
+	cfgs := append([]Config(nil), r.FileConfig...)
+	sort.Slice(cfgs, func(i, j int) bool {
+		return cfgs[i].Key < cfgs[j].Key
+	})
+	// Neither keys, values, nor names can contain newlines, so
+	// this encoding is unambiguous.
+	var buf []byte
+	for _, cfg := range cfgs {
+		if len(cfg.Value) == 0 {
+			continue
+		}
+		buf = append(buf, cfg.Key...)
+		buf = append(buf, ": "...)
+		buf = append(buf, cfg.Value...)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, r.FullName...)
+	return string(buf)
+}
+
+// Add adds a copy of r to s.
+func (s *ResultSet) Add(r *Result) {
+	if s.results == nil {
+		s.results = make(map[string][]*Result)
+	}
+	key := identityKey(r)
+	if _, ok := s.results[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.results[key] = append(s.results[key], r.Clone())
+}
+
+// Remove removes the first Result in s with r's identity that is
+// Equal to r. It reports whether it found such a Result.
+func (s *ResultSet) Remove(r *Result) bool {
+	key := identityKey(r)
+	results := s.results[key]
+	for i, r2 := range results {
+		if r2.Equal(r) {
+			s.results[key] = append(results[:i:i], results[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of Results in s.
+func (s *ResultSet) Len() int {
+	n := 0
+	for _, results := range s.results {
+		n += len(results)
+	}
+	return n
+}
+
+// Results returns the Results in s. Results are grouped by identity,
+// in the order each identity was first added, and Results with the
+// same identity are in the order they were added. The caller must
+// not modify the returned Results.
+func (s *ResultSet) Results() []*Result {
+	var out []*Result
+	for _, key := range s.keys {
+		out = append(out, s.results[key]...)
+	}
+	return out
+}
+
+// Apply applies the entries of a patch to s in order. Adding a
+// Result always succeeds. Removing a Result that isn't in s is an
+// error: this usually means the patch was computed against a
+// different set of results. If Apply returns an error, the entries
+// before the failed entry have been applied.
+func (s *ResultSet) Apply(patch []PatchEntry) error {
+	for _, e := range patch {
+		switch e.Op {
+		case PatchAdd:
+			s.Add(e.Result)
+		case PatchRemove:
+			if !s.Remove(e.Result) {
+				return fmt.Errorf("%s:%d: removed result %s not found", e.FileName, e.Line, e.Result.FullName)
+			}
+		default:
+			return fmt.Errorf("%s:%d: unknown patch operation %d", e.FileName, e.Line, e.Op)
+		}
+	}
+	return nil
+}
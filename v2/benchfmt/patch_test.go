@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	const baseline = `goos: linux
+BenchmarkA 1 1 ns/op
+BenchmarkA 1 2 ns/op
+BenchmarkB 1 3 ns/op
+goos: darwin
+BenchmarkA 1 4 ns/op
+`
+	var set ResultSet
+	for _, res := range parseAll(t, baseline) {
+		set.Add(res)
+	}
+
+	const patch = `goos: linux
+-BenchmarkA 1 2 ns/op
++BenchmarkC 1 5 ns/op
++BenchmarkA 1 6 ns/op
+`
+	entries, err := ReadPatch(strings.NewReader(patch), "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Op != PatchRemove || entries[1].Op != PatchAdd || entries[0].Line != 2 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if err := set.Apply(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	var got strings.Builder
+	w := NewWriter(&got)
+	for _, res := range set.Results() {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Results are grouped by identity.
+	const want = `goos: linux
+
+BenchmarkA 1 1 ns/op
+BenchmarkA 1 6 ns/op
+BenchmarkB 1 3 ns/op
+
+goos: darwin
+
+BenchmarkA 1 4 ns/op
+
+goos: linux
+
+BenchmarkC 1 5 ns/op
+`
+	if got.String() != want {
+		t.Errorf("want:\n%sgot:\n%s", want, got.String())
+	}
+	if set.Len() != 5 {
+		t.Errorf("want 5 results, got %d", set.Len())
+	}
+
+	// Removing a result that isn't present fails. The
+	// configuration is part of its identity.
+	entries, err = ReadPatch(strings.NewReader("goos: windows\n-BenchmarkB 1 3 ns/op\n"), "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Apply(entries); err == nil || !strings.HasPrefix(err.Error(), "patch:2: ") {
+		t.Errorf("want error at patch:2, got %v", err)
+	}
+
+	// Malformed patches.
+	for _, bad := range []string{"BenchmarkA 1 1 ns/op\n", "+BenchmarkA 1\n"} {
+		if _, err := ReadPatch(strings.NewReader(bad), "patch"); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
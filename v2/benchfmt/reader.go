@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"unicode"
 	"unicode/utf8"
 
@@ -24,15 +25,87 @@ import (
 //
 // The zero value of the Reader is a valid Reader, but the user must
 // call Reset before using it.
+//
+// A single input may contain several runs of a benchmark, for
+// example, if it is the concatenated output of several "go test"
+// invocations. The Reader counts these runs: each file configuration
+// block that follows a result starts a new run.
+//
+// The input may also declare metadata about units with lines of the
+// form "Unit <unit> <key>=<value> ...". The Reader collects these in
+// its UnitMetadata map. A line must have at least one key=value pair
+// to be a unit line, so other text that happens to start with "Unit"
+// is ignored like any other non-benchmark output.
 type Reader struct {
+	// RunKey, if non-empty, is a file configuration key in which
+	// the Reader records the index of the run of each result,
+	// starting at 0. This is conventionally ".run". Like ".file",
+	// keys beginning with "." cannot appear in the input, so this
+	// cannot conflict with keys in the input. RunKey must be set
+	// before calling Reset.
+	RunKey string
+
+	// MaxLineLength, if positive, is the maximum length in bytes of
+	// a line in the input, not counting its newline. Reading a
+	// longer line is an I/O error.
+	// If MaxLineLength is 0, the limit is bufio.MaxScanTokenSize.
+	// This should be raised to read results with very long lines,
+	// such as results that report many units. MaxLineLength must
+	// be set before calling Reset.
+	MaxLineLength int
+
+	// FieldSeparator, if non-zero, is an ASCII character that
+	// separates the fields of benchmark result lines in addition
+	// to white space. This lets the Reader parse near-standard
+	// output, such as "BenchmarkX 100 5 ns/op, 3 B/op" with a
+	// separator of ','. The separator cannot appear within a
+	// benchmark name or unit. It does not affect configuration
+	// lines.
+	FieldSeparator byte
+
+	// Uncertainty indicates that a measurement in a benchmark
+	// result line may be followed by an uncertainty field, such
+	// as the "±5" in "BenchmarkX 100 5 ±5 ns/op", as emitted by
+	// some tools. The uncertainty is recorded in Value.Err. If
+	// Uncertainty is false, such a line is malformed.
+	Uncertainty bool
+
+	// ConfigOnly indicates that the Reader should read only the
+	// file configuration of the input, which is much faster than
+	// parsing every result. Instead of one Result per benchmark
+	// line, Scan returns a Result for the first benchmark line
+	// after each block of file configuration lines, and skips
+	// other benchmark lines without parsing them. These Results
+	// have the file configuration in effect for the following
+	// results, but no name, iteration count, or values.
+	// Configuration that isn't followed by any benchmark lines
+	// isn't returned.
+	ConfigOnly bool
+
 	s        *bufio.Scanner
 	fileName string
 	lineNum  int
 	err      error // current I/O error
 
+	// run is the index of the current run. afterResult indicates
+	// that a result has been read in the current run, so the
+	// next file configuration line starts a new run.
+	run         int
+	afterResult bool
+
+	// configChanged indicates that the file configuration has
+	// changed since the last Result returned in ConfigOnly mode.
+	configChanged bool
+
 	result    Result
 	resultErr error
 
+	// sepBuf is a scratch buffer for rewriting separators in
+	// benchmark lines.
+	sepBuf []byte
+
+	unitMetadata map[UnitMetadataKey]string
+
 	interns map[string]string
 }
 
@@ -48,6 +121,31 @@ func (s *SyntaxError) Error() string {
 	return fmt.Sprintf("%s:%d: %s", s.FileName, s.Line, s.Msg)
 }
 
+// A UnitMetadataKey identifies a metadata key of a unit, as declared
+// by a "Unit" line in the input.
+type UnitMetadataKey struct {
+	Unit, Key string
+}
+
+// UnitScaleKey is the unit metadata key that declares how a unit
+// should be displayed. Its value is an SI or binary prefix, such as
+// "m" or "Ki", or "1" for no prefix. For example, the line
+//
+//	Unit sec/op scale=m
+//
+// requests that sec/op values always be shown in milliseconds.
+const UnitScaleKey = "scale"
+
+// UnitBetterKey is the unit metadata key that declares whether
+// "higher" or "lower" values of a unit are better. For example, the
+// line
+//
+//	Unit MB/s better=higher
+//
+// declares that higher throughput is better. By convention, lower
+// values are better for units without this key.
+const UnitBetterKey = "better"
+
 var noResult = errors.New("Reader.Scan has not been called")
 
 // NewReader constructs a reader to parse the Go benchmark format from
@@ -66,6 +164,17 @@ func NewReader(r io.Reader, fileName string) *Reader {
 // any results are read from the input file.
 func (r *Reader) Reset(ior io.Reader, fileName string, initConfig ...string) {
 	r.s = bufio.NewScanner(ior)
+	if r.MaxLineLength > 0 {
+		// Start with the default initial buffer size, but
+		// don't allocate more than the limit. The Scanner's
+		// limit includes the newline.
+		max := r.MaxLineLength + 1
+		initial := 4096
+		if initial > max {
+			initial = max
+		}
+		r.s.Buffer(make([]byte, 0, initial), max)
+	}
 	if fileName == "" {
 		fileName = "<unknown>"
 	}
@@ -73,9 +182,14 @@ func (r *Reader) Reset(ior io.Reader, fileName string, initConfig ...string) {
 	r.lineNum = 0
 	r.err = nil
 	r.resultErr = noResult
+	r.run, r.afterResult = 0, false
+	r.configChanged = true
 	if r.interns == nil {
 		r.interns = make(map[string]string)
 	}
+	for k := range r.unitMetadata {
+		delete(r.unitMetadata, k)
+	}
 
 	// Wipe the Result.
 	r.result.FileConfig = r.result.FileConfig[:0]
@@ -93,9 +207,33 @@ func (r *Reader) Reset(ior io.Reader, fileName string, initConfig ...string) {
 	for i := 0; i < len(initConfig); i += 2 {
 		r.result.SetFileConfig(initConfig[i], initConfig[i+1])
 	}
+	if r.RunKey != "" {
+		r.result.SetFileConfig(r.RunKey, "0")
+	}
+}
+
+// Run returns the index of the run containing the most recent
+// result, starting at 0.
+func (r *Reader) Run() int {
+	return r.run
+}
+
+// UnitMetadata returns the unit metadata declared so far in the
+// current input. Later declarations of the same unit and key replace
+// earlier ones. Reset clears the unit metadata.
+//
+// The caller should not modify the returned map, and should copy it
+// if it needs to retain the metadata across calls to Reset.
+func (r *Reader) UnitMetadata() map[UnitMetadataKey]string {
+	if r.unitMetadata == nil {
+		r.unitMetadata = make(map[UnitMetadataKey]string)
+	}
+	return r.unitMetadata
 }
 
 var benchmarkPrefix = []byte("Benchmark")
+var unitPrefix = []byte("Unit")
+var uncertaintyPrefix = []byte("±")
 
 // Scan advances the reader to the next result and returns true if a
 // result was read. The caller should use the Result method to get the
@@ -117,12 +255,45 @@ func (r *Reader) Scan() bool {
 		// Most lines are benchmark lines, and we can check
 		// for that very quickly, so start with that.
 		if bytes.HasPrefix(line, benchmarkPrefix) {
+			if r.ConfigOnly {
+				r.afterResult = true
+				if !r.configChanged {
+					continue
+				}
+				r.configChanged = false
+				r.result.FullName = r.result.FullName[:0]
+				r.result.Iters = 0
+				r.result.Values = r.result.Values[:0]
+				r.resultErr = nil
+				return true
+			}
 			// At this point we commit to this being a
 			// benchmark line. If it's malformed, we treat
 			// that as an error.
+			if r.FieldSeparator != 0 {
+				line = r.replaceSeparator(line)
+			}
 			r.resultErr = r.parseBenchmarkLine(line)
+			r.afterResult = true
 			return true
+		} else if isUnitLine(line) {
+			// A malformed unit line is reported like a
+			// malformed result, so the caller sees it.
+			if err := r.parseUnitLine(line); err != nil {
+				r.resultErr = err
+				return true
+			}
 		} else if key, val, ok := parseKeyValueLine(line); ok {
+			if r.afterResult {
+				// Configuration after results starts
+				// a new run.
+				r.run++
+				r.afterResult = false
+				if r.RunKey != "" {
+					r.result.SetFileConfig(r.RunKey, strconv.Itoa(r.run))
+				}
+			}
+			r.configChanged = true
 			// Intern key, since there tend to be few
 			// unique keys.
 			keyStr := r.intern(key)
@@ -137,6 +308,16 @@ func (r *Reader) Scan() bool {
 	}
 
 	if err := r.s.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			// The error is about the line we failed to
+			// read, not the last line we read.
+			max := r.MaxLineLength
+			if max <= 0 {
+				max = bufio.MaxScanTokenSize
+			}
+			r.err = fmt.Errorf("%s:%d: line longer than %d bytes: %w", r.fileName, r.lineNum+1, max, err)
+			return false
+		}
 		r.err = fmt.Errorf("%s:%d: %w", r.fileName, r.lineNum, err)
 		return false
 	}
@@ -144,8 +325,35 @@ func (r *Reader) Scan() bool {
 	return false
 }
 
+// ScanInto is like Scan, but if it reads a well-formed result, it
+// also copies that result into dst. Unlike the Result returned by the
+// Result method, dst is owned by the caller and is not overwritten by
+// the next call to Scan. ScanInto reuses dst's existing storage where
+// possible, so collecting results into a reused slice of Results
+// allocates far less than cloning each result.
+//
+// If the result is malformed, ScanInto still returns true but leaves
+// dst unmodified, and the caller should use the Result method to get
+// the error.
+func (r *Reader) ScanInto(dst *Result) bool {
+	if !r.Scan() {
+		return false
+	}
+	if r.resultErr == nil {
+		r.result.copyInto(dst)
+	}
+	return true
+}
+
 // parseKeyValueLine attempts to parse line as a key: value pair. ok
 // indicates whether the line could be parsed.
+//
+// The whitespace separating "key:" from the value is stripped, so
+// leading whitespace is never part of a value. This is intentional:
+// the format does not distinguish the separator from leading
+// whitespace in the value. Everything after the separator, including
+// interior and trailing whitespace, is preserved. Hence, a value
+// consisting only of whitespace is empty, which deletes the key.
 func parseKeyValueLine(line []byte) (key, val []byte, ok bool) {
 	for i := 0; i < len(line); {
 		r, n := utf8.DecodeRune(line[i:])
@@ -184,6 +392,18 @@ func parseKeyValueLine(line []byte) (key, val []byte, ok bool) {
 	return
 }
 
+// replaceSeparator returns a copy of line with r.FieldSeparator
+// replaced by spaces. The result is only valid until the next call.
+func (r *Reader) replaceSeparator(line []byte) []byte {
+	r.sepBuf = append(r.sepBuf[:0], line...)
+	for i, ch := range r.sepBuf {
+		if ch == r.FieldSeparator {
+			r.sepBuf[i] = ' '
+		}
+	}
+	return r.sepBuf
+}
+
 // parseBenchmarkLine parses line as a benchmark result and updates
 // r.result. The caller must have already checked that it begins with
 // "Benchmark".
@@ -194,6 +414,9 @@ func (r *Reader) parseBenchmarkLine(line []byte) error {
 	// Skip "Benchmark"
 	line = line[len("Benchmark"):]
 
+	// Annotations on the previous result don't carry over.
+	r.result.Meta = nil
+
 	// Read the name.
 	r.result.FullName, line = splitField(line)
 
@@ -230,16 +453,67 @@ func (r *Reader) parseBenchmarkLine(line []byte) error {
 			return &SyntaxError{r.fileName, r.lineNum, err.Error()}
 		}
 		f, line = splitField(line)
+		var valErr float64
+		if r.Uncertainty && bytes.HasPrefix(f, uncertaintyPrefix) {
+			valErr, err = atof(f[len(uncertaintyPrefix):])
+			switch err := err.(type) {
+			case nil:
+			case *bytesconv.NumError:
+				return &SyntaxError{r.fileName, r.lineNum, "parsing uncertainty: " + err.Err.Error()}
+			default:
+				return &SyntaxError{r.fileName, r.lineNum, err.Error()}
+			}
+			f, line = splitField(line)
+		}
 		if len(f) == 0 {
 			return &SyntaxError{r.fileName, r.lineNum, "missing units"}
 		}
 		unit := r.intern(f)
-		r.result.Values = append(r.result.Values, Value{val, unit})
+		r.result.Values = append(r.result.Values, Value{Value: val, Unit: unit, Err: valErr})
 	}
 
 	return nil
 }
 
+// isUnitLine returns whether line is a unit metadata line: the word
+// "Unit", a unit name, and a key=value field.
+func isUnitLine(line []byte) bool {
+	f, line := splitField(line)
+	if !bytes.Equal(f, unitPrefix) {
+		return false
+	}
+	unit, line := splitField(line)
+	if len(unit) == 0 {
+		return false
+	}
+	kv, _ := splitField(line)
+	return bytes.IndexByte(kv, '=') > 0
+}
+
+// parseUnitLine parses line as a unit metadata line and records its
+// metadata. The caller must have already checked isUnitLine.
+func (r *Reader) parseUnitLine(line []byte) error {
+	// Skip "Unit"
+	_, line = splitField(line)
+
+	unit, line := splitField(line)
+	unitStr := r.intern(unit)
+	for {
+		var f []byte
+		f, line = splitField(line)
+		if len(f) == 0 {
+			break
+		}
+		i := bytes.IndexByte(f, '=')
+		if i <= 0 {
+			return &SyntaxError{r.fileName, r.lineNum, "expected key=value"}
+		}
+		key := UnitMetadataKey{unitStr, r.intern(f[:i])}
+		r.UnitMetadata()[key] = string(f[i+1:])
+	}
+	return nil
+}
+
 func (r *Reader) intern(x []byte) string {
 	const maxIntern = 1024
 	if s, ok := r.interns[string(x)]; ok {
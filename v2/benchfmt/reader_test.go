@@ -5,7 +5,9 @@
 package benchfmt
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -44,7 +46,11 @@ func printResult(w io.Writer, r *Result) {
 	}
 	fmt.Fprintf(w, "%s %d", r.FullName, r.Iters)
 	for _, val := range r.Values {
-		fmt.Fprintf(w, " %v %s", val.Value, val.Unit)
+		if val.Err != 0 {
+			fmt.Fprintf(w, " %v ±%v %s", val.Value, val.Err, val.Unit)
+		} else {
+			fmt.Fprintf(w, " %v %s", val.Value, val.Unit)
+		}
 	}
 	fmt.Fprintf(w, "\n")
 }
@@ -80,12 +86,12 @@ BenchmarkTwo 300 4.5 ns/op
 				[]Config{{"key", []byte("value")}},
 				"One",
 				100,
-				[]Value{{1, "ns/op"}, {2, "B/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}},
 			), r(
 				[]Config{{"key", []byte("value")}},
 				"Two",
 				300,
-				[]Value{{4.5, "ns/op"}},
+				[]Value{{Value: 4.5, Unit: "ns/op"}},
 			)},
 		},
 		{
@@ -99,17 +105,17 @@ BenchmarkEmSpace  1  1  ns/op
 				[]Config{},
 				"Spaces",
 				1,
-				[]Value{{1, "ns/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}},
 			), r(
 				[]Config{},
 				"HugeVal",
 				1,
-				[]Value{{9999999999999999999999999999999, "ns/op"}},
+				[]Value{{Value: 9999999999999999999999999999999, Unit: "ns/op"}},
 			), r(
 				[]Config{},
 				"EmSpace",
 				1,
-				[]Value{{1, "ns/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}},
 			)},
 		},
 		{
@@ -126,7 +132,7 @@ BenchmarkOne 100 1 ns/op
 				[]Config{{"key1", []byte("value")}, {"key2", []byte("value")}},
 				"One",
 				100,
-				[]Value{{1, "ns/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}},
 			)},
 		},
 		{
@@ -151,6 +157,26 @@ also not a benchmark
 				errResult("test:8: missing units"),
 			},
 		},
+		{
+			"cpu",
+			`goos: linux
+goarch: amd64
+pkg: example.com/pkg
+cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+BenchmarkOne-8 100 1 ns/op
+`,
+			[]*Result{r(
+				[]Config{
+					{"goos", []byte("linux")},
+					{"goarch", []byte("amd64")},
+					{"pkg", []byte("example.com/pkg")},
+					{"cpu", []byte("Intel(R) Xeon(R) CPU @ 2.20GHz")},
+				},
+				"One-8",
+				100,
+				[]Value{{Value: 1, Unit: "ns/op"}},
+			)},
+		},
 		{
 			"remove existing label",
 			`key: value
@@ -161,7 +187,7 @@ BenchmarkOne 100 1 ns/op
 				[]Config{},
 				"One",
 				100,
-				[]Value{{1, "ns/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}},
 			)},
 		},
 		{
@@ -175,7 +201,7 @@ BenchmarkOne 100 1 ns/op
 				[]Config{{"key1", []byte("third")}, {"key2", []byte("second")}},
 				"One",
 				100,
-				[]Value{{1, "ns/op"}},
+				[]Value{{Value: 1, Unit: "ns/op"}},
 			)},
 		},
 	} {
@@ -250,3 +276,472 @@ func BenchmarkReader(b *testing.B) {
 	b.ReportMetric(float64(n/b.N), "records/op")
 	b.ReportMetric(float64(n)*float64(time.Second)/float64(dur), "records/sec")
 }
+
+func BenchmarkReaderConfigOnly(b *testing.B) {
+	path := "testdata/bent"
+	fileInfos, err := ioutil.ReadDir(path)
+	if err != nil {
+		b.Fatal("reading test data directory: ", err)
+	}
+
+	var files []*os.File
+	for _, info := range fileInfos {
+		f, err := os.Open(filepath.Join(path, info.Name()))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	for _, configOnly := range []bool{false, true} {
+		name := "full"
+		if configOnly {
+			name = "configOnly"
+		}
+		b.Run(name, func(b *testing.B) {
+			var n int
+			for i := 0; i < b.N; i++ {
+				r := new(Reader)
+				for _, f := range files {
+					if _, err := f.Seek(0, 0); err != nil {
+						b.Fatal("seeking to 0: ", err)
+					}
+					r.Reset(f, f.Name())
+					r.ConfigOnly = configOnly
+					for r.Scan() {
+						n++
+						if _, err := r.Result(); err != nil {
+							b.Fatal("malformed record: ", err)
+						}
+					}
+					if err := r.Err(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(n/b.N), "records/op")
+		})
+	}
+}
+
+func TestReaderConfigOnly(t *testing.T) {
+	const input = `a: 1
+b: 2
+BenchmarkOne 100 1 ns/op
+BenchmarkTwo 100 2 ns/op
+b: 3
+BenchmarkOne malformed
+BenchmarkTwo 100 2 ns/op
+Unit ns/op better=lower
+a: 1
+BenchmarkOne 100 1 ns/op
+c: 4
+`
+	got := parseAll(t, input, func(r *Reader) { r.ConfigOnly = true })
+	// Each block of configuration yields one result, even if it
+	// doesn't change the configuration. Malformed benchmark lines
+	// aren't parsed, so they aren't errors. The trailing
+	// configuration has no results, so it isn't returned.
+	want := []*Result{
+		r([]Config{{"a", []byte("1")}, {"b", []byte("2")}}, "", 0, nil),
+		r([]Config{{"a", []byte("1")}, {"b", []byte("3")}}, "", 0, nil),
+		r([]Config{{"a", []byte("1")}, {"b", []byte("3")}}, "", 0, nil),
+	}
+	var gotBuf, wantBuf strings.Builder
+	for _, res := range got {
+		printResult(&gotBuf, res)
+	}
+	for _, res := range want {
+		printResult(&wantBuf, res)
+	}
+	if gotBuf.String() != wantBuf.String() {
+		t.Errorf("want:\n%s\ngot:\n%s", wantBuf.String(), gotBuf.String())
+	}
+
+	// Runs are still counted.
+	rd := new(Reader)
+	rd.RunKey = ".run"
+	rd.ConfigOnly = true
+	rd.Reset(strings.NewReader(input), "test")
+	var runs []string
+	for rd.Scan() {
+		res, err := rd.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, res.GetFileConfig(".run"))
+	}
+	if want := []string{"0", "1", "2"}; !reflect.DeepEqual(want, runs) {
+		t.Errorf("want runs %v, got %v", want, runs)
+	}
+}
+
+func TestReaderScanInto(t *testing.T) {
+	const input = `key: value
+BenchmarkOne 100 1 ns/op 2 B/op
+key2: value2
+BenchmarkTwo 300 4.5 ns/op
+BenchmarkBad
+key:
+BenchmarkThree 1 2 ns/op
+`
+	want := parseAll(t, input)
+
+	r := NewReader(strings.NewReader(input), "test")
+	// Reuse a single Result that starts out with junk in it to
+	// check that ScanInto fully overwrites it.
+	dst := &Result{
+		FileConfig: []Config{{"junk", []byte("junk")}, {"junk2", []byte("junk")}, {"junk3", []byte("junk")}},
+		FullName:   []byte("JunkName"),
+		Values:     []Value{{Value: 1, Unit: "junk"}, {Value: 2, Unit: "junk"}, {Value: 3, Unit: "junk"}},
+	}
+	var got []*Result
+	for r.ScanInto(dst) {
+		if _, err := r.Result(); err != nil {
+			got = append(got, errResult(err.Error()))
+			continue
+		}
+		got = append(got, dst.Clone())
+
+		// Check that the index matches.
+		for i, cfg := range dst.FileConfig {
+			if pos, ok := dst.FileConfigIndex(cfg.Key); !ok || pos != i {
+				t.Errorf("key %s: want index %d, got %d, %v", cfg.Key, i, pos, ok)
+			}
+		}
+		if _, ok := dst.FileConfigIndex("junk"); ok {
+			t.Errorf("stale key junk in index")
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "got:\n")
+		for _, res := range got {
+			printResult(&buf, res)
+		}
+		fmt.Fprintf(&buf, "want:\n")
+		for _, res := range want {
+			printResult(&buf, res)
+		}
+		t.Error(buf.String())
+	}
+}
+
+func BenchmarkCollect(b *testing.B) {
+	const n = 100000
+	var input strings.Builder
+	for i := 0; i < n; i++ {
+		if i%1000 == 0 {
+			fmt.Fprintf(&input, "commit: %d\ngoos: linux\n", i)
+		}
+		fmt.Fprintf(&input, "BenchmarkName/n=%d-8 1000 %d ns/op 16 B/op 1 allocs/op\n", i%100, i)
+	}
+	data := input.String()
+
+	b.Run("Clone", func(b *testing.B) {
+		r := new(Reader)
+		for i := 0; i < b.N; i++ {
+			var results []*Result
+			r.Reset(strings.NewReader(data), "test")
+			for r.Scan() {
+				res, err := r.Result()
+				if err != nil {
+					b.Fatal(err)
+				}
+				results = append(results, res.Clone())
+			}
+			if len(results) != n {
+				b.Fatalf("got %d results, want %d", len(results), n)
+			}
+		}
+	})
+
+	b.Run("ScanInto", func(b *testing.B) {
+		r := new(Reader)
+		var results []Result
+		for i := 0; i < b.N; i++ {
+			results = results[:0]
+			r.Reset(strings.NewReader(data), "test")
+			for {
+				if len(results) < cap(results) {
+					results = results[:len(results)+1]
+				} else {
+					results = append(results, Result{})
+				}
+				if !r.ScanInto(&results[len(results)-1]) {
+					results = results[:len(results)-1]
+					break
+				}
+				if _, err := r.Result(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if len(results) != n {
+				b.Fatalf("got %d results, want %d", len(results), n)
+			}
+		}
+	})
+}
+
+func TestReaderRuns(t *testing.T) {
+	const run = `goos: linux
+goarch: amd64
+pkg: example.com/a
+BenchmarkOne 1 1 ns/op
+BenchmarkTwo 1 1 ns/op
+`
+	// Two concatenated runs.
+	input := run + run
+
+	ext, err := NewExtractor(".run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(Reader)
+	r.RunKey = ".run"
+	r.Reset(strings.NewReader(input), "test")
+	var got []string
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprint(r.Run()); string(ext(res)) != want {
+			t.Errorf("%s: .run is %q, but Run is %s", res.FullName, ext(res), want)
+		}
+		got = append(got, fmt.Sprintf("%s:%s", res.FullName, ext(res)))
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := "One:0 Two:0 One:1 Two:1"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// Without RunKey, results don't get a run key, but runs are
+	// still counted.
+	r = NewReader(strings.NewReader(input), "test")
+	for r.Scan() {
+		res, _ := r.Result()
+		if ext(res) != nil {
+			t.Errorf("%s: unexpected .run %q", res.FullName, ext(res))
+		}
+	}
+	if r.Run() != 1 {
+		t.Errorf("want final run 1, got %d", r.Run())
+	}
+}
+
+func TestReaderMaxLineLength(t *testing.T) {
+	// Construct a result line longer than the default limit by
+	// reporting many units.
+	var line strings.Builder
+	line.WriteString("BenchmarkMany 1")
+	for i := 0; line.Len() <= bufio.MaxScanTokenSize; i++ {
+		fmt.Fprintf(&line, " %d unit%d", i, i)
+	}
+	input := "BenchmarkOne 1 1 ns/op\n" + line.String() + "\n"
+
+	// By default, this is an error attributed to the long line.
+	r := NewReader(strings.NewReader(input), "test")
+	n := 0
+	for r.Scan() {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("want 1 result before the long line, got %d", n)
+	}
+	if err := r.Err(); err == nil {
+		t.Errorf("want error for long line")
+	} else if !errors.Is(err, bufio.ErrTooLong) || !strings.HasPrefix(err.Error(), "test:2: line longer than ") {
+		t.Errorf("want line length error on test:2, got %s", err)
+	}
+
+	// Raising the limit allows it.
+	r = new(Reader)
+	r.MaxLineLength = 2 * line.Len()
+	r.Reset(strings.NewReader(input), "test")
+	n = 0
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if n == 2 && len(res.Values) < 1000 {
+			t.Errorf("want many values, got %d", len(res.Values))
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 results, got %d", n)
+	}
+
+	// Lowering the limit rejects shorter lines.
+	r.MaxLineLength = 10
+	r.Reset(strings.NewReader(input), "test")
+	for r.Scan() {
+		t.Errorf("unexpected result")
+	}
+	if err := r.Err(); err == nil || !strings.HasPrefix(err.Error(), "test:1: line longer than 10 bytes") {
+		t.Errorf("want line length error on test:1, got %v", err)
+	}
+
+	// The limit doesn't count the newline, so a line of exactly
+	// MaxLineLength bytes is allowed, but one more byte isn't.
+	short := "BenchmarkA 1 1 ns/op"
+	r.MaxLineLength = len(short)
+	r.Reset(strings.NewReader(short+"\n"+short+"\n"), "test")
+	n = 0
+	for r.Scan() {
+		n++
+	}
+	if err := r.Err(); err != nil || n != 2 {
+		t.Errorf("line of MaxLineLength bytes: want 2 results, got %d, %v", n, err)
+	}
+	r.Reset(strings.NewReader(short+"0\n"), "test")
+	for r.Scan() {
+		t.Errorf("unexpected result")
+	}
+	if err := r.Err(); err == nil || !strings.HasPrefix(err.Error(), fmt.Sprintf("test:1: line longer than %d bytes", len(short))) {
+		t.Errorf("line of MaxLineLength+1 bytes: want line length error, got %v", err)
+	}
+}
+
+func TestReaderUnitMetadata(t *testing.T) {
+	const input = `Unit ns/op assume=exact scale=m
+Unit B/op scale=Ki
+BenchmarkOne 1 1 ns/op
+Unit
+Unit tests passed
+Unit B/op scale=Ki bad
+Unit ns/op scale=µ
+Units: not a unit line
+`
+	r := NewReader(strings.NewReader(input), "test")
+	var got []string
+	for r.Scan() {
+		if _, err := r.Result(); err != nil {
+			got = append(got, err.Error())
+		} else {
+			got = append(got, "result")
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Lines without a key=value after the unit aren't unit lines.
+	want := "[result test:6: expected key=value]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("want %s, got %v", want, got)
+	}
+
+	md := r.UnitMetadata()
+	wantMD := map[UnitMetadataKey]string{
+		{"ns/op", "assume"}: "exact",
+		{"ns/op", "scale"}:  "µ",
+		{"B/op", "scale"}:   "Ki",
+	}
+	if !reflect.DeepEqual(md, wantMD) {
+		t.Errorf("want metadata %v, got %v", wantMD, md)
+	}
+
+	// Reset clears the metadata.
+	r.Reset(strings.NewReader(""), "test")
+	if len(r.UnitMetadata()) != 0 {
+		t.Errorf("want no metadata after Reset, got %v", r.UnitMetadata())
+	}
+}
+
+func TestReaderFieldSeparator(t *testing.T) {
+	const input = `key: a, b
+BenchmarkOne,100,5 ns/op,3 B/op
+BenchmarkTwo 100 5 ns/op,  3 B/op,
+`
+	// By default, the commas are part of the fields.
+	got := parseAll(t, input)
+	if len(got) != 2 || got[0].Values != nil || got[1].Values[0].Unit != "ns/op," {
+		var buf strings.Builder
+		for _, res := range got {
+			printResult(&buf, res)
+		}
+		t.Errorf("without separator, want malformed results, got:\n%s", buf.String())
+	}
+
+	got = parseAll(t, input, func(r *Reader) { r.FieldSeparator = ',' })
+	want := []*Result{
+		r([]Config{{"key", []byte("a, b")}}, "One", 100, []Value{{Value: 5, Unit: "ns/op"}, {Value: 3, Unit: "B/op"}}),
+		r([]Config{{"key", []byte("a, b")}}, "Two", 100, []Value{{Value: 5, Unit: "ns/op"}, {Value: 3, Unit: "B/op"}}),
+	}
+	if !reflect.DeepEqual(want, got) {
+		var buf strings.Builder
+		for _, res := range got {
+			printResult(&buf, res)
+		}
+		t.Errorf("want:\n%s\ngot:\n%s", "One 100 5 ns/op 3 B/op\nTwo 100 5 ns/op 3 B/op", buf.String())
+	}
+}
+
+func TestReaderUncertainty(t *testing.T) {
+	const input = `BenchmarkOne 1 100 ±5 ns/op 3 B/op
+BenchmarkTwo 1 100 ±x ns/op
+BenchmarkThree 1 100 ±5
+`
+	print := func(got []*Result) string {
+		var buf strings.Builder
+		for _, res := range got {
+			printResult(&buf, res)
+		}
+		return buf.String()
+	}
+
+	// By default, the uncertainty is malformed.
+	got := parseAll(t, input)
+	if len(got) != 3 || got[0].Values != nil {
+		t.Errorf("without Uncertainty, want malformed results, got:\n%s", print(got))
+	}
+
+	got = parseAll(t, input, func(r *Reader) { r.Uncertainty = true })
+	want := []*Result{
+		r([]Config{}, "One", 1, []Value{{Value: 100, Unit: "ns/op", Err: 5}, {Value: 3, Unit: "B/op"}}),
+		errResult("test:2: parsing uncertainty: invalid syntax"),
+		errResult("test:3: missing units"),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%s\ngot:\n%s", print(want), print(got))
+	}
+
+	// By default, Writer omits the uncertainty, so any Reader can
+	// read its output.
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Write(got[0]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "BenchmarkOne 1 100 ns/op 3 B/op\n"; buf.String() != want {
+		t.Errorf("want written %q, got %q", want, buf.String())
+	}
+
+	// With SetUncertainty, the uncertainty round-trips through
+	// Writer.
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetUncertainty(true)
+	if err := w.Write(got[0]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "BenchmarkOne 1 100 ±5 ns/op 3 B/op\n"; buf.String() != want {
+		t.Errorf("want written %q, got %q", want, buf.String())
+	}
+	got2 := parseAll(t, buf.String(), func(r *Reader) { r.Uncertainty = true })
+	if len(got2) != 1 || !got2[0].Equal(got[0]) {
+		t.Errorf("want round-tripped:\n%s\ngot:\n%s", print(got[:1]), print(got2))
+	}
+}
@@ -16,7 +16,11 @@
 // The format is documented at https://golang.org/design/14313-benchmark-format
 package benchfmt
 
-import "bytes"
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
 
 // Result is a single benchmark result and all of its measurements.
 //
@@ -50,6 +54,15 @@ type Result struct {
 	// Values is this benchmark's measurements and their units.
 	Values []Value
 
+	// Meta is scratch space for tools to annotate a Result with
+	// their own data as it passes through a program, such as the
+	// source of the Result or a computed tag. It is not part of
+	// the benchmark format: Reader never sets it, Writer never
+	// writes it, and Equal ignores it. Clone copies the map, but
+	// not the values in it. Reader clears Meta of its Result when
+	// it reads the next result.
+	Meta map[string]interface{}
+
 	// configPos maps from Config.Key to index in FileConfig. This
 	// may be nil, which indicates the index needs to be
 	// constructed.
@@ -74,6 +87,14 @@ type Config struct {
 type Value struct {
 	Value float64
 	Unit  string
+
+	// Err, if non-zero, is a precomputed uncertainty of Value,
+	// such as the half-width of a confidence interval. In the
+	// format, this is written as a field beginning with "±"
+	// between the value and the unit, as in "100 ±5 ns/op". Only
+	// a Reader with Uncertainty set parses this, so a Writer only
+	// writes it if enabled with Writer.SetUncertainty.
+	Err float64
 }
 
 // Clone makes a copy of Result that shares no state with r.
@@ -83,6 +104,7 @@ func (r *Result) Clone() *Result {
 		FullName:   append([]byte(nil), r.FullName...),
 		Iters:      r.Iters,
 		Values:     append([]Value(nil), r.Values...),
+		Meta:       cloneMeta(r.Meta),
 	}
 	for i, cfg := range r.FileConfig {
 		r2.FileConfig[i].Key = cfg.Key
@@ -91,6 +113,85 @@ func (r *Result) Clone() *Result {
 	return r2
 }
 
+// cloneMeta returns a shallow copy of meta.
+func cloneMeta(meta map[string]interface{}) map[string]interface{} {
+	if meta == nil {
+		return nil
+	}
+	meta2 := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		meta2[k] = v
+	}
+	return meta2
+}
+
+// copyInto makes dst a copy of r that shares no state with r, reusing
+// dst's existing storage where possible.
+func (r *Result) copyInto(dst *Result) {
+	if cap(dst.FileConfig) >= len(r.FileConfig) {
+		dst.FileConfig = dst.FileConfig[:len(r.FileConfig)]
+	} else {
+		dst.FileConfig = append(dst.FileConfig[:cap(dst.FileConfig)], make([]Config, len(r.FileConfig)-cap(dst.FileConfig))...)
+	}
+	for i, cfg := range r.FileConfig {
+		dst.FileConfig[i].Key = cfg.Key
+		dst.FileConfig[i].Value = append(dst.FileConfig[i].Value[:0], cfg.Value...)
+	}
+	dst.FullName = append(dst.FullName[:0], r.FullName...)
+	dst.Iters = r.Iters
+	dst.Values = append(dst.Values[:0], r.Values...)
+	dst.Meta = cloneMeta(r.Meta)
+
+	// Rebuild the index.
+	if dst.configPos == nil {
+		dst.configPos = make(map[string]int, len(dst.FileConfig))
+	} else {
+		for k := range dst.configPos {
+			delete(dst.configPos, k)
+		}
+	}
+	for i, cfg := range dst.FileConfig {
+		dst.configPos[cfg.Key] = i
+	}
+}
+
+// Equal reports whether r and other are logically equal. Unlike
+// reflect.DeepEqual, it ignores the order of file configuration keys
+// and the order of values: r and other are equal if they have the
+// same full name and iteration count, the same set of file
+// configuration key/value pairs, and the same multiset of values.
+// Equal ignores Meta.
+func (r *Result) Equal(other *Result) bool {
+	if r.Iters != other.Iters || !bytes.Equal(r.FullName, other.FullName) {
+		return false
+	}
+
+	if len(r.FileConfig) != len(other.FileConfig) {
+		return false
+	}
+	for _, cfg := range r.FileConfig {
+		pos, ok := other.FileConfigIndex(cfg.Key)
+		if !ok || !bytes.Equal(cfg.Value, other.FileConfig[pos].Value) {
+			return false
+		}
+	}
+
+	if len(r.Values) != len(other.Values) {
+		return false
+	}
+	counts := make(map[Value]int, len(r.Values))
+	for _, v := range r.Values {
+		counts[v]++
+	}
+	for _, v := range other.Values {
+		if counts[v] == 0 {
+			return false
+		}
+		counts[v]--
+	}
+	return true
+}
+
 // SetFileConfig sets file configuration key to value, overriding or
 // adding the configuration as necessary. If value is "", it deletes
 // key.
@@ -158,6 +259,75 @@ func (r *Result) FileConfigIndex(key string) (pos int, ok bool) {
 	return
 }
 
+// CommonConfig returns the file configuration key/value pairs that
+// are identical across all of results. A key that is missing from
+// any result is not common. The returned Configs are in the order
+// they appear in the first result and do not share state with
+// results.
+//
+// This is useful for reporting the shared configuration of a set of
+// results once. DistinctConfig returns the complementary
+// per-result configuration.
+func CommonConfig(results []*Result) []Config {
+	if len(results) == 0 {
+		return nil
+	}
+	var common []Config
+outer:
+	for _, cfg := range results[0].FileConfig {
+		for _, res := range results[1:] {
+			pos, ok := res.FileConfigIndex(cfg.Key)
+			if !ok || !bytes.Equal(res.FileConfig[pos].Value, cfg.Value) {
+				continue outer
+			}
+		}
+		common = append(common, Config{cfg.Key, append([]byte(nil), cfg.Value...)})
+	}
+	return common
+}
+
+// DistinctConfig returns the file configuration key/value pairs of
+// res that are not in common, which is typically the result of
+// CommonConfig. The returned Configs are in the order they appear in
+// res and do not share state with res.
+func DistinctConfig(res *Result, common []Config) []Config {
+	var distinct []Config
+outer:
+	for _, cfg := range res.FileConfig {
+		for _, c := range common {
+			if c.Key == cfg.Key && bytes.Equal(c.Value, cfg.Value) {
+				continue outer
+			}
+		}
+		distinct = append(distinct, Config{cfg.Key, append([]byte(nil), cfg.Value...)})
+	}
+	return distinct
+}
+
+// SampleCount returns the number of results that have full name name
+// and exactly the file configuration config (in any order). The Go
+// benchmark format represents each sample of a benchmark as a
+// separate result line, so this is the number of samples behind the
+// measurements for that benchmark configuration, which statistics
+// need to know the true sample size.
+func SampleCount(results []*Result, name string, config []Config) int {
+	n := 0
+outer:
+	for _, res := range results {
+		if string(res.FullName) != name || len(res.FileConfig) != len(config) {
+			continue
+		}
+		for _, c := range config {
+			pos, ok := res.FileConfigIndex(c.Key)
+			if !ok || !bytes.Equal(res.FileConfig[pos].Value, c.Value) {
+				continue outer
+			}
+		}
+		n++
+	}
+	return n
+}
+
 // Value returns the measurement for the given unit.
 func (r *Result) Value(unit string) (float64, bool) {
 	for _, v := range r.Values {
@@ -168,6 +338,37 @@ func (r *Result) Value(unit string) (float64, bool) {
 	return 0, false
 }
 
+// ValueAny returns the measurement for the first of units that r
+// has, along with that unit. This is useful when different tools
+// report the same metric in different units, such as "ns/op" and
+// "sec/op". Note that the value is in the returned unit, so callers
+// that accept units with different scales must convert it. See also
+// benchunit.Value, which converts between units of the same metric.
+func (r *Result) ValueAny(units ...string) (val float64, unit string, ok bool) {
+	for _, unit := range units {
+		if val, ok := r.Value(unit); ok {
+			return val, unit, true
+		}
+	}
+	return 0, "", false
+}
+
+// TotalTime returns the total running time of r in seconds, that is,
+// its iteration count times its time per operation. It uses the
+// "ns/op" value of r, or, failing that, its "sec/op" value, as
+// produced by benchunit.Tidy. If r has neither, TotalTime returns 0,
+// false.
+func (r *Result) TotalTime() (float64, bool) {
+	perOp, unit, ok := r.ValueAny("ns/op", "sec/op")
+	if !ok {
+		return 0, false
+	}
+	if unit == "ns/op" {
+		perOp /= 1e9
+	}
+	return float64(r.Iters) * perOp, true
+}
+
 // BaseName returns the base part of a full benchmark name, without
 // any configuration keys or GOMAXPROCS.
 func BaseName(fullName []byte) []byte {
@@ -190,8 +391,22 @@ func BaseName(fullName []byte) []byte {
 // 3. "-<gomaxprocs>" indicates the GOMAXPROCS of this benchmark. This
 // component can only appear last.
 //
+// A trailing "-<digits>" is ambiguous: "Decode/level-9" could be a
+// sub-benchmark "level" at GOMAXPROCS 9 or a sub-benchmark "level-9"
+// at GOMAXPROCS 1. NameParts takes it to be GOMAXPROCS, unless the
+// final sub-benchmark part is "/<key>-<digits>" and key was registered
+// with RegisterDashParam. Since that registry is process-wide, the
+// result of NameParts for such names depends on what any package in
+// the program has registered.
+//
 // Concatenating the base name and the configuration parts
 // reconstructs the full name.
+//
+// NameParts splits at every "/". The testing package does not escape
+// "/" in sub-benchmark names, so a sub-benchmark name that itself
+// contains a "/", such as a file path, is split into several
+// positional parts. NamePartsKeyed is an alternative for names like
+// this.
 func NameParts(fullName []byte) (baseName []byte, parts [][]byte) {
 	// First pull off any GOMAXPROCS.
 	buf, gomaxprocs := splitGomaxprocs(fullName)
@@ -211,9 +426,96 @@ func NameParts(fullName []byte) (baseName []byte, parts [][]byte) {
 	return nameParts[0], nameParts[1:]
 }
 
+// NamePartsKeyed is like NameParts, but treats a "/" after the first
+// sub-benchmark configuration part as a separator only if it begins a
+// "/<key>=<value>" part. Any other "/" is taken to be part of the
+// preceding part. For example, "Foo/a/b/n=1/c" splits into "Foo",
+// "/a/b", and "/n=1/c", where NameParts would return four parts.
+//
+// This is useful for benchmarks whose positional sub-benchmark names
+// contain slashes, at the cost of not recognizing positional parts
+// after the first part.
+func NamePartsKeyed(fullName []byte) (baseName []byte, parts [][]byte) {
+	base, parts := NameParts(fullName)
+	if len(parts) < 2 {
+		return base, parts
+	}
+	// Merge parts into their predecessor. NameParts returns
+	// sub-slices of fullName, so adjacent parts are contiguous
+	// and merging just extends the slice.
+	out := parts[:1]
+	for _, part := range parts[1:] {
+		if part[0] == '-' || bytes.IndexByte(part, '=') > 1 {
+			// GOMAXPROCS or key=value part.
+			out = append(out, part)
+			continue
+		}
+		prev := out[len(out)-1]
+		out[len(out)-1] = prev[:len(prev)+len(part)]
+	}
+	return base, out
+}
+
+var (
+	dashParamLock sync.Mutex
+	dashParams    atomic.Value // map[string]bool, copy on write
+)
+
+// RegisterDashParam registers key as a sub-benchmark parameter that is
+// written "<key>-<digits>", such as "level-9". When the final part of
+// a benchmark name is "/<key>-<digits>", the digits are taken to be
+// the value of key rather than GOMAXPROCS, so "Decode/level-9" has
+// GOMAXPROCS 1, while "Decode/level-9-8" has GOMAXPROCS 8.
+func RegisterDashParam(key string) {
+	dashParamLock.Lock()
+	defer dashParamLock.Unlock()
+
+	old, _ := dashParams.Load().(map[string]bool)
+	m := make(map[string]bool, len(old)+1)
+	for k := range old {
+		m[k] = true
+	}
+	m[key] = true
+	dashParams.Store(m)
+}
+
+// unregisterDashParam removes key registered by RegisterDashParam.
+// This is for tests, since the registry is global.
+func unregisterDashParam(key string) {
+	dashParamLock.Lock()
+	defer dashParamLock.Unlock()
+
+	old, _ := dashParams.Load().(map[string]bool)
+	m := make(map[string]bool, len(old))
+	for k := range old {
+		if k != key {
+			m[k] = true
+		}
+	}
+	dashParams.Store(m)
+}
+
+// isDashParam reports whether the "-" at buf[i] separates a registered
+// dash parameter key from its value.
+func isDashParam(buf []byte, i int) bool {
+	m, _ := dashParams.Load().(map[string]bool)
+	if len(m) == 0 {
+		return false
+	}
+	slash := bytes.LastIndexByte(buf[:i], '/')
+	if slash < 0 {
+		// Parameters only appear in sub-benchmark parts.
+		return false
+	}
+	return m[string(buf[slash+1:i])]
+}
+
 func splitGomaxprocs(buf []byte) (prefix, gomaxprocs []byte) {
 	for i := len(buf) - 1; i >= 0; i-- {
 		if buf[i] == '-' && i < len(buf)-1 {
+			if isDashParam(buf, i) {
+				break
+			}
 			return buf[:i], buf[i:]
 		} else if !('0' <= buf[i] && buf[i] <= '9') {
 			// Not a digit.
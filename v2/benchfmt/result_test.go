@@ -7,6 +7,7 @@ package benchfmt
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -91,7 +92,7 @@ func TestResultGetFileConfig(t *testing.T) {
 
 func TestResultValue(t *testing.T) {
 	r := &Result{
-		Values: []Value{{42, "ns/op"}, {24, "B/op"}},
+		Values: []Value{{Value: 42, Unit: "ns/op"}, {Value: 24, Unit: "B/op"}},
 	}
 	check := func(unit string, want float64) {
 		t.Helper()
@@ -110,6 +111,131 @@ func TestResultValue(t *testing.T) {
 	}
 }
 
+func TestResultValueAny(t *testing.T) {
+	check := func(r *Result, units []string, want float64, wantUnit string) {
+		t.Helper()
+		got, gotUnit, ok := r.ValueAny(units...)
+		if wantUnit == "" {
+			if ok {
+				t.Errorf("%v: want no value, got %v %s", units, got, gotUnit)
+			}
+			return
+		}
+		if !ok || got != want || gotUnit != wantUnit {
+			t.Errorf("%v: want %v %s, got %v %s (ok=%v)", units, want, wantUnit, got, gotUnit, ok)
+		}
+	}
+	ns := &Result{Values: []Value{{Value: 42, Unit: "ns/op"}, {Value: 24, Unit: "B/op"}}}
+	sec := &Result{Values: []Value{{Value: 4.2e-8, Unit: "sec/op"}}}
+	both := &Result{Values: []Value{{Value: 4.2e-8, Unit: "sec/op"}, {Value: 42, Unit: "ns/op"}}}
+
+	// Whichever unit is present is returned.
+	check(ns, []string{"ns/op", "sec/op"}, 42, "ns/op")
+	check(sec, []string{"ns/op", "sec/op"}, 4.2e-8, "sec/op")
+	// Earlier units are preferred, regardless of the order of
+	// values.
+	check(both, []string{"ns/op", "sec/op"}, 42, "ns/op")
+	check(both, []string{"sec/op", "ns/op"}, 4.2e-8, "sec/op")
+	check(ns, []string{"sec/op", "B/sec"}, 0, "")
+	check(ns, nil, 0, "")
+}
+
+func TestResultEqual(t *testing.T) {
+	base := func() *Result {
+		return &Result{
+			FileConfig: []Config{{"a", []byte("1")}, {"b", []byte("2")}},
+			FullName:   []byte("Name/x=1"),
+			Iters:      100,
+			Values:     []Value{{Value: 42, Unit: "ns/op"}, {Value: 24, Unit: "B/op"}, {Value: 42, Unit: "ns/op"}},
+		}
+	}
+	check := func(name string, r *Result, want bool) {
+		t.Helper()
+		if got := base().Equal(r); got != want {
+			t.Errorf("%s: want %v, got %v", name, want, got)
+		}
+		if got := r.Equal(base()); got != want {
+			t.Errorf("%s (reversed): want %v, got %v", name, want, got)
+		}
+	}
+
+	check("identical", base(), true)
+
+	r := base()
+	r.FileConfig[0], r.FileConfig[1] = r.FileConfig[1], r.FileConfig[0]
+	check("config order", r, true)
+
+	r = base()
+	r.Values[0], r.Values[1] = r.Values[1], r.Values[0]
+	check("value order", r, true)
+
+	r = base()
+	r.Values[1].Value = 25
+	check("value", r, false)
+
+	r = base()
+	r.Values[2] = Value{Value: 24, Unit: "B/op"}
+	check("value multiplicity", r, false)
+
+	r = base()
+	r.Values = r.Values[:2]
+	check("value count", r, false)
+
+	r = base()
+	r.SetFileConfig("b", "3")
+	check("config value", r, false)
+
+	r = base()
+	r.SetFileConfig("c", "3")
+	check("extra config", r, false)
+
+	r = base()
+	r.FullName = []byte("Name/x=2")
+	check("name", r, false)
+
+	r = base()
+	r.Iters = 1
+	check("iters", r, false)
+}
+
+func TestResultMeta(t *testing.T) {
+	res := &Result{FullName: []byte("One"), Iters: 1, Values: []Value{{Value: 1, Unit: "ns/op"}}}
+	res.SetFileConfig("a", "1")
+	res.Meta = map[string]interface{}{"tag": "x", "run": 3}
+
+	// Meta survives Clone, but the clone's map is independent.
+	res2 := res.Clone()
+	if res2.Meta["tag"] != "x" || res2.Meta["run"] != 3 {
+		t.Errorf("want Meta to survive Clone, got %v", res2.Meta)
+	}
+	res2.Meta["tag"] = "y"
+	if res.Meta["tag"] != "x" {
+		t.Errorf("modifying clone's Meta modified original")
+	}
+	if !res.Equal(res2) {
+		t.Errorf("want Equal to ignore Meta")
+	}
+
+	// Meta is never written.
+	var out strings.Builder
+	if err := NewWriter(&out).Write(res); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a: 1\n\nBenchmarkOne 1 1 ns/op\n"; out.String() != want {
+		t.Errorf("want:\n%sgot:\n%s", want, out.String())
+	}
+
+	// The Reader clears Meta for each result.
+	r := NewReader(strings.NewReader("BenchmarkOne 1 1 ns/op\nBenchmarkTwo 1 1 ns/op\n"), "test")
+	r.Scan()
+	res, _ = r.Result()
+	res.Meta = map[string]interface{}{"tag": "x"}
+	r.Scan()
+	if res, _ = r.Result(); res.Meta != nil {
+		t.Errorf("want nil Meta on next result, got %v", res.Meta)
+	}
+}
+
 func TestBaseName(t *testing.T) {
 	check := func(fullName string, want string) {
 		t.Helper()
@@ -158,4 +284,147 @@ func TestNameParts(t *testing.T) {
 	// Empty name
 	check("", "")
 	check("/a/b", "", "/a", "/b")
+	// Slashes in a positional part are not distinguished
+	check("Test/dir/file.go/n=1", "Test", "/dir", "/file.go", "/n=1")
+}
+
+func TestRegisterDashParam(t *testing.T) {
+	RegisterDashParam("level")
+	defer unregisterDashParam("level")
+
+	check := func(fullName string, base string, parts ...string) {
+		t.Helper()
+		got, gotParts := NameParts([]byte(fullName))
+		if string(got) != base || fmt.Sprintf("%q", gotParts) != fmt.Sprintf("%q", parts) {
+			t.Errorf("FullName(%q) = %q, %q, want %q, %q", fullName, got, gotParts, base, parts)
+		}
+	}
+	// A registered parameter keeps its value.
+	check("Decode/level-9", "Decode", "/level-9")
+	check("Decode/size=1/level-9", "Decode", "/size=1", "/level-9")
+	// GOMAXPROCS still follows the parameter.
+	check("Decode/level-9-8", "Decode", "/level-9", "-8")
+	// Other names are unaffected.
+	check("Decode-9", "Decode", "-9")
+	check("Decode/other-9", "Decode", "/other", "-9")
+	check("level-9", "level", "-9")
+	check("Decode/xlevel-9", "Decode", "/xlevel", "-9")
+
+	res := &Result{FullName: []byte("Decode/level-9")}
+	if got := string(extractGomaxprocs(res)); got != "1" {
+		t.Errorf("want GOMAXPROCS 1 for Decode/level-9, got %s", got)
+	}
+
+	// Unregistering restores the default interpretation.
+	unregisterDashParam("level")
+	check("Decode/level-9", "Decode", "/level", "-9")
+}
+
+func TestNamePartsKeyed(t *testing.T) {
+	check := func(fullName string, base string, parts ...string) {
+		t.Helper()
+		got, gotParts := NamePartsKeyed([]byte(fullName))
+		fail := string(got) != string(base)
+		if len(gotParts) != len(parts) {
+			fail = true
+		} else {
+			for i := range parts {
+				if parts[i] != string(gotParts[i]) {
+					fail = true
+				}
+			}
+		}
+		if fail {
+			t.Errorf("NamePartsKeyed(%q) = %q, %q, want %q, %q", fullName, got, gotParts, base, parts)
+		}
+	}
+	check("Test", "Test")
+	check("Test-42", "Test", "-42")
+	check("Test/foo", "Test", "/foo")
+	check("Test/foo=42/bar=24-4", "Test", "/foo=42", "/bar=24", "-4")
+	// Slashes in a positional part
+	check("Test/dir/file.go/n=1-8", "Test", "/dir/file.go", "/n=1", "-8")
+	// Slashes in a value
+	check("Test/path=a/b/n=1", "Test", "/path=a/b", "/n=1")
+	// A later positional part is not recognized
+	check("Test/n=1/fast", "Test", "/n=1/fast")
+	check("Test/a/=b", "Test", "/a/=b")
+	check("Test/foo/", "Test", "/foo/")
+
+	// The parts still reconstruct the full name.
+	const name = "Test/a/b/k=v/c-2"
+	base, parts := NamePartsKeyed([]byte(name))
+	got := string(base)
+	for _, part := range parts {
+		got += string(part)
+	}
+	if got != name {
+		t.Errorf("parts of %q reconstruct %q", name, got)
+	}
+}
+
+func TestCommonConfig(t *testing.T) {
+	str := func(cfgs []Config) string {
+		var kv []string
+		for _, cfg := range cfgs {
+			kv = append(kv, fmt.Sprintf("%s: %s", cfg.Key, cfg.Value))
+		}
+		return fmt.Sprintf("%q", kv)
+	}
+	results := []*Result{
+		r([]Config{{"goos", []byte("linux")}, {"goarch", []byte("amd64")}, {"commit", []byte("a")}}, "Name", 1, nil),
+		r([]Config{{"goarch", []byte("amd64")}, {"commit", []byte("b")}, {"goos", []byte("linux")}}, "Name", 1, nil),
+		r([]Config{{"goos", []byte("linux")}, {"commit", []byte("a")}, {"goarch", []byte("amd64")}, {"cpu", []byte("x")}}, "Name", 1, nil),
+	}
+
+	common := CommonConfig(results)
+	if got, want := str(common), `["goos: linux" "goarch: amd64"]`; got != want {
+		t.Errorf("common: got %s, want %s", got, want)
+	}
+
+	wantDistinct := []string{
+		`["commit: a"]`,
+		`["commit: b"]`,
+		`["commit: a" "cpu: x"]`,
+	}
+	for i, res := range results {
+		if got := str(DistinctConfig(res, common)); got != wantDistinct[i] {
+			t.Errorf("distinct %d: got %s, want %s", i, got, wantDistinct[i])
+		}
+	}
+
+	// A key present in only some results is not common.
+	common = CommonConfig(results[2:])
+	if got, want := str(common), `["goos: linux" "commit: a" "goarch: amd64" "cpu: x"]`; got != want {
+		t.Errorf("common of one: got %s, want %s", got, want)
+	}
+	if got := CommonConfig(nil); got != nil {
+		t.Errorf("common of none: got %s, want nil", str(got))
+	}
+}
+
+func TestSampleCount(t *testing.T) {
+	results := parseAll(t, `goos: linux
+commit: a
+BenchmarkOne 1 1 ns/op
+BenchmarkOne 1 2 ns/op
+BenchmarkTwo 1 1 ns/op
+BenchmarkOne 1 3 ns/op
+commit: b
+BenchmarkOne 1 4 ns/op
+`)
+	linuxA := []Config{{"commit", []byte("a")}, {"goos", []byte("linux")}}
+	check := func(name string, config []Config, want int) {
+		t.Helper()
+		if got := SampleCount(results, name, config); got != want {
+			t.Errorf("%s %v: got %d samples, want %d", name, config, got, want)
+		}
+	}
+	check("One", linuxA, 3)
+	check("Two", linuxA, 1)
+	check("One", []Config{{"goos", []byte("linux")}, {"commit", []byte("b")}}, 1)
+	check("Three", linuxA, 0)
+	// The configuration must match exactly.
+	check("One", linuxA[:1], 0)
+	check("One", nil, 0)
 }
@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A SectionWriter writes Go benchmark results grouped into sections
+// by the value of a file configuration key.
+//
+// Unlike Writer, a SectionWriter buffers all results until Flush is
+// called. Flush then emits each section in sorted order of the key's
+// value. Each section begins with a comment line naming the section
+// followed by the complete file configuration of the first result in
+// that section. Within a section, results appear in the order they
+// were written and file configuration changes are emitted as they
+// would be by Writer.
+//
+// The format can't distinguish a key with an empty value from a
+// missing key, since Writer writes an empty value as a deleted key.
+// SectionWriter likewise puts results with an empty value and results
+// without the key in the same section, labeled "unset".
+type SectionWriter struct {
+	w   *Writer
+	key string
+
+	results []*Result
+}
+
+// NewSectionWriter returns a writer that writes Go benchmark results
+// to w grouped by the value of file configuration key.
+func NewSectionWriter(w io.Writer, key string) *SectionWriter {
+	return &SectionWriter{w: NewWriter(w), key: key}
+}
+
+// Write buffers a copy of benchmark result res. The result will be
+// written to the underlying io.Writer when Flush is called.
+func (s *SectionWriter) Write(res *Result) error {
+	s.results = append(s.results, res.Clone())
+	return nil
+}
+
+// Flush sorts the buffered results by the value of the section key
+// and writes them out in sections. Results without the section key
+// form a section that sorts before all others.
+func (s *SectionWriter) Flush() error {
+	results := s.results
+	s.results = nil
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].GetFileConfig(s.key) < results[j].GetFileConfig(s.key)
+	})
+
+	w := s.w
+	for i, res := range results {
+		val := res.GetFileConfig(s.key)
+		if i == 0 || val != results[i-1].GetFileConfig(s.key) {
+			// Start a new section.
+			comment := fmt.Sprintf("section %s=%s", s.key, val)
+			if val == "" {
+				comment = fmt.Sprintf("section %s unset", s.key)
+			}
+			if err := w.WriteComment(comment); err != nil {
+				return err
+			}
+			w.ForceConfig()
+		}
+		if err := w.Write(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
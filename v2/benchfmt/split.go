@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxOpen is the default limit on the number of files a
+// SplitWriter keeps open at once.
+const DefaultMaxOpen = 64
+
+// A SplitWriter writes Go benchmark results to a set of files in a
+// directory, choosing the file for each result by a key computed from
+// the result. This is the inverse of reading many files with Files:
+// it can, for example, split a combined set of results into one file
+// per "goos".
+//
+// Each output file has its own Writer, so each file has the complete
+// file configuration of its results, exactly as if its results had
+// been written to it alone.
+//
+// To support many distinct keys, a SplitWriter keeps at most MaxOpen
+// files open at once. When it needs to open another file, it closes
+// the least recently written file, and reopens it for appending if it
+// later writes to that file again.
+type SplitWriter struct {
+	// MaxOpen is the maximum number of files to keep open at
+	// once. If MaxOpen is 0, it uses DefaultMaxOpen.
+	MaxOpen int
+
+	dir   string
+	keyFn func(*Result) string
+
+	outs map[string]*splitOut
+	// open is the list of outputs with open files, most recently
+	// written first.
+	open *list.List
+}
+
+// A splitOut is a single output file of a SplitWriter.
+type splitOut struct {
+	path string
+	w    *Writer
+	file *os.File
+	elt  *list.Element // Element of SplitWriter.open, if file is open
+}
+
+// NewSplitWriter returns a SplitWriter that writes each result to the
+// file in dir named by keyFn(result). The key must be a valid file
+// name, not a path. The first time a SplitWriter writes to a file, it
+// creates the file, or truncates it if it already exists.
+func NewSplitWriter(dir string, keyFn func(*Result) string) *SplitWriter {
+	return &SplitWriter{dir: dir, keyFn: keyFn, outs: make(map[string]*splitOut), open: list.New()}
+}
+
+// Write writes benchmark result res to the file for its key.
+func (s *SplitWriter) Write(res *Result) error {
+	key := s.keyFn(res)
+	out := s.outs[key]
+	if out == nil {
+		if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+			return fmt.Errorf("%s: invalid output file name %q", res.FullName, key)
+		}
+		out = &splitOut{path: filepath.Join(s.dir, key), w: NewWriter(nil)}
+		s.outs[key] = out
+	}
+	if err := s.use(out); err != nil {
+		return err
+	}
+	return out.w.Write(res)
+}
+
+// use opens out's file if necessary, and marks it as the most
+// recently written.
+func (s *SplitWriter) use(out *splitOut) error {
+	if out.elt != nil {
+		s.open.MoveToFront(out.elt)
+		return nil
+	}
+
+	max := s.MaxOpen
+	if max <= 0 {
+		max = DefaultMaxOpen
+	}
+	for s.open.Len() >= max {
+		if err := s.closeOut(s.open.Back().Value.(*splitOut)); err != nil {
+			return err
+		}
+	}
+
+	var file *os.File
+	var err error
+	if out.w.w == nil {
+		// First use. Create or truncate the file.
+		file, err = os.Create(out.path)
+	} else {
+		file, err = os.OpenFile(out.path, os.O_WRONLY|os.O_APPEND, 0)
+	}
+	if err != nil {
+		return err
+	}
+	out.file = file
+	out.w.w = file
+	out.elt = s.open.PushFront(out)
+	return nil
+}
+
+// closeOut closes out's file. out's Writer retains its state, so
+// writing to out again reopens the file and continues where it left
+// off.
+func (s *SplitWriter) closeOut(out *splitOut) error {
+	s.open.Remove(out.elt)
+	out.elt = nil
+	file := out.file
+	out.file = nil
+	return file.Close()
+}
+
+// Close closes all open files. It returns the first error
+// encountered, if any.
+func (s *SplitWriter) Close() error {
+	var firstErr error
+	for s.open.Len() > 0 {
+		if err := s.closeOut(s.open.Front().Value.(*splitOut)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSplitWriter(t *testing.T) {
+	const input = `goos: linux
+goarch: amd64
+BenchmarkA 1 1 ns/op
+goos: darwin
+BenchmarkA 1 2 ns/op
+goos: windows
+BenchmarkA 1 3 ns/op
+goos: linux
+goarch: arm64
+BenchmarkB 1 4 ns/op
+goos: darwin
+BenchmarkB 1 5 ns/op
+goarch: amd64
+goos: linux
+note: x
+BenchmarkC 1 6 ns/op
+`
+	dir, err := ioutil.TempDir("", "benchfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewSplitWriter(dir, func(res *Result) string {
+		return res.GetFileConfig("goos") + ".txt"
+	})
+	// Force files to be closed and reopened.
+	s.MaxOpen = 2
+	in := parseAll(t, input)
+	for _, res := range in {
+		if err := s.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read each file back. It must have the right results, each
+	// with its original configuration.
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	var got []string
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, res := range parseAll(t, string(data)) {
+			got = append(got, fmt.Sprintf("%s: %s %s %s %s", filepath.Base(name), res.FullName, res.GetFileConfig("goos"), res.GetFileConfig("goarch"), res.GetFileConfig("note")))
+		}
+	}
+	want := []string{
+		"darwin.txt: A darwin amd64 ",
+		"darwin.txt: B darwin arm64 ",
+		"linux.txt: A linux amd64 ",
+		"linux.txt: B linux arm64 ",
+		"linux.txt: C linux amd64 x",
+		"windows.txt: A windows amd64 ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	// A reopened file continues the configuration of its Writer.
+	data, err := ioutil.ReadFile(filepath.Join(dir, "linux.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLinux := `goos: linux
+goarch: amd64
+
+BenchmarkA 1 1 ns/op
+
+goarch: arm64
+
+BenchmarkB 1 4 ns/op
+
+goarch: amd64
+note: x
+
+BenchmarkC 1 6 ns/op
+`
+	if string(data) != wantLinux {
+		t.Errorf("want linux.txt:\n%s\ngot:\n%s", wantLinux, data)
+	}
+
+	// Keys must be file names.
+	s = NewSplitWriter(dir, func(res *Result) string { return "../escape" })
+	if err := s.Write(in[0]); err == nil {
+		t.Errorf("want error for key with path separator")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+// A SuspicionRule decides whether a benchmark result is implausible,
+// which usually indicates a broken run. For example, the testing
+// package runs a benchmark until it has taken about a second, so a
+// microbenchmark result with an iteration count of 1 probably means
+// the benchmark didn't run properly.
+//
+// The zero SuspicionRule considers no results suspicious.
+type SuspicionRule struct {
+	// MinIters, if non-zero, is the minimum plausible iteration
+	// count. Results with fewer iterations are suspicious.
+	MinIters int
+
+	// MinTotal, if non-zero, is the minimum plausible total
+	// running time of a benchmark in seconds, that is, its
+	// iteration count times its time per operation. Results that
+	// ran for less time are suspicious. This only applies to
+	// results with a "ns/op" or "sec/op" value.
+	MinTotal float64
+}
+
+// DefaultSuspicionRule is the rule used by the ".suspicious"
+// extractor. It flags results that ran for less than 10ms in total,
+// which catches short benchmarks with implausibly low iteration
+// counts without flagging slow benchmarks that legitimately run for
+// only a few iterations.
+//
+// A program may change DefaultSuspicionRule to adjust ".suspicious",
+// but must do so before creating any Extractors or projections that
+// use it: each ".suspicious" Extractor uses the rule in effect when it
+// was created.
+var DefaultSuspicionRule = SuspicionRule{MinTotal: 0.01}
+
+// Suspicious returns whether res is implausible according to rule.
+func (rule SuspicionRule) Suspicious(res *Result) bool {
+	if rule.MinIters != 0 && res.Iters < rule.MinIters {
+		return true
+	}
+	if rule.MinTotal != 0 {
+		if total, ok := res.TotalTime(); ok && total < rule.MinTotal {
+			return true
+		}
+	}
+	return false
+}
+
+// NewExtractorSuspicious returns an extractor that returns "true" if
+// a result is suspicious according to rule, and "false" otherwise.
+func NewExtractorSuspicious(rule SuspicionRule) Extractor {
+	return func(res *Result) []byte {
+		if rule.Suspicious(res) {
+			return suspiciousTrue
+		}
+		return suspiciousFalse
+	}
+}
+
+var suspiciousTrue = []byte("true")
+var suspiciousFalse = []byte("false")
@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import "testing"
+
+func TestSuspicious(t *testing.T) {
+	check := func(rule SuspicionRule, res *Result, want bool) {
+		t.Helper()
+		if got := rule.Suspicious(res); got != want {
+			t.Errorf("%+v: %s %d %v: got %v, want %v", rule, res.FullName, res.Iters, res.Values, got, want)
+		}
+	}
+	micro1 := r(nil, "Micro", 1, []Value{{Value: 50, Unit: "ns/op"}})
+	micro := r(nil, "Micro", 20000000, []Value{{Value: 50, Unit: "ns/op"}})
+	slow := r(nil, "Slow", 1, []Value{{Value: 2e9, Unit: "ns/op"}})
+	tidy := r(nil, "Tidy", 1, []Value{{Value: 50e-9, Unit: "sec/op"}})
+	noTime := r(nil, "NoTime", 1, []Value{{Value: 16, Unit: "B/op"}})
+
+	iters := SuspicionRule{MinIters: 10}
+	check(iters, micro1, true)
+	check(iters, micro, false)
+	check(iters, slow, true)
+
+	check(DefaultSuspicionRule, micro1, true)
+	check(DefaultSuspicionRule, micro, false)
+	check(DefaultSuspicionRule, slow, false)
+	check(DefaultSuspicionRule, tidy, true)
+	check(DefaultSuspicionRule, noTime, false)
+
+	check(SuspicionRule{}, micro1, false)
+
+	// The extractor.
+	ext, err := NewExtractor(".suspicious")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(ext(micro1)); got != "true" {
+		t.Errorf("want .suspicious true, got %s", got)
+	}
+	if got := string(ext(micro)); got != "false" {
+		t.Errorf("want .suspicious false, got %s", got)
+	}
+
+	// The extractor keeps the rule it was created with.
+	defer func(rule SuspicionRule) { DefaultSuspicionRule = rule }(DefaultSuspicionRule)
+	DefaultSuspicionRule = SuspicionRule{}
+	if got := string(ext(micro1)); got != "true" {
+		t.Errorf("after changing the default rule, want .suspicious true, got %s", got)
+	}
+}
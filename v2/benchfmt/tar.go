@@ -0,0 +1,106 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// TarReader reads benchmark results from each file in a tar archive.
+//
+// Like Files, this reader adds a ".file" configuration key to the
+// output Results containing the name of the archive entry the result
+// was read from. Entries other than regular files, such as
+// directories, are skipped.
+type TarReader struct {
+	tr     *tar.Reader
+	reader Reader
+	inFile bool
+	err    error
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NewTarReader returns a reader that reads benchmark results from
+// the tar archive in r. If r is gzip-compressed, NewTarReader
+// transparently decompresses it.
+func NewTarReader(r io.Reader) *TarReader {
+	t := new(TarReader)
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			t.err = err
+			return t
+		}
+		t.tr = tar.NewReader(zr)
+	} else {
+		t.tr = tar.NewReader(br)
+	}
+	return t
+}
+
+// Scan advances the reader to the next result in the archive and
+// returns true if a result was read. The caller should use the
+// Result method to get the result. If an I/O error occurs, or this
+// reaches the end of the archive, it returns false and the caller
+// should use the Err method to check for errors.
+func (t *TarReader) Scan() bool {
+	if t.err != nil {
+		return false
+	}
+
+	for {
+		if !t.inFile {
+			// Find the next regular file.
+			hdr, err := t.tr.Next()
+			if err == io.EOF {
+				// We're out of entries.
+				return false
+			} else if err != nil {
+				t.err = err
+				return false
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			t.inFile = true
+			t.reader.Reset(t.tr, hdr.Name, ".file", hdr.Name)
+		}
+
+		// Try to get the next result.
+		if t.reader.Scan() {
+			return true
+		}
+		if err := t.reader.Err(); err != nil {
+			t.err = err
+			return false
+		}
+		// Just an EOF. Move to the next entry.
+		t.inFile = false
+	}
+}
+
+// Result returns the last result read, or an error if the result was
+// malformed.
+//
+// Parse errors are non-fatal, so the caller can continue to call
+// Scan.
+//
+// The caller should not retain the Result object, as it will be
+// overwritten by the next call to Scan.
+func (t *TarReader) Result() (*Result, error) {
+	return t.reader.Result()
+}
+
+// Err returns the first non-EOF I/O error that was encountered by the
+// TarReader.
+func (t *TarReader) Err() error {
+	return t.err
+}
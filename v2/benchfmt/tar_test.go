@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestTarReader(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	add := func(hdr *tar.Header, data string) {
+		hdr.Size = int64(len(data))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	add(&tar.Header{Name: "dir/a.txt", Typeflag: tar.TypeReg, Mode: 0644}, "key: a\nBenchmarkOne 1 1 ns/op\nBenchmarkTwo 1 2 ns/op\n")
+	add(&tar.Header{Name: "dir/b.txt", Typeflag: tar.TypeReg, Mode: 0644}, "BenchmarkThree 1 3 ns/op\n")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `{.file: dir/a.txt} {key: a} One 1 1 ns/op
+{.file: dir/a.txt} {key: a} Two 1 2 ns/op
+{.file: dir/b.txt} Three 1 3 ns/op
+`
+	check := func(t *testing.T, tr *TarReader) {
+		t.Helper()
+		got := new(strings.Builder)
+		for tr.Scan() {
+			res, err := tr.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			printResult(got, res)
+		}
+		if err := tr.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want {
+			t.Errorf("want:\n%sgot:\n%s", want, got)
+		}
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		check(t, NewTarReader(bytes.NewReader(tarBuf.Bytes())))
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		var gzBuf bytes.Buffer
+		zw := gzip.NewWriter(&gzBuf)
+		zw.Write(tarBuf.Bytes())
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		check(t, NewTarReader(&gzBuf))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		tr := NewTarReader(bytes.NewReader(tarBuf.Bytes()[:600]))
+		for tr.Scan() {
+		}
+		if tr.Err() == nil {
+			t.Errorf("want error reading truncated archive")
+		}
+	})
+}
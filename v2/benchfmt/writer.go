@@ -8,6 +8,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // A Writer writes the Go benchmark format.
@@ -18,8 +21,52 @@ type Writer struct {
 	first      bool
 	fileConfig map[string][]byte
 	order      []string
+
+	// force indicates the next Write should emit the complete
+	// file configuration.
+	force bool
+
+	// dedup is the duplicate suppression mode. lastKey is the
+	// dedupKey of the last result written, and seen is the set of
+	// dedupKeys of all results written in DedupAll mode.
+	dedup   Dedup
+	keyBuf  []byte
+	lastKey string
+	seen    map[string]struct{}
+
+	// sortValues indicates that Write should emit values sorted by
+	// unit. sorted is scratch space for sorting.
+	sortValues bool
+	sorted     []Value
+
+	// uncertainty indicates that Write should emit Value.Err.
+	uncertainty bool
 }
 
+// Dedup is a mode for suppressing duplicate results in a Writer.
+//
+// Two results are duplicates if they have the same full name,
+// iteration count, file configuration (in any order), and multiset of
+// values (in any order). File configuration is compared as Write would
+// write it, so a key with an empty or whitespace-only value is the
+// same as a missing key.
+type Dedup int
+
+const (
+	// DedupNone writes every result.
+	DedupNone Dedup = iota
+
+	// DedupConsecutive suppresses a result that duplicates the
+	// result written immediately before it.
+	DedupConsecutive
+
+	// DedupAll suppresses a result that duplicates any result
+	// written before it. The Writer remembers every distinct
+	// result it writes, so this uses memory proportional to the
+	// size of the output.
+	DedupAll
+)
+
 // NewWriter returns a writer that writes Go benchmark results to w.
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{w: w, first: true, fileConfig: make(map[string][]byte)}
@@ -28,23 +75,54 @@ func NewWriter(w io.Writer) *Writer {
 // Write writes benchmark result res to w. If res's file configuration
 // differs from the current file configuration in w, it first emits
 // the appropriate file configuration lines.
+//
+// The format cannot represent leading whitespace in a file
+// configuration value, so Write omits it, and writes a value that is
+// empty or consists only of whitespace as a deleted key. This way,
+// reading the output produces the same configuration as Write wrote.
+//
+// By default, Write omits the uncertainty of each value. See
+// SetUncertainty.
 func (w *Writer) Write(res *Result) error {
-	// If any file config changed, write out the changes.
-	if len(w.fileConfig) != len(res.FileConfig) {
-		w.writeFileConfig(res)
-	} else {
-		for _, cfg := range res.FileConfig {
-			if val, ok := w.fileConfig[cfg.Key]; !ok || !bytes.Equal(cfg.Value, val) {
-				w.writeFileConfig(res)
-				break
+	if w.dedup != DedupNone {
+		w.keyBuf = dedupKey(w.keyBuf[:0], res, w.uncertainty)
+		if w.dedup == DedupConsecutive {
+			if string(w.keyBuf) == w.lastKey {
+				return nil
+			}
+			w.lastKey = string(w.keyBuf)
+		} else {
+			if _, ok := w.seen[string(w.keyBuf)]; ok {
+				return nil
 			}
+			w.seen[string(w.keyBuf)] = struct{}{}
 		}
 	}
 
+	// If any file config changed, write out the changes.
+	if w.force {
+		w.writeFileConfig(res, true)
+		w.force = false
+	} else if w.configChanged(res) {
+		w.writeFileConfig(res, false)
+	}
+
 	// Print the benchmark line.
 	fmt.Fprintf(&w.buf, "Benchmark%s %d", res.FullName, res.Iters)
-	for _, val := range res.Values {
-		fmt.Fprintf(&w.buf, " %v %s", val.Value, val.Unit)
+	vals := res.Values
+	if w.sortValues {
+		w.sorted = append(w.sorted[:0], vals...)
+		vals = w.sorted
+		sort.SliceStable(vals, func(i, j int) bool {
+			return vals[i].Unit < vals[j].Unit
+		})
+	}
+	for _, val := range vals {
+		if val.Err != 0 && w.uncertainty {
+			fmt.Fprintf(&w.buf, " %v ±%v %s", val.Value, val.Err, val.Unit)
+		} else {
+			fmt.Fprintf(&w.buf, " %v %s", val.Value, val.Unit)
+		}
 	}
 	w.buf.WriteByte('\n')
 
@@ -57,7 +135,157 @@ func (w *Writer) Write(res *Result) error {
 	return err
 }
 
-func (w *Writer) writeFileConfig(res *Result) {
+// ForceConfig causes the next call to Write to emit the complete file
+// configuration of its result, including keys whose values have not
+// changed since the previous result. Keys that were deleted are still
+// emitted as deletions, so the output reads the same as a whole, but
+// each part of the output starting at a forced configuration block
+// can also be read on its own. This is useful for marking the start
+// of sections that may later be split apart.
+func (w *Writer) ForceConfig() {
+	w.force = true
+}
+
+// WriteComment writes text to w as a comment line, starting with
+// "# ". Readers ignore comment lines, so this is useful for annotating
+// output for people. Like a file configuration block, a comment that
+// follows results is preceded by a blank line. text must not contain
+// a newline.
+func (w *Writer) WriteComment(text string) error {
+	if strings.ContainsAny(text, "\r\n") {
+		return fmt.Errorf("comment contains a newline: %q", text)
+	}
+	if !w.first {
+		w.buf.WriteByte('\n')
+		w.first = true
+	}
+	fmt.Fprintf(&w.buf, "# %s\n", text)
+	_, err := w.w.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// SetDedup sets the mode for suppressing duplicate results in
+// subsequent calls to Write. Suppressed results are not written at
+// all, including any changes to the file configuration. The default
+// mode is DedupNone.
+func (w *Writer) SetDedup(mode Dedup) {
+	w.dedup = mode
+	w.lastKey = ""
+	if mode == DedupAll {
+		if w.seen == nil {
+			w.seen = make(map[string]struct{})
+		}
+	} else {
+		w.seen = nil
+	}
+}
+
+// SetSortValues sets whether subsequent calls to Write emit each
+// result's values sorted by unit, rather than in the order of
+// Result.Values. This makes the output independent of the order in
+// which the producer of a result reported its measurements. Values
+// with the same unit keep their relative order. This does not modify
+// the Results passed to Write.
+func (w *Writer) SetSortValues(on bool) {
+	w.sortValues = on
+}
+
+// SetUncertainty sets whether subsequent calls to Write emit the
+// uncertainty of each value that has one, as in "100 ±5 ns/op". Only
+// a Reader with Uncertainty set can read such lines; other Readers,
+// including those of most tools, treat them as malformed and drop
+// the result. Hence, by default, Write omits Value.Err, and callers
+// should only enable this when the output will be read by a Reader
+// with Uncertainty set.
+func (w *Writer) SetUncertainty(on bool) {
+	w.uncertainty = on
+}
+
+// dedupKey appends a canonical encoding of res to buf. Duplicate
+// results have the same encoding. If uncertainty is false, the
+// encoding omits the uncertainty of each value, just as Write does,
+// so results that would be written identically are duplicates.
+func dedupKey(buf []byte, res *Result, uncertainty bool) []byte {
+	// Names, keys, and values cannot contain newlines, so this
+	// encoding is unambiguous.
+	buf = append(buf, res.FullName...)
+	buf = append(buf, '\n')
+	buf = strconv.AppendInt(buf, int64(res.Iters), 10)
+	buf = append(buf, '\n')
+
+	cfgs := make([]Config, 0, len(res.FileConfig))
+	for _, cfg := range res.FileConfig {
+		if val := trimValue(cfg.Value); len(val) > 0 {
+			cfgs = append(cfgs, Config{cfg.Key, val})
+		}
+	}
+	sort.Slice(cfgs, func(i, j int) bool {
+		return cfgs[i].Key < cfgs[j].Key
+	})
+	for _, cfg := range cfgs {
+		buf = append(buf, cfg.Key...)
+		buf = append(buf, ": "...)
+		buf = append(buf, cfg.Value...)
+		buf = append(buf, '\n')
+	}
+
+	vals := append([]Value(nil), res.Values...)
+	sort.Slice(vals, func(i, j int) bool {
+		if vals[i].Unit != vals[j].Unit {
+			return vals[i].Unit < vals[j].Unit
+		}
+		if vals[i].Value != vals[j].Value {
+			return vals[i].Value < vals[j].Value
+		}
+		return uncertainty && vals[i].Err < vals[j].Err
+	})
+	for _, val := range vals {
+		buf = strconv.AppendFloat(buf, val.Value, 'g', -1, 64)
+		if val.Err != 0 && uncertainty {
+			buf = append(buf, " ±"...)
+			buf = strconv.AppendFloat(buf, val.Err, 'g', -1, 64)
+		}
+		buf = append(buf, ' ')
+		buf = append(buf, val.Unit...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// trimValue returns the part of file configuration value val that can
+// be represented in the format. Leading whitespace is indistinguishable
+// from the separator after the key.
+func trimValue(val []byte) []byte {
+	for len(val) > 0 && (val[0] == ' ' || val[0] == '\t') {
+		val = val[1:]
+	}
+	return val
+}
+
+// configChanged returns whether res's file configuration differs from
+// w's current file configuration.
+func (w *Writer) configChanged(res *Result) bool {
+	n := 0
+	for _, cfg := range res.FileConfig {
+		val := trimValue(cfg.Value)
+		if len(val) == 0 {
+			// Equivalent to a missing key.
+			continue
+		}
+		n++
+		if have, ok := w.fileConfig[cfg.Key]; !ok || !bytes.Equal(val, have) {
+			return true
+		}
+	}
+	return n != len(w.fileConfig)
+}
+
+// writeFileConfig writes the file configuration lines necessary to
+// change w's current file configuration to res's. If full is true,
+// it writes every key in res's configuration, even those that did
+// not change.
+func (w *Writer) writeFileConfig(res *Result, full bool) {
 	if !w.first {
 		// Configuration blocks after results get an extra blank.
 		w.buf.WriteByte('\n')
@@ -69,7 +297,11 @@ func (w *Writer) writeFileConfig(res *Result) {
 		key := w.order[i]
 		have := w.fileConfig[key]
 		idx, ok := res.FileConfigIndex(key)
-		if !ok {
+		var val []byte
+		if ok {
+			val = trimValue(res.FileConfig[idx].Value)
+		}
+		if len(val) == 0 {
 			// Key was deleted.
 			fmt.Fprintf(&w.buf, "%s:\n", key)
 			delete(w.fileConfig, key)
@@ -78,14 +310,16 @@ func (w *Writer) writeFileConfig(res *Result) {
 			i--
 			continue
 		}
-		if bytes.Equal(have, res.FileConfig[idx].Value) {
+		if bytes.Equal(have, val) {
 			// Value did not change.
+			if full {
+				fmt.Fprintf(&w.buf, "%s: %s\n", key, val)
+			}
 			continue
 		}
 		// Value changed.
-		cfg := &res.FileConfig[idx]
-		fmt.Fprintf(&w.buf, "%s: %s\n", key, cfg.Value)
-		w.fileConfig[key] = append(w.fileConfig[key][:0], cfg.Value...)
+		fmt.Fprintf(&w.buf, "%s: %s\n", key, val)
+		w.fileConfig[key] = append(w.fileConfig[key][:0], val...)
 	}
 
 	// Find new keys.
@@ -94,9 +328,14 @@ func (w *Writer) writeFileConfig(res *Result) {
 			if _, ok := w.fileConfig[cfg.Key]; ok {
 				continue
 			}
+			val := trimValue(cfg.Value)
+			if len(val) == 0 {
+				// Equivalent to a missing key.
+				continue
+			}
 			// New key.
-			fmt.Fprintf(&w.buf, "%s: %s\n", cfg.Key, cfg.Value)
-			w.fileConfig[cfg.Key] = append([]byte(nil), cfg.Value...)
+			fmt.Fprintf(&w.buf, "%s: %s\n", cfg.Key, val)
+			w.fileConfig[cfg.Key] = append([]byte(nil), val...)
 			w.order = append(w.order, cfg.Key)
 		}
 	}
@@ -6,6 +6,7 @@ package benchfmt
 
 import (
 	"bytes"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -29,6 +30,10 @@ BenchmarkOne 1 1 ns/op
 key1: val2
 key: b
 
+BenchmarkOne 1 1 ns/op
+
+cpu: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+
 BenchmarkOne 1 1 ns/op
 `
 
@@ -49,3 +54,300 @@ BenchmarkOne 1 1 ns/op
 		t.Fatalf("want:\n%sgot:\n%s", input, out.String())
 	}
 }
+
+func TestWriterForceConfig(t *testing.T) {
+	res := &Result{FullName: []byte("One"), Iters: 1, Values: []Value{{Value: 1, Unit: "ns/op"}}}
+	res.SetFileConfig("a", "1")
+	res.SetFileConfig("b", "2")
+
+	out := new(strings.Builder)
+	w := NewWriter(out)
+	write := func() {
+		t.Helper()
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+	write()
+	w.ForceConfig()
+	write()
+	write()
+	res.SetFileConfig("a", "")
+	w.ForceConfig()
+	write()
+
+	const want = `a: 1
+b: 2
+
+BenchmarkOne 1 1 ns/op
+BenchmarkOne 1 1 ns/op
+
+a: 1
+b: 2
+
+BenchmarkOne 1 1 ns/op
+BenchmarkOne 1 1 ns/op
+
+a:
+b: 2
+
+BenchmarkOne 1 1 ns/op
+`
+	if out.String() != want {
+		t.Fatalf("want:\n%sgot:\n%s", want, out.String())
+	}
+}
+
+func TestSectionWriter(t *testing.T) {
+	const input = `goos: linux
+x: 1
+
+BenchmarkA 1 1 ns/op
+
+goos: darwin
+
+BenchmarkB 1 1 ns/op
+
+goos: linux
+x: 2
+
+BenchmarkC 1 1 ns/op
+
+goos:
+
+BenchmarkD 1 1 ns/op
+`
+	const want = `# section goos unset
+x: 2
+
+BenchmarkD 1 1 ns/op
+
+# section goos=darwin
+x: 1
+goos: darwin
+
+BenchmarkB 1 1 ns/op
+
+# section goos=linux
+x: 1
+goos: linux
+
+BenchmarkA 1 1 ns/op
+
+x: 2
+
+BenchmarkC 1 1 ns/op
+`
+
+	out := new(strings.Builder)
+	w := NewSectionWriter(out, "goos")
+	r := NewReader(bytes.NewReader([]byte(input)), "test")
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Fatalf("want no output before Flush, got:\n%s", out.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != want {
+		t.Fatalf("want:\n%sgot:\n%s", want, out.String())
+	}
+
+	if err := NewWriter(out).WriteComment("a\nb"); err == nil {
+		t.Errorf("want error for comment with a newline")
+	}
+}
+
+func TestWriterWhitespace(t *testing.T) {
+	res := &Result{FullName: []byte("One"), Iters: 1, Values: []Value{{Value: 1, Unit: "ns/op"}}}
+	res.SetFileConfig("interior", "a b\t c")
+	res.SetFileConfig("trailing", "x  ")
+	res.SetFileConfig("leading", " \tx")
+	res.SetFileConfig("space", "   ")
+
+	out := new(strings.Builder)
+	w := NewWriter(out)
+	write := func() {
+		t.Helper()
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+	// Writing the same configuration again doesn't emit any
+	// configuration, even with the whitespace-only value.
+	write()
+	// Changing a key to only whitespace deletes it.
+	res.SetFileConfig("trailing", "\t")
+	write()
+
+	want := "interior: a b\t c\ntrailing: x  \nleading: x\n\nBenchmarkOne 1 1 ns/op\nBenchmarkOne 1 1 ns/op\n\ntrailing:\n\nBenchmarkOne 1 1 ns/op\n"
+	if out.String() != want {
+		t.Fatalf("want:\n%q\ngot:\n%q", want, out.String())
+	}
+
+	// Read it back.
+	got := parseAll(t, out.String())
+	if len(got) != 3 {
+		t.Fatalf("want 3 results, got %d", len(got))
+	}
+	check := func(res *Result, key, want string, wantOK bool) {
+		t.Helper()
+		_, ok := res.FileConfigIndex(key)
+		if val := res.GetFileConfig(key); val != want || ok != wantOK {
+			t.Errorf("key %s: want %q, %v; got %q, %v", key, want, wantOK, val, ok)
+		}
+	}
+	for _, res := range got {
+		check(res, "interior", "a b\t c", true)
+		check(res, "leading", "x", true)
+		check(res, "space", "", false)
+	}
+	check(got[0], "trailing", "x  ", true)
+	check(got[2], "trailing", "", false)
+}
+
+func TestWriterDedup(t *testing.T) {
+	const input = `a: 1
+
+BenchmarkX 1 1 ns/op 2 B/op
+BenchmarkX 1 1 ns/op 2 B/op
+BenchmarkX 1 2 B/op 1 ns/op
+BenchmarkX 2 1 ns/op 2 B/op
+BenchmarkY 1 1 ns/op
+
+a: 2
+
+BenchmarkX 1 1 ns/op 2 B/op
+
+a: 1
+
+BenchmarkY 1 1 ns/op
+BenchmarkX 1 1 ns/op 2 B/op
+`
+	write := func(mode Dedup) string {
+		out := new(strings.Builder)
+		w := NewWriter(out)
+		w.SetDedup(mode)
+		r := NewReader(strings.NewReader(input), "test")
+		for r.Scan() {
+			res, err := r.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out.String()
+	}
+
+	if got := write(DedupNone); got != input {
+		t.Errorf("DedupNone: want:\n%sgot:\n%s", input, got)
+	}
+
+	// Value order doesn't matter, but iteration count does.
+	const wantConsecutive = `a: 1
+
+BenchmarkX 1 1 ns/op 2 B/op
+BenchmarkX 2 1 ns/op 2 B/op
+BenchmarkY 1 1 ns/op
+
+a: 2
+
+BenchmarkX 1 1 ns/op 2 B/op
+
+a: 1
+
+BenchmarkY 1 1 ns/op
+BenchmarkX 1 1 ns/op 2 B/op
+`
+	if got := write(DedupConsecutive); got != wantConsecutive {
+		t.Errorf("DedupConsecutive: want:\n%sgot:\n%s", wantConsecutive, got)
+	}
+
+	// The configuration change back to a: 1 is suppressed along
+	// with the results.
+	const wantAll = `a: 1
+
+BenchmarkX 1 1 ns/op 2 B/op
+BenchmarkX 2 1 ns/op 2 B/op
+BenchmarkY 1 1 ns/op
+
+a: 2
+
+BenchmarkX 1 1 ns/op 2 B/op
+`
+	if got := write(DedupAll); got != wantAll {
+		t.Errorf("DedupAll: want:\n%sgot:\n%s", wantAll, got)
+	}
+
+	// Results that differ only in uncertainty are duplicates
+	// unless the uncertainty is written.
+	for _, uncertainty := range []bool{false, true} {
+		out := new(strings.Builder)
+		w := NewWriter(out)
+		w.SetDedup(DedupAll)
+		w.SetUncertainty(uncertainty)
+		for _, err := range []float64{0.5, 0.25} {
+			if err := w.Write(r(nil, "X", 1, []Value{{Value: 1, Unit: "ns/op", Err: err}})); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := 1
+		if uncertainty {
+			want = 2
+		}
+		if got := strings.Count(out.String(), "BenchmarkX"); got != want {
+			t.Errorf("uncertainty %v: want %d results, got:\n%s", uncertainty, want, out.String())
+		}
+	}
+}
+
+func TestWriterSortValues(t *testing.T) {
+	vals1 := []Value{{Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}, {Value: 3, Unit: "allocs/op", Err: 0.5}}
+	vals2 := []Value{{Value: 3, Unit: "allocs/op", Err: 0.5}, {Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}}
+	res1 := r(nil, "X", 1, vals1)
+	res2 := r(nil, "X", 1, vals2)
+
+	write := func(res *Result, sort bool) string {
+		out := new(strings.Builder)
+		w := NewWriter(out)
+		w.SetSortValues(sort)
+		w.SetUncertainty(true)
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	// By default, values are written in order.
+	if got1, got2 := write(res1, false), write(res2, false); got1 == got2 {
+		t.Errorf("without SetSortValues, want different output, got %q for both", got1)
+	}
+
+	// With sorting, the output is canonical and units stay with
+	// their values.
+	const want = "BenchmarkX 1 2 B/op 3 ±0.5 allocs/op 1 ns/op\n"
+	if got := write(res1, true); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := write(res2, true); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Write doesn't modify the Result.
+	if !reflect.DeepEqual(res2.Values, []Value{{Value: 3, Unit: "allocs/op", Err: 0.5}, {Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}}) {
+		t.Errorf("Write modified Values: %v", res2.Values)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A GroupStat is a statistic of the values of a unit across a group
+// of results.
+type GroupStat struct {
+	// Name is appended to a unit, separated by "-", to form the
+	// unit of the annotation, as in "ns/op-median".
+	Name string
+
+	// Compute returns the statistic of vals, which is non-empty
+	// and sorted in increasing order.
+	Compute func(vals []float64) float64
+}
+
+// Statistics for StatAnnotator.
+var (
+	StatMedian = GroupStat{"median", func(vals []float64) float64 {
+		if len(vals)%2 == 1 {
+			return vals[len(vals)/2]
+		}
+		return (vals[len(vals)/2-1] + vals[len(vals)/2]) / 2
+	}}
+	StatMean = GroupStat{"mean", func(vals []float64) float64 {
+		var sum float64
+		for _, v := range vals {
+			sum += v
+		}
+		return sum / float64(len(vals))
+	}}
+	StatMin = GroupStat{"min", func(vals []float64) float64 {
+		return vals[0]
+	}}
+	StatMax = GroupStat{"max", func(vals []float64) float64 {
+		return vals[len(vals)-1]
+	}}
+)
+
+// A StatAnnotator annotates results with statistics of the group
+// they belong to, where results are grouped by a projection. For
+// example, it can add to each result a "ns/op-median" value giving
+// the median "ns/op" of all results of the same benchmark, so
+// downstream tools can consume the statistics alongside the
+// measurements.
+//
+// Since the statistics depend on every result in a group, a
+// StatAnnotator must see all results before it can return any.
+//
+// A StatAnnotator is also a Stage that emits the annotated results
+// when it is flushed.
+type StatAnnotator struct {
+	stats []GroupStat
+	buf   groupBuffer
+}
+
+// NewStatAnnotator returns a StatAnnotator that groups results by
+// group and annotates them with each of stats.
+func NewStatAnnotator(group *Schema, stats ...GroupStat) *StatAnnotator {
+	return &StatAnnotator{stats: stats, buf: newGroupBuffer(group)}
+}
+
+// Add adds res to a. Results that are filtered by the group
+// projection are dropped. Add retains a copy of res, so the caller
+// may reuse res.
+func (a *StatAnnotator) Add(res *benchfmt.Result) {
+	a.buf.add(res, true, nil)
+}
+
+// Results returns the results added to a in the order they were
+// added. For each value of each result, the result also has a value
+// for each statistic of a, computed over all of the values with the
+// same unit in the result's group. Units that are synonyms according
+// to benchunit.CanonicalUnit are treated as the same unit.
+//
+// Results should be called after all results have been added. It
+// resets a, so a can then be reused for a new set of results.
+func (a *StatAnnotator) Results() []*benchfmt.Result {
+	return a.buf.annotate(func(vals []float64) valueAnnotator {
+		computed := make([]float64, len(a.stats))
+		for i, stat := range a.stats {
+			computed[i] = stat.Compute(vals)
+		}
+		return func(val benchfmt.Value, out []benchfmt.Value) []benchfmt.Value {
+			for i, stat := range a.stats {
+				out = append(out, benchfmt.Value{Value: computed[i], Unit: val.Unit + "-" + stat.Name})
+			}
+			return out
+		}
+	})
+}
+
+// Process adds res to a. a takes ownership of res.
+func (a *StatAnnotator) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	a.buf.add(res, false, nil)
+	return nil
+}
+
+// Flush emits the annotated results of a.
+func (a *StatAnnotator) Flush(emit func(*benchfmt.Result)) error {
+	for _, res := range a.Results() {
+		emit(res)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestStatAnnotator(t *testing.T) {
+	const input = `BenchmarkA 1 10 ns/op 1 B/op
+BenchmarkB 1 100 ns/op
+BenchmarkA 1 30 ns/op 3 B/op
+BenchmarkA 1 20 ns/op 8 B/op
+BenchmarkB 1 200 ns/op
+BenchmarkC/x=1 1 5 ns/op
+`
+	var p ProjectionParser
+	group, err := p.Parse(".name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewStatAnnotator(group, StatMedian, StatMax)
+	r := benchfmt.NewReader(strings.NewReader(input), "test")
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.Add(res)
+	}
+
+	var got []string
+	for _, res := range a.Results() {
+		line := string(res.FullName)
+		for _, val := range res.Values {
+			line += fmt.Sprintf(" %v %s", val.Value, val.Unit)
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"A 10 ns/op 1 B/op 20 ns/op-median 30 ns/op-max 3 B/op-median 8 B/op-max",
+		"B 100 ns/op 150 ns/op-median 200 ns/op-max",
+		"A 30 ns/op 3 B/op 20 ns/op-median 30 ns/op-max 3 B/op-median 8 B/op-max",
+		"A 20 ns/op 8 B/op 20 ns/op-median 30 ns/op-max 3 B/op-median 8 B/op-max",
+		"B 200 ns/op 150 ns/op-median 200 ns/op-max",
+		"C/x=1 5 ns/op 5 ns/op-median 5 ns/op-max",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if len(a.Results()) != 0 {
+		t.Errorf("want no results after Results")
+	}
+}
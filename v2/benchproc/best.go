@@ -0,0 +1,102 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"sort"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// KeepBest keeps only the best n results in each group of results,
+// where results are grouped by a projection and ranked by the value
+// of a particular unit. For example, this can reduce repeated runs of
+// each benchmark to just the fastest run.
+//
+// Whether lower or higher values are better depends on the unit, so
+// the caller specifies it. Ties are broken in favor of results that
+// were added earlier, so the outcome is deterministic.
+type KeepBest struct {
+	group  *Schema
+	unit   string
+	n      int
+	higher bool
+
+	seq    int
+	groups map[Config][]bestEntry
+}
+
+type bestEntry struct {
+	res *benchfmt.Result
+	val float64
+	seq int
+}
+
+// NewKeepBest returns a KeepBest that groups results by group and
+// keeps the n results in each group with the best value of unit. If
+// higherIsBetter is false, the best values are the lowest, as for
+// times such as "sec/op". If it is true, they are the highest, as for
+// rates such as "B/s". Tools can take this from a unit's
+// benchfmt.UnitBetterKey metadata.
+func NewKeepBest(group *Schema, unit string, n int, higherIsBetter bool) *KeepBest {
+	return &KeepBest{group: group, unit: unit, n: n, higher: higherIsBetter, groups: make(map[Config][]bestEntry)}
+}
+
+// Add adds res to k. Results that are filtered by the group
+// projection or that have no value for k's unit are dropped. Add
+// retains a copy of res, so the caller may reuse res.
+func (k *KeepBest) Add(res *benchfmt.Result) {
+	if k.n <= 0 {
+		return
+	}
+	val, ok := res.Value(k.unit)
+	if !ok {
+		return
+	}
+	cfg, ok := k.group.Project(res)
+	if !ok {
+		return
+	}
+
+	seq := k.seq
+	k.seq++
+	entries := k.groups[cfg]
+	// Find the insertion point. Since seq is increasing, placing
+	// this entry after all entries with an equal value breaks
+	// ties in favor of earlier results.
+	i := sort.Search(len(entries), func(i int) bool {
+		if k.higher {
+			return entries[i].val < val
+		}
+		return entries[i].val > val
+	})
+	if i >= k.n {
+		// Not among the best.
+		return
+	}
+	if len(entries) < k.n {
+		entries = append(entries, bestEntry{})
+	}
+	copy(entries[i+1:], entries[i:])
+	entries[i] = bestEntry{res.Clone(), val, seq}
+	k.groups[cfg] = entries
+}
+
+// Results returns the kept results from all groups in the order
+// they were added.
+func (k *KeepBest) Results() []*benchfmt.Result {
+	var all []bestEntry
+	for _, entries := range k.groups {
+		all = append(all, entries...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].seq < all[j].seq
+	})
+	out := make([]*benchfmt.Result, len(all))
+	for i, e := range all {
+		out[i] = e.res
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestKeepBest(t *testing.T) {
+	results := []*benchfmt.Result{
+		{FullName: []byte("A"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("1")}}, Values: []benchfmt.Value{{Value: 30, Unit: "ns/op"}}},
+		{FullName: []byte("B"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("1")}}, Values: []benchfmt.Value{{Value: 50, Unit: "ns/op"}}},
+		{FullName: []byte("A"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("2")}}, Values: []benchfmt.Value{{Value: 20, Unit: "ns/op"}}},
+		{FullName: []byte("B"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("2")}}, Values: []benchfmt.Value{{Value: 40, Unit: "ns/op"}}},
+		{FullName: []byte("A"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("3")}}, Values: []benchfmt.Value{{Value: 25, Unit: "ns/op"}}},
+		{FullName: []byte("B"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("3")}}, Values: []benchfmt.Value{{Value: 40, Unit: "ns/op"}}},
+		{FullName: []byte("C"), FileConfig: []benchfmt.Config{{Key: "run", Value: []byte("3")}}, Values: []benchfmt.Value{{Value: 1, Unit: "B/op"}}},
+	}
+	str := func(results []*benchfmt.Result) string {
+		var out []string
+		for _, res := range results {
+			val, _ := res.Value("ns/op")
+			out = append(out, fmt.Sprintf("%s@%s=%v", res.FullName, res.GetFileConfig("run"), val))
+		}
+		return strings.Join(out, " ")
+	}
+	test := func(n int, higher bool, want string) {
+		t.Helper()
+		var p ProjectionParser
+		group, err := p.Parse(".name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := NewKeepBest(group, "ns/op", n, higher)
+		for _, res := range results {
+			k.Add(res)
+		}
+		if got := str(k.Results()); got != want {
+			t.Errorf("n=%d higher=%v: got %s, want %s", n, higher, got, want)
+		}
+	}
+
+	// B ties between runs 2 and 3, so the earlier run wins. C has
+	// no ns/op, so it's dropped.
+	test(1, false, "A@2=20 B@2=40")
+	test(2, false, "A@2=20 B@2=40 A@3=25 B@3=40")
+	test(5, false, "A@1=30 B@1=50 A@2=20 B@2=40 A@3=25 B@3=40")
+	test(0, false, "")
+
+	// With higher values better, B's runs 2 and 3 tie for
+	// second, so again the earlier run wins.
+	test(1, true, "A@1=30 B@1=50")
+	test(2, true, "A@1=30 B@1=50 B@2=40 A@3=25")
+}
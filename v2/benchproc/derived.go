@@ -0,0 +1,80 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"math"
+	"strconv"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+// DerivedInvalid is the value of a derived field for a Result where
+// the field could not be computed, because an input key is missing
+// or not a number, or the computed value is not finite.
+const DerivedInvalid = "<invalid>"
+
+// AddDerived appends a field called name to s whose value is computed
+// from the numeric values of keys a and b by f. For example, passing
+// Ratio as f projects the ratio of two numeric file configuration
+// keys, so Results with the same ratio have the same Config. a and b
+// may be any keys accepted by benchfmt.NewExtractor. Their values may
+// have SI or binary prefixes, as accepted by benchunit.ParseScaled.
+//
+// The computed value is formatted in the shortest form that
+// represents it exactly. Where the value can't be computed, the field
+// is DerivedInvalid.
+//
+// The field uses first-observation order. Unlike keys in a projection
+// expression, a and b are not excluded from any ".config" field.
+func (s *Schema) AddDerived(name, a, b string, f func(a, b float64) float64) (Field, error) {
+	extA, err := benchfmt.NewExtractor(a)
+	if err != nil {
+		return Field{}, err
+	}
+	extB, err := benchfmt.NewExtractor(b)
+	if err != nil {
+		return Field{}, err
+	}
+	field := s.addField(s.root, name)
+	field.order = make(map[string]int)
+	field.orderName = "first"
+	var buf []byte
+	s.project = append(s.project, func(r *benchfmt.Result, row *[]string) bool {
+		va, okA := parseDerivedInput(extA(r))
+		vb, okB := parseDerivedInput(extB(r))
+		val := math.NaN()
+		if okA && okB {
+			val = f(va, vb)
+		}
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			(*row)[field.idx] = DerivedInvalid
+			return true
+		}
+		buf = strconv.AppendFloat(buf[:0], val, 'g', -1, 64)
+		(*row)[field.idx] = s.intern(buf)
+		return true
+	})
+	return field, nil
+}
+
+func parseDerivedInput(val []byte) (float64, bool) {
+	if val == nil {
+		return 0, false
+	}
+	x, err := benchunit.ParseScaled(string(val))
+	return x, err == nil
+}
+
+// Difference returns a - b. It can be passed to Schema.AddDerived.
+func Difference(a, b float64) float64 {
+	return a - b
+}
+
+// Ratio returns a / b. It can be passed to Schema.AddDerived.
+func Ratio(a, b float64) float64 {
+	return a / b
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestAddDerived(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse(".name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := s.AddDerived("speedup", "before", "after", Ratio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := make(map[Config][]string)
+	for _, in := range []string{"10/5", "4/2", "3/1", "1k/500", "x/1", "1/", "1/0", "6/3"} {
+		kv := strings.Split(in, "/")
+		res := &benchfmt.Result{FullName: []byte("A")}
+		res.SetFileConfig("before", kv[0])
+		res.SetFileConfig("after", kv[1])
+		cfg, ok := s.Project(res)
+		if !ok {
+			t.Fatalf("%s: unexpectedly filtered", in)
+		}
+		groups[cfg] = append(groups[cfg], in)
+	}
+
+	cfgs := s.Configs()
+	SortConfigs(cfgs)
+	var got []string
+	for _, cfg := range cfgs {
+		got = append(got, fmt.Sprintf("%s=%s", cfg.Get(field), strings.Join(groups[cfg], ",")))
+	}
+	want := "2=10/5,4/2,1k/500,6/3 3=3/1 <invalid>=x/1,1/,1/0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+
+	if _, err := s.AddDerived("bad", "", "after", Difference); err == nil {
+		t.Errorf("want error for empty key")
+	}
+}
@@ -11,6 +11,7 @@
 package benchproc
 
 import (
+	"bytes"
 	"fmt"
 
 	"golang.org/x/perf/v2/benchfmt"
@@ -31,13 +32,28 @@ type Filter struct {
 	usesUnits bool
 }
 
+// anyNameKey is the query key that matches if any "/key=value" part
+// of a benchmark name has a matching value, regardless of its key.
+const anyNameKey = "/*"
+
 // NewFilter constructs a result filter from a boolean query.
+//
+// In addition to the keys accepted by benchfmt.NewExtractor, the
+// query may use the key ".unit" to match the units of individual
+// values, and the key "/*" to match any name configuration key. For
+// example, "/*:large" matches both "Foo/size=large" and
+// "Bar/input=large".
 func NewFilter(query string) (*Filter, error) {
 	q, err := kvql.Parse(query)
 	if err != nil {
 		return nil, err
 	}
+	return newFilter(query, q)
+}
 
+// newFilter constructs a result filter from parsed query q. query is
+// the original query text, for error messages.
+func newFilter(query string, q kvql.Query) (*Filter, error) {
 	// Collect extractors for different keys.
 	f := &Filter{
 		query:      q,
@@ -60,6 +76,8 @@ func NewFilter(query string) (*Filter, error) {
 			}
 			if q.Key == ".unit" {
 				f.usesUnits = true
+			} else if q.Key == anyNameKey {
+				// Matched directly against the name parts.
 			} else {
 				ext, err := benchfmt.NewExtractor(q.Key)
 				if err != nil {
@@ -77,6 +95,73 @@ func NewFilter(query string) (*Filter, error) {
 	return f, nil
 }
 
+// A Facet is one branch of the top-level OR of a Filter.
+type Facet struct {
+	// Label is the branch in query syntax, such as ".unit:ns/op".
+	Label string
+
+	// Filter matches the results of this facet. It is the
+	// original Filter with the top-level OR replaced by just this
+	// branch.
+	Filter *Filter
+}
+
+// Facets splits f into one Facet for each branch of its top-level OR,
+// so a tool can process each facet separately rather than their
+// union. For example, the query "goos:linux .unit:(ns/op B/op)" has
+// two facets, labeled ".unit:ns/op" and ".unit:B/op", whose Filters
+// are equivalent to "goos:linux .unit:ns/op" and "goos:linux
+// .unit:B/op".
+//
+// The top-level OR is either the whole query, or the first OR that
+// is directly ANDed with the rest of the query. Branches with the same
+// label are only returned once. If f has no top-level OR, Facets
+// returns a single Facet for all of f.
+func (f *Filter) Facets() []Facet {
+	// Find the top-level OR.
+	var or *kvql.QueryOp
+	var and *kvql.QueryOp
+	orPos := -1
+	if op, ok := f.query.(*kvql.QueryOp); ok {
+		if op.Op == kvql.OpOr {
+			or = op
+		} else if op.Op == kvql.OpAnd {
+			for i, sub := range op.Exprs {
+				if sub, ok := sub.(*kvql.QueryOp); ok && sub.Op == kvql.OpOr {
+					or, and, orPos = sub, op, i
+					break
+				}
+			}
+		}
+	}
+	if or == nil || len(or.Exprs) == 0 {
+		return []Facet{{f.query.String(), f}}
+	}
+
+	var facets []Facet
+	seen := make(map[string]bool)
+	for _, branch := range or.Exprs {
+		label := branch.String()
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		q := branch
+		if and != nil {
+			exprs := append([]kvql.Query(nil), and.Exprs...)
+			exprs[orPos] = branch
+			q = &kvql.QueryOp{Op: kvql.OpAnd, Exprs: exprs}
+		}
+		facet, err := newFilter(q.String(), q)
+		if err != nil {
+			// Every key in q was already accepted by f.
+			panic("constructing facet: " + err.Error())
+		}
+		facets = append(facets, Facet{label, facet})
+	}
+	return facets
+}
+
 // Match returns the set of res.Values that match f.
 func (f *Filter) Match(res *benchfmt.Result) Match {
 	// TODO: Most of the time file keys don't change. If Result
@@ -157,6 +242,12 @@ func (f *Filter) match(res *benchfmt.Result, node kvql.Query) (m matchBuilder) {
 			}
 			return
 		}
+		if node.Key == anyNameKey {
+			if matchAnyNamePart(res, node) {
+				m.setAll()
+			}
+			return
+		}
 		ext := f.extractors[node.Key]
 		if node.Match(ext(res)) {
 			m.setAll()
@@ -165,6 +256,26 @@ func (f *Filter) match(res *benchfmt.Result, node kvql.Query) (m matchBuilder) {
 	return
 }
 
+// matchAnyNamePart reports whether the value of any "/key=value" part
+// of res's name matches node. Positional parts and the GOMAXPROCS
+// suffix have no key, so they never match.
+func matchAnyNamePart(res *benchfmt.Result, node *kvql.QueryMatch) bool {
+	_, parts := benchfmt.NameParts(res.FullName)
+	for _, part := range parts {
+		if part[0] != '/' {
+			continue
+		}
+		eq := bytes.IndexByte(part, '=')
+		if eq < 0 {
+			continue
+		}
+		if node.Match(part[eq+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
 type matchBuilder struct {
 	head uint64
 	rest []uint64
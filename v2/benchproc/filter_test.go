@@ -7,6 +7,7 @@ package benchproc
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"golang.org/x/perf/v2/benchfmt"
 )
@@ -16,8 +17,8 @@ func TestFilter(t *testing.T) {
 		FileConfig: []benchfmt.Config{{"f1", []byte("v1")}, {"f2", []byte("v2")}},
 		FullName:   []byte("Name/n1=v3"),
 		Values: []benchfmt.Value{
-			{100, "ns/op"},
-			{100, "B/op"},
+			{Value: 100, Unit: "ns/op"},
+			{Value: 100, Unit: "B/op"},
 		},
 	}).Clone()
 	const ALL = 0b11
@@ -56,6 +57,122 @@ func TestFilter(t *testing.T) {
 		check(t, ".fullname:Name/n1=v3", ALL)
 	})
 
+	t.Run("any name key", func(t *testing.T) {
+		f, err := NewFilter("/*:large")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, name := range []string{"A/size=large", "B/n=1/input=large", "C/size=small", "D/large", "E", "F/size=larger"} {
+			res := &benchfmt.Result{FullName: []byte(name)}
+			if m := f.Match(res); m.All() {
+				got = append(got, name)
+			}
+		}
+		// Positional parts have no key, so "D/large" doesn't
+		// match.
+		want := "[A/size=large B/n=1/input=large]"
+		if fmt.Sprint(got) != want {
+			t.Errorf("want %s, got %v", want, got)
+		}
+
+		check(t, "/*:v3", ALL)
+		check(t, "/*:v1", NONE)
+		check(t, "/*:~v", ALL)
+		check(t, "-/*:v3", NONE)
+	})
+
+	t.Run("gomaxprocs", func(t *testing.T) {
+		f, err := NewFilter(".gomaxprocs:@>=4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, name := range []string{"Name", "Name-2", "Name-4", "Name-8", "Name-16", "Name/gomaxprocs=32"} {
+			res := &benchfmt.Result{FullName: []byte(name)}
+			if m := f.Match(res); m.All() {
+				got = append(got, name)
+			}
+		}
+		// The missing GOMAXPROCS is implicitly 1, and "16" must
+		// compare numerically greater than "4".
+		want := "[Name-4 Name-8 Name-16 Name/gomaxprocs=32]"
+		if fmt.Sprint(got) != want {
+			t.Errorf("want %s, got %v", want, got)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		recent := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+		dates := []string{"2023-12-31", "2024-01-01", "2024-06-01 12:00:00", recent, "unknown", ""}
+		filterDates := func(query string) string {
+			t.Helper()
+			f, err := NewFilter(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, date := range dates {
+				res := &benchfmt.Result{FullName: []byte("Name")}
+				res.SetFileConfig("commit-date", date)
+				if m := f.Match(res); m.All() {
+					got = append(got, date)
+				}
+			}
+			return fmt.Sprint(got)
+		}
+		// Values that don't parse as dates are excluded.
+		want := fmt.Sprint([]string{"2024-01-01", "2024-06-01 12:00:00", recent})
+		if got := filterDates("commit-date:@since(2024-01-01)"); got != want {
+			t.Errorf("absolute: want %s, got %s", want, got)
+		}
+		want = fmt.Sprint([]string{recent})
+		if got := filterDates("commit-date:@since(30d)"); got != want {
+			t.Errorf("relative: want %s, got %s", want, got)
+		}
+	})
+
+	t.Run("cpu", func(t *testing.T) {
+		// The value of the "cpu" key emitted by go test contains
+		// spaces and regexp metacharacters.
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("cpu", "Intel(R) Xeon(R) CPU @ 2.20GHz")
+		for query, want := range map[string]bool{
+			`cpu:~Xeon`: true,
+			`cpu:"Intel\(R\) Xeon\(R\) CPU @ 2\.20GHz"`: true,
+			`cpu:~"CPU @ 2\.20GHz"`:                     true,
+			// Values are regexps, so the parentheses
+			// must be escaped to match literally.
+			`cpu:"Intel(R) Xeon(R) CPU @ 2.20GHz"`: false,
+		} {
+			f, err := NewFilter(query)
+			if err != nil {
+				t.Fatalf("%s: %s", query, err)
+			}
+			if m := f.Match(res); m.All() != want {
+				t.Errorf("%s: got %v, want %v", query, m.All(), want)
+			}
+		}
+	})
+
+	t.Run("suspicious", func(t *testing.T) {
+		f, err := NewFilter("-.suspicious:true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, iters := range []int{1, 1000000} {
+			res := &benchfmt.Result{FullName: []byte("Name"), Iters: iters, Values: []benchfmt.Value{{Value: 100, Unit: "ns/op"}}}
+			if m := f.Match(res); m.All() {
+				got = append(got, fmt.Sprint(iters))
+			}
+		}
+		want := "[1000000]"
+		if fmt.Sprint(got) != want {
+			t.Errorf("want %s, got %v", want, got)
+		}
+	})
+
 	t.Run("units", func(t *testing.T) {
 		check(t, ".unit:ns/op", 0b01)
 		check(t, ".unit:B/op", 0b10)
@@ -94,3 +211,48 @@ func TestFilter(t *testing.T) {
 		}
 	})
 }
+
+func TestFilterFacets(t *testing.T) {
+	res := (&benchfmt.Result{
+		FileConfig: []benchfmt.Config{{"goos", []byte("linux")}},
+		FullName:   []byte("Name"),
+		Values: []benchfmt.Value{
+			{Value: 100, Unit: "ns/op"},
+			{Value: 100, Unit: "B/op"},
+			{Value: 1, Unit: "allocs/op"},
+		},
+	}).Clone()
+
+	check := func(query string, want ...string) {
+		t.Helper()
+		f, err := NewFilter(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, facet := range f.Facets() {
+			// Record which values each facet matches.
+			m := facet.Filter.Match(res)
+			var units []string
+			for i, val := range res.Values {
+				if m.Test(i) {
+					units = append(units, val.Unit)
+				}
+			}
+			got = append(got, fmt.Sprintf("%s=%v", facet.Label, units))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: want facets %v, got %v", query, want, got)
+		}
+	}
+
+	check(".unit:(ns/op B/op)", ".unit:ns/op=[ns/op]", ".unit:B/op=[B/op]")
+	// The rest of the query applies to every facet.
+	check("goos:linux .unit:(ns/op B/op)", ".unit:ns/op=[ns/op]", ".unit:B/op=[B/op]")
+	check("goos:darwin .unit:(ns/op B/op)", ".unit:ns/op=[]", ".unit:B/op=[]")
+	// Duplicate branches are one facet.
+	check(".unit:ns/op OR .unit:allocs/op OR .unit:ns/op", ".unit:ns/op=[ns/op]", ".unit:allocs/op=[allocs/op]")
+	// No top-level OR.
+	check("goos:linux", "goos:linux=[ns/op B/op allocs/op]")
+	check("-(.unit:ns/op OR .unit:B/op)", "-(.unit:ns/op OR .unit:B/op)=[allocs/op]")
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import "fmt"
+
+// A ProjectionFlag is a flag.Value for a projection expression.
+//
+// A ProjectionFlag checks the syntax of the expression when the flag
+// is set, so a malformed projection is reported as a usage error when
+// the command line is parsed. It defers actually parsing the
+// expression with its ProjectionParser until Schema is first called.
+// Since projections parsed by the same ProjectionParser are mutually
+// exclusive, this way the projection is parsed only once, with its
+// final value, and callers control the order projections are parsed
+// in by the order they call Schema.
+type ProjectionFlag struct {
+	parser *ProjectionParser
+	expr   string
+	schema *Schema
+}
+
+// NewProjectionFlag returns a ProjectionFlag that will parse its
+// projection using parser and whose default value is expr. It panics
+// if expr is not a valid projection expression.
+//
+// The result is typically passed to flag.Var.
+func NewProjectionFlag(parser *ProjectionParser, expr string) *ProjectionFlag {
+	f := &ProjectionFlag{parser: parser}
+	if err := f.Set(expr); err != nil {
+		panic(fmt.Sprintf("bad default projection %q: %s", expr, err))
+	}
+	return f
+}
+
+// String returns the projection expression.
+func (f *ProjectionFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// Set checks the syntax of projection expression expr and sets the
+// flag to expr.
+func (f *ProjectionFlag) Set(expr string) error {
+	if f.schema != nil {
+		return fmt.Errorf("projection already parsed")
+	}
+	// Check the syntax using a scratch parser, which doesn't
+	// affect the exclusions of f.parser.
+	var p ProjectionParser
+	p.MissingValue = f.parser.MissingValue
+	if _, err := p.Parse(expr); err != nil {
+		return err
+	}
+	f.expr = expr
+	return nil
+}
+
+// Schema returns the Schema for the flag's projection expression. The
+// first call parses the expression with the flag's ProjectionParser.
+// Later calls return the same Schema.
+func (f *ProjectionFlag) Schema() *Schema {
+	if f.schema == nil {
+		s, err := f.parser.Parse(f.expr)
+		if err != nil {
+			// Set already checked the expression.
+			panic(fmt.Sprintf("parsing projection %q: %s", f.expr, err))
+		}
+		f.schema = s
+	}
+	return f.schema
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestProjectionFlag(t *testing.T) {
+	newFlags := func() (*flag.FlagSet, *ProjectionFlag, *ProjectionFlag) {
+		var parser ProjectionParser
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		col := NewProjectionFlag(&parser, "commit")
+		row := NewProjectionFlag(&parser, ".config")
+		fs.Var(col, "col", "")
+		fs.Var(row, "row", "")
+		return fs, col, row
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		fs, _, _ := newFlags()
+		err := fs.Parse([]string{"-col", "goos@nonesuch"})
+		if err == nil {
+			t.Fatalf("want error")
+		}
+		for _, want := range []string{`invalid value "goos@nonesuch" for flag -col`, `unknown order "nonesuch"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("want error containing %q, got %s", want, err)
+			}
+		}
+	})
+
+	t.Run("exclusion", func(t *testing.T) {
+		fs, col, row := newFlags()
+		if err := fs.Parse([]string{"-col", "goos"}); err != nil {
+			t.Fatal(err)
+		}
+		colBy, rowBy := col.Schema(), row.Schema()
+		if colBy != col.Schema() {
+			t.Errorf("want the same Schema from each call")
+		}
+
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("goos", "linux")
+		res.SetFileConfig("commit", "abc")
+		colCfg, _ := colBy.Project(res)
+		rowCfg, _ := rowBy.Project(res)
+		// The flag's value, not the default, is excluded from
+		// .config.
+		if got, want := colCfg.String(), "goos:linux"; got != want {
+			t.Errorf("col: want %s, got %s", want, got)
+		}
+		if got, want := rowCfg.String(), "commit:abc"; got != want {
+			t.Errorf("row: want %s, got %s", want, got)
+		}
+	})
+}
+
+func TestProjectionFlagBadDefault(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("want panic for bad default")
+		}
+	}()
+	var parser ProjectionParser
+	NewProjectionFlag(&parser, "goos@nonesuch")
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+// A MissingCell is a combination of a row and a column of a grid
+// that has no data.
+type MissingCell struct {
+	Row, Col Config
+}
+
+// GridCompleteness returns every combination of a Config in rows and
+// a Config in cols for which present returns false. These are the
+// cells of a row×col grid that have no data, such as a benchmark
+// that didn't run in some configuration. The result is in row-major
+// order, following the order of rows and cols. It is empty if the
+// grid is complete.
+func GridCompleteness(rows, cols []Config, present func(row, col Config) bool) []MissingCell {
+	var missing []MissingCell
+	for _, row := range rows {
+		for _, col := range cols {
+			if !present(row, col) {
+				missing = append(missing, MissingCell{row, col})
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestGridCompleteness(t *testing.T) {
+	var p ProjectionParser
+	rowBy, _ := p.Parse(".name")
+	colBy, _ := p.Parse("goos")
+
+	type cell struct{ row, col Config }
+	have := make(map[cell]bool)
+	for _, name := range []string{"A@linux", "A@darwin", "B@linux", "C@darwin", "C@linux", "A@windows"} {
+		parts := strings.Split(name, "@")
+		res := &benchfmt.Result{FullName: []byte(parts[0])}
+		res.SetFileConfig("goos", parts[1])
+		row, _ := rowBy.Project(res)
+		col, _ := colBy.Project(res)
+		have[cell{row, col}] = true
+	}
+	rows, cols := rowBy.Configs(), colBy.Configs()
+	present := func(row, col Config) bool {
+		return have[cell{row, col}]
+	}
+
+	var got []string
+	for _, m := range GridCompleteness(rows, cols, present) {
+		got = append(got, fmt.Sprintf("%s@%s", m.Row.Get(rowBy.Fields()[0]), m.Col.Get(colBy.Fields()[0])))
+	}
+	if want := "B@darwin B@windows C@windows"; strings.Join(got, " ") != want {
+		t.Errorf("want missing %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// A complete grid has no missing cells.
+	if m := GridCompleteness(rows, cols[:1], func(row, col Config) bool { return true }); len(m) != 0 {
+		t.Errorf("want no missing cells, got %v", m)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"sort"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+// A groupBuffer buffers results grouped by a projection, along with
+// the values of each unit in each group, until all results have been
+// seen. It does the bookkeeping for annotators whose annotations
+// depend on every result in a group, such as StatAnnotator,
+// ScoreAnnotator, and Normalizer, which each supply a function that
+// prepares the annotations of one unit in one group.
+type groupBuffer struct {
+	group *Schema
+	// interleave places the annotations of each value immediately
+	// after that value, rather than after all of the values of
+	// the result.
+	interleave bool
+
+	results []*benchfmt.Result
+	cfgs    []Config
+	// vals records the values of each unit in each group.
+	vals map[Config]map[string][]float64
+}
+
+// A valueAnnotator appends the annotations of val to out and returns
+// the extended slice.
+type valueAnnotator func(val benchfmt.Value, out []benchfmt.Value) []benchfmt.Value
+
+func newGroupBuffer(group *Schema) groupBuffer {
+	return groupBuffer{group: group, vals: make(map[Config]map[string][]float64)}
+}
+
+// add records res if it isn't filtered by the group projection. If
+// clone is true, add records a copy of res. If keep is non-nil, add
+// only records the values of res at the indexes i for which keep(i)
+// is true in the group's values, though the recorded result retains
+// all of its values.
+func (b *groupBuffer) add(res *benchfmt.Result, clone bool, keep func(i int) bool) {
+	cfg, ok := b.group.Project(res)
+	if !ok {
+		return
+	}
+	if clone {
+		res = res.Clone()
+	}
+	units := b.vals[cfg]
+	if units == nil {
+		units = make(map[string][]float64)
+		b.vals[cfg] = units
+	}
+	for i, val := range res.Values {
+		if keep != nil && !keep(i) {
+			continue
+		}
+		// Combine values whose units are synonyms.
+		unit := benchunit.CanonicalUnit(val.Unit)
+		units[unit] = append(units[unit], val.Value)
+	}
+	b.results = append(b.results, res)
+	b.cfgs = append(b.cfgs, cfg)
+}
+
+// annotate calls prepare with the sorted values of each unit in each
+// group and annotates each value of that unit in that group using the
+// returned valueAnnotator. If prepare returns nil, those values are
+// left unannotated. Units that are synonyms according to
+// benchunit.CanonicalUnit are treated as the same unit.
+//
+// annotate returns the recorded results in the order they were added
+// and resets b.
+func (b *groupBuffer) annotate(prepare func(vals []float64) valueAnnotator) []*benchfmt.Result {
+	type groupKey struct {
+		cfg  Config
+		unit string
+	}
+	prepared := make(map[groupKey]valueAnnotator)
+	for cfg, units := range b.vals {
+		for unit, vals := range units {
+			sort.Float64s(vals)
+			prepared[groupKey{cfg, unit}] = prepare(vals)
+		}
+	}
+	b.vals = make(map[Config]map[string][]float64)
+
+	for i, res := range b.results {
+		vals := res.Values
+		out := vals
+		if b.interleave {
+			out = make([]benchfmt.Value, 0, 2*len(vals))
+		}
+		for _, val := range vals {
+			if b.interleave {
+				out = append(out, val)
+			}
+			if f := prepared[groupKey{b.cfgs[i], benchunit.CanonicalUnit(val.Unit)}]; f != nil {
+				out = f(val, out)
+			}
+		}
+		res.Values = out
+	}
+	results := b.results
+	b.results, b.cfgs = nil, nil
+	return results
+}
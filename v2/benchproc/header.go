@@ -25,7 +25,8 @@ type ConfigHeader struct {
 	Len int
 
 	// Value is the value that all Configs have in common for
-	// Field.
+	// Field. If Field has a format function set by
+	// Field.SetFormat, this is the formatted value.
 	Value string
 }
 
@@ -42,6 +43,10 @@ type ConfigHeader struct {
 // node are identical for fields 0 through i-1. Hence, the
 // ConfigHeaders also logically form a tree because each level
 // subdivides the level above it.
+//
+// Nodes are divided by the Configs' actual values, even if a field
+// has a format function that maps different values to the same
+// display value.
 func NewConfigHeader(configs []Config) (levels [][]*ConfigHeader) {
 	if len(configs) == 0 {
 		return nil
@@ -56,12 +61,18 @@ func NewConfigHeader(configs []Config) (levels [][]*ConfigHeader) {
 	for i, field := range fields {
 		for _, parent := range prevLevel {
 			var node *ConfigHeader
+			var nodeVal string
 			for j, config := range configs[parent.Start : parent.Start+parent.Len] {
 				val := config.Get(field)
-				if node != nil && val == node.Value {
+				if node != nil && val == nodeVal {
 					node.Len++
 				} else {
-					node = &ConfigHeader{i, parent.Start + j, 1, val}
+					disp := val
+					if field.format != nil {
+						disp = field.format(val)
+					}
+					node = &ConfigHeader{i, parent.Start + j, 1, disp}
+					nodeVal = val
 					levels[i] = append(levels[i], node)
 				}
 			}
@@ -129,3 +129,31 @@ a1 -- --
 		checkHeader(t, hdr, "")
 	})
 }
+
+func TestConfigHeaderFormat(t *testing.T) {
+	cm := newConfigMaker()
+	c1 := cm.new("commit", "abc1234aaaa", "goos", "linux")
+	c2 := cm.new("commit", "abc1234aaaa", "goos", "darwin")
+	// Same prefix, but a different commit.
+	c3 := cm.new("commit", "abc1234bbbb", "goos", "linux")
+	fields := cm.s.Fields()
+	fields[0].SetFormat(func(val string) string {
+		if len(val) > 7 {
+			return val[:7]
+		}
+		return val
+	})
+	hdr := NewConfigHeader([]Config{c1, c2, c3})
+	checkHeader(t, hdr, `
+abc1234 -- abc1234
+linux darwin linux`)
+
+	// Grouping and the Configs themselves still use the full
+	// value.
+	if c1 == c3 {
+		t.Errorf("want distinct Configs for distinct commits")
+	}
+	if got := c1.Get(fields[0]); got != "abc1234aaaa" {
+		t.Errorf("want full value abc1234aaaa, got %s", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kvql
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts is the layouts accepted by ParseDate.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.RubyDate,
+}
+
+// ParseDate parses s as a date and time. See benchproc.ParseDate.
+func ParseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseAge parses s as a duration. In addition to the units accepted
+// by time.ParseDuration, it accepts a single number of days or weeks,
+// such as "30d" or "2w".
+func parseAge(s string) (time.Duration, bool) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(s[:len(s)-len(suffix)], 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			return time.Duration(n * float64(unit)), true
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
@@ -12,15 +12,41 @@
 //   match   = "(" expr ")"
 //           | "-" match
 //           | "*"
-//           | word ":" (word | "(" {word} ")") .
+//           | word ":" (value | "(" {value} ")") .
+//   value   = ["~"] word | "@" cmp .
+//   cmp     = ("<" | "<=" | ">" | ">=" | "==" | "!=") number
+//           | "since" "(" word ")" .
 //   word    = [^ ():]* | "\"" [^"]* "\""
+//
+// Values are regexps. By default, they are anchored at the beginning
+// and end, so they must match the entire value of a key. A value
+// prefixed with "~" is unanchored and may match any substring of
+// the value of a key.
+//
+// A value prefixed with "@" is a numeric comparison. For example,
+// "key:@>=4" matches if the value of key is a number greater than or
+// equal to 4. Both the value of key and the number in the comparison
+// may have an SI or binary prefix, as formatted by benchunit, so
+// "key:@>=1Ki" matches values such as "1024", "2Mi", and "1.5k".
+// Values of key that are not numbers never match a comparison.
+//
+// A value of the form "@since(date)" is a date comparison. It matches
+// if the value of key is a date at or after date, where both are in
+// a format accepted by ParseDate. Instead of a date, the argument may
+// be an age, such as "30d", "2w", or "12h", in which case it matches
+// dates no older than that age when the query was parsed. Values of
+// key that are not dates never match.
 package kvql
 
 import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 	"unicode"
+
+	"golang.org/x/perf/v2/benchunit"
 )
 
 // Parse parses a query string into a Query tree.
@@ -178,13 +204,13 @@ func (p *parser) match(i int) (Query, int) {
 		switch p.toks[i+2].Kind {
 		default:
 			return nil, p.error(i, "expected key:value")
-		case 'w':
+		case 'w', '~', '@':
 			// Simple match.
 			return p.matchWord(i+2, off, key)
 		case '(':
 			// Multi-match.
 			terms := []Query{}
-			for i += 3; p.toks[i].Kind == 'w'; {
+			for i += 3; p.toks[i].Kind == 'w' || p.toks[i].Kind == '~' || p.toks[i].Kind == '@'; {
 				var q Query
 				q, i = p.matchWord(i, off, key)
 				terms = append(terms, q)
@@ -203,17 +229,80 @@ func (p *parser) match(i int) (Query, int) {
 }
 
 func (p *parser) matchWord(i int, keyOff int, key string) (Query, int) {
+	if p.toks[i].Kind == '@' {
+		return p.matchCmp(i+1, keyOff, key)
+	}
+	anchored := true
+	if p.toks[i].Kind == '~' {
+		// Unanchored match.
+		anchored = false
+		i++
+		if p.toks[i].Kind != 'w' {
+			return nil, p.error(i, "expected value")
+		}
+	}
 	if p.toks[i].Kind != 'w' {
 		panic("matchWord called on non-word token")
 	}
 	// Make sure the regexp is well-formed before we manipulate
 	// the string.
-	_, err := regexp.Compile(p.toks[i].Tok)
+	re, err := regexp.Compile(p.toks[i].Tok)
 	if err != nil {
 		return nil, p.error(i, err.Error())
 	}
 
 	// Now make the regexp we'll actually use.
-	re := regexp.MustCompile("^(?:" + p.toks[i].Tok + ")$")
-	return &QueryMatch{keyOff, key, re, p.toks[i].Tok}, i + 1
+	if anchored {
+		re = regexp.MustCompile("^(?:" + p.toks[i].Tok + ")$")
+	}
+	return &QueryMatch{Off: keyOff, Key: key, match: re, mStr: p.toks[i].Tok, Anchored: anchored}, i + 1
+}
+
+// cmpOps is the numeric comparison operators. Longer operators must
+// come before their prefixes.
+var cmpOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+func (p *parser) matchCmp(i int, keyOff int, key string) (Query, int) {
+	if p.toks[i].Kind != 'w' {
+		return nil, p.error(i, "expected comparison")
+	}
+	tok := p.toks[i].Tok
+	if tok == "since" {
+		return p.matchSince(i+1, keyOff, key)
+	}
+	for _, op := range cmpOps {
+		if !strings.HasPrefix(tok, op) {
+			continue
+		}
+		val, err := benchunit.ParseScaled(tok[len(op):])
+		if err != nil {
+			return nil, p.error(i, "expected number after "+op)
+		}
+		return &QueryMatch{Off: keyOff, Key: key, mStr: tok, Anchored: true, cmp: op, cmpVal: val}, i + 1
+	}
+	return nil, p.error(i, "expected comparison")
+}
+
+func (p *parser) matchSince(i int, keyOff int, key string) (Query, int) {
+	if p.toks[i].Kind != '(' {
+		return nil, p.error(i, "expected (")
+	}
+	i++
+	if p.toks[i].Kind != 'w' {
+		return nil, p.error(i, "expected date or age")
+	}
+	arg := p.toks[i].Tok
+	since, ok := ParseDate(arg)
+	if !ok {
+		age, ok := parseAge(arg)
+		if !ok {
+			return nil, p.error(i, "expected date or age")
+		}
+		since = time.Now().Add(-age)
+	}
+	i++
+	if p.toks[i].Kind != ')' {
+		return nil, p.error(i, "expected )")
+	}
+	return &QueryMatch{Off: keyOff, Key: key, mStr: arg, Anchored: true, cmp: "since", since: since}, i + 1
 }
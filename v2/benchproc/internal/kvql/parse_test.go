@@ -4,7 +4,10 @@
 
 package kvql
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestParse(t *testing.T) {
 	check := func(query string, want string) {
@@ -50,4 +53,124 @@ func TestParse(t *testing.T) {
 	check(`a:(b c d)`, `(a:b OR a:c OR a:d)`)
 	checkErr(`a:(b AND c)`, "expected value", 5)
 	checkErr(`a:()`, "nothing to match", 3)
+	check(`a:~b`, `a:~b`)
+	check(`a:~"b c"`, `a:~"b c"`)
+	check(`a:"~b"`, `a:"~b"`)
+	check(`a:(~b c)`, `(a:~b OR a:c)`)
+	checkErr(`a:~`, "expected value", 3)
+	checkErr(`a:~~b`, "expected value", 3)
+	checkErr(`~a:b`, "unexpected \"~\"", 0)
+	check(`a:@>=4`, `a:@>=4`)
+	check(`a:@<-1.5`, `a:@<-1.5`)
+	check(`a:"@b"`, `a:"@b"`)
+	check(`a:(@<2 @>8)`, `(a:@<2 OR a:@>8)`)
+	checkErr(`a:@`, "expected comparison", 3)
+	checkErr(`a:@4`, "expected comparison", 3)
+	checkErr(`a:@>=x`, "expected number after >=", 3)
+	check(`a:@since(2024-01-01)`, `a:@since(2024-01-01)`)
+	check(`a:@since("2024-01-01 12:00")`, `a:@since("2024-01-01 12:00")`)
+	check(`a:@since(30d)`, `a:@since(30d)`)
+	checkErr(`a:@since`, "expected (", 8)
+	checkErr(`a:@since(yesterday)`, "expected date or age", 9)
+	checkErr(`a:@since(2024-01-01`, "expected )", 19)
+}
+
+func TestMatchAnchoring(t *testing.T) {
+	check := func(query, value string, want bool) {
+		t.Helper()
+		q, err := Parse(query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", query, err)
+		}
+		m, ok := q.(*QueryMatch)
+		if !ok {
+			t.Fatalf("%s: want *QueryMatch, got %T", query, q)
+		}
+		if got := m.MatchString(value); got != want {
+			t.Errorf("%s: match %q got %v, want %v", query, value, got, want)
+		}
+		if got := m.Match([]byte(value)); got != want {
+			t.Errorf("%s: match []byte %q got %v, want %v", query, value, got, want)
+		}
+	}
+	// Anchored by default.
+	check(`a:Lookup`, "Lookup", true)
+	check(`a:Lookup`, "MapLookup", false)
+	check(`a:Lookup`, "LookupFast", false)
+	check(`a:Look.*`, "LookupFast", true)
+	// Unanchored with "~".
+	check(`a:~Lookup`, "Lookup", true)
+	check(`a:~Lookup`, "MapLookup", true)
+	check(`a:~Lookup`, "LookupFast", true)
+	check(`a:~Lookup`, "Insert", false)
+	// Explicit anchors still work in unanchored matches.
+	check(`a:~^Lookup`, "MapLookup", false)
+	check(`a:~^Lookup`, "LookupFast", true)
+}
+
+func TestMatchCompare(t *testing.T) {
+	check := func(query, value string, want bool) {
+		t.Helper()
+		q, err := Parse(query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", query, err)
+		}
+		m := q.(*QueryMatch)
+		if got := m.MatchString(value); got != want {
+			t.Errorf("%s: match %q got %v, want %v", query, value, got, want)
+		}
+		if got := m.Match([]byte(value)); got != want {
+			t.Errorf("%s: match []byte %q got %v, want %v", query, value, got, want)
+		}
+	}
+	check(`a:@>=4`, "4", true)
+	check(`a:@>=4`, "16", true)
+	check(`a:@>=4`, "2", false)
+	// Comparisons are numeric, not lexical.
+	check(`a:@>4`, "10", true)
+	check(`a:@<4`, "10", false)
+	check(`a:@<=4`, "4.0", true)
+	check(`a:@==4`, "4e0", true)
+	check(`a:@!=4`, "5", true)
+	// Non-numbers never match.
+	check(`a:@>=4`, "", false)
+	check(`a:@!=4`, "x", false)
+	// Values may have SI or binary prefixes.
+	check(`size:@>=1024`, "1Ki", true)
+	check(`size:@>=1024`, "2Mi", true)
+	check(`size:@>=1024`, "512", false)
+	check(`size:@>=1024`, "1k", false)
+	check(`size:@<1Ki`, "1000", true)
+	check(`size:@==1.5k`, "1500", true)
+}
+
+func TestMatchSince(t *testing.T) {
+	check := func(query, value string, want bool) {
+		t.Helper()
+		q, err := Parse(query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", query, err)
+		}
+		m := q.(*QueryMatch)
+		if got := m.MatchString(value); got != want {
+			t.Errorf("%s: match %q got %v, want %v", query, value, got, want)
+		}
+		if got := m.Match([]byte(value)); got != want {
+			t.Errorf("%s: match []byte %q got %v, want %v", query, value, got, want)
+		}
+	}
+	check(`a:@since(2024-01-01)`, "2024-01-01", true)
+	check(`a:@since(2024-01-01)`, "2024-03-15 10:30:00", true)
+	check(`a:@since(2024-01-01)`, "2023-12-31T23:59:59Z", false)
+	check(`a:@since("2024-01-01 12:00")`, "2024-01-01", false)
+	// Relative ages are relative to when the query was parsed.
+	now := time.Now()
+	check(`a:@since(30d)`, now.Add(-29*24*time.Hour).Format(time.RFC3339), true)
+	check(`a:@since(30d)`, now.Add(-31*24*time.Hour).Format(time.RFC3339), false)
+	check(`a:@since(2w)`, now.Add(-13*24*time.Hour).Format(time.RFC3339), true)
+	check(`a:@since(12h)`, now.Add(-13*time.Hour).Format(time.RFC3339), false)
+	// Values that aren't dates never match.
+	check(`a:@since(2024-01-01)`, "", false)
+	check(`a:@since(2024-01-01)`, "yesterday", false)
+	check(`a:@since(30d)`, "12345", false)
 }
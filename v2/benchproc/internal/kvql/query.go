@@ -9,7 +9,10 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+
+	"golang.org/x/perf/v2/benchunit"
 )
 
 // Query is a node in the query tree. It can either be a QueryOp or a
@@ -26,6 +29,18 @@ type QueryMatch struct {
 	Key   string
 	match *regexp.Regexp
 	mStr  string // Original query regexp
+
+	// Anchored indicates the regexp must match the entire value,
+	// rather than any substring of the value.
+	Anchored bool
+
+	// cmp, if non-empty, is a numeric comparison operator. In
+	// this case, match is nil and the value is compared against
+	// cmpVal. If cmp is "since", the value is instead parsed as a
+	// date and compared against since.
+	cmp    string
+	cmpVal float64
+	since  time.Time
 }
 
 func (q *QueryMatch) isQuery() {}
@@ -40,22 +55,66 @@ func (q *QueryMatch) String() string {
 				return strconv.Quote(s)
 			}
 		}
+		if strings.HasPrefix(s, "~") || strings.HasPrefix(s, "@") {
+			return strconv.Quote(s)
+		}
 		// No quoting necessary.
 		return s
 	}
+	if q.cmp == "since" {
+		return quote(q.Key) + ":@since(" + quote(q.mStr) + ")"
+	}
+	if q.cmp != "" {
+		return quote(q.Key) + ":@" + quote(q.mStr)
+	}
+	if !q.Anchored {
+		return quote(q.Key) + ":~" + quote(q.mStr)
+	}
 	return quote(q.Key) + ":" + quote(q.mStr)
 }
 
 // Match returns whether q matches the given value of q.Key.
 func (q *QueryMatch) Match(value []byte) bool {
+	if q.cmp != "" {
+		return q.compare(string(value))
+	}
 	return q.match.Match(value)
 }
 
 // MatchString returns whether q matches the given value of q.Key.
 func (q *QueryMatch) MatchString(value string) bool {
+	if q.cmp != "" {
+		return q.compare(value)
+	}
 	return q.match.MatchString(value)
 }
 
+func (q *QueryMatch) compare(value string) bool {
+	if q.cmp == "since" {
+		t, ok := ParseDate(value)
+		return ok && !t.Before(q.since)
+	}
+	x, err := benchunit.ParseScaled(value)
+	if err != nil {
+		return false
+	}
+	switch q.cmp {
+	case "<":
+		return x < q.cmpVal
+	case "<=":
+		return x <= q.cmpVal
+	case ">":
+		return x > q.cmpVal
+	case ">=":
+		return x >= q.cmpVal
+	case "==":
+		return x == q.cmpVal
+	case "!=":
+		return x != q.cmpVal
+	}
+	panic("bad comparison operator " + q.cmp)
+}
+
 // QueryOp is a boolean operator in the Query tree. OpNot must have
 // exactly one child node. OpAnd and OpOr may have zero or more child
 // nodes.
@@ -58,10 +58,10 @@ func Tokenize(q string) ([]Tok, error) {
 	var toks []Tok
 	for len(q) > 0 {
 		off := len(qOrig) - len(q)
-		// At the beginning of a word, we accept "-" and "*"
-		// as operators, but in the middle of words we treat
-		// them as part of the word.
-		if isOp(rune(q[0])) || q[0] == '-' || q[0] == '*' {
+		// At the beginning of a word, we accept "-", "*", and
+		// "~" as operators, but in the middle of words we
+		// treat them as part of the word.
+		if isOp(rune(q[0])) || q[0] == '-' || q[0] == '*' || q[0] == '~' {
 			toks = append(toks, Tok{q[0], off, q[:1]})
 			q = q[1:]
 		} else if n := isSpace(q); n > 0 {
@@ -0,0 +1,125 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// MinimalKeys returns a smallest subset of candidateKeys that
+// distinguishes results as well as all of candidateKeys do. That is,
+// any two results that differ in some candidate key also differ in
+// some returned key. If the results are all distinct under
+// candidateKeys, they are all distinct under the returned keys. This
+// is useful for choosing a default projection that separates a set of
+// results without mentioning keys that don't matter.
+//
+// Each key may be any key accepted by benchfmt.NewExtractor. A key
+// that is missing from a result is distinct from a key with an empty
+// value. The returned keys are in the order of candidateKeys. If
+// there are several smallest subsets, MinimalKeys returns the one
+// whose keys come earliest in candidateKeys.
+//
+// This searches subsets of candidateKeys in order of size, so it
+// takes time exponential in the size of the returned subset. Keys
+// that have the same value in every result are never needed, so they
+// don't contribute to this cost.
+func MinimalKeys(results []*benchfmt.Result, candidateKeys []string) ([]string, error) {
+	// Extract the value of every candidate key from every result,
+	// interned as small integers. 0 is a missing key.
+	type column struct {
+		key  string
+		vals []int
+	}
+	var cols []column
+	for _, key := range candidateKeys {
+		ext, err := benchfmt.NewExtractor(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+		ids := make(map[string]int)
+		vals := make([]int, len(results))
+		for i, res := range results {
+			val := ext(res)
+			if val == nil {
+				continue
+			}
+			id, ok := ids[string(val)]
+			if !ok {
+				id = len(ids) + 1
+				ids[string(val)] = id
+			}
+			vals[i] = id
+		}
+		if len(ids) == 0 || len(ids) == 1 && !contains(vals, 0) {
+			// The same in every result.
+			continue
+		}
+		cols = append(cols, column{key, vals})
+	}
+
+	// countDistinct returns the number of distinct results under
+	// the columns in subset.
+	var buf []byte
+	countDistinct := func(subset []int) int {
+		seen := make(map[string]struct{})
+		for i := range results {
+			buf = buf[:0]
+			for _, c := range subset {
+				v := cols[c].vals[i]
+				buf = append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+			}
+			seen[string(buf)] = struct{}{}
+		}
+		return len(seen)
+	}
+	all := make([]int, len(cols))
+	for i := range all {
+		all[i] = i
+	}
+	want := countDistinct(all)
+
+	// Try subsets in order of size, and within each size in
+	// lexicographic order.
+	for size := 0; size <= len(cols); size++ {
+		subset := make([]int, size)
+		for i := range subset {
+			subset[i] = i
+		}
+		for {
+			if countDistinct(subset) == want {
+				keys := make([]string, len(subset))
+				for i, c := range subset {
+					keys[i] = cols[c].key
+				}
+				return keys, nil
+			}
+			// Advance to the next combination.
+			i := size - 1
+			for i >= 0 && subset[i] == len(cols)-size+i {
+				i--
+			}
+			if i < 0 {
+				break
+			}
+			subset[i]++
+			for j := i + 1; j < size; j++ {
+				subset[j] = subset[j-1] + 1
+			}
+		}
+	}
+	panic("all keys must distinguish results")
+}
+
+func contains(xs []int, x int) bool {
+	for _, y := range xs {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
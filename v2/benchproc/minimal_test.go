@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestMinimalKeys(t *testing.T) {
+	res := func(name string, cfg ...string) *benchfmt.Result {
+		r := &benchfmt.Result{FullName: []byte(name)}
+		for i := 0; i < len(cfg); i += 2 {
+			r.SetFileConfig(cfg[i], cfg[i+1])
+		}
+		return r
+	}
+	// goos is the same everywhere, cpu and machine are redundant
+	// with each other, and /size is redundant with .name.
+	results := []*benchfmt.Result{
+		res("A/size=1", "goos", "linux", "machine", "m1", "cpu", "x86"),
+		res("A/size=1", "goos", "linux", "machine", "m2", "cpu", "arm"),
+		res("B/size=2", "goos", "linux", "machine", "m1", "cpu", "x86"),
+		res("B/size=2", "goos", "linux", "machine", "m2", "cpu", "arm"),
+	}
+	check := func(results []*benchfmt.Result, candidates string, want string) {
+		t.Helper()
+		got, err := MinimalKeys(results, strings.Fields(candidates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("%s: want [%s], got %q", candidates, want, got)
+		}
+	}
+	check(results, "goos machine cpu .name /size", "machine .name")
+	check(results, "goos cpu /size machine .name", "cpu /size")
+	// .fullname alone doesn't distinguish results, so it's not
+	// enough.
+	check(results, ".fullname cpu", ".fullname cpu")
+	// A single key that distinguishes everything wins.
+	results[1].SetFileConfig("id", "2")
+	results[2].SetFileConfig("id", "3")
+	results[3].SetFileConfig("id", "4")
+	check(results, "machine .name id", "id")
+	// A missing key is distinct from an empty one.
+	check([]*benchfmt.Result{res("X"), res("X/k=")}, ".name /k", "/k")
+
+	// If results are indistinguishable, keep as many distinct
+	// as possible.
+	dups := []*benchfmt.Result{
+		res("A", "machine", "m1", "cpu", "x86"),
+		res("A", "machine", "m1", "cpu", "x86"),
+		res("A", "machine", "m2", "cpu", "arm"),
+	}
+	check(dups, ".name machine cpu", "machine")
+	check(dups, ".name", "")
+	check(nil, ".name machine", "")
+
+	if _, err := MinimalKeys(results, []string{""}); err == nil {
+		t.Errorf("want error for empty key")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"math"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A Normalizer annotates each value of each result with its ratio to
+// a baseline value of the same unit in the result's group, where
+// results are grouped by a projection. For example, with a group
+// projection of ".fullname" and a baseline filter of
+// "commit:abc123", it adds to each result a "ns/op-norm" value that
+// is 1 for the baseline commit of each benchmark and, for example, 1.1
+// for a commit that is 10% slower. Since normalized values are
+// unitless, they are comparable across benchmarks, so their geomean
+// is a meaningful summary.
+//
+// Since the baseline of a group may come after other results of that
+// group, a Normalizer must see all results before it can return any.
+// Like a StatAnnotator, it is also a Stage.
+type Normalizer struct {
+	baseline *Filter
+	stat     GroupStat
+	// buf records only the baseline values of each group.
+	buf groupBuffer
+}
+
+// NormalizedUnit returns the unit of the normalized values Normalizer
+// computes for unit.
+func NormalizedUnit(unit string) string {
+	return unit + "-norm"
+}
+
+// NewNormalizer returns a Normalizer that groups results by group and
+// normalizes them to the values in the group matched by baseline. If
+// a group has several baseline values of a unit, such as from
+// repeated runs, stat reduces them to a single baseline, for example,
+// StatMedian.
+func NewNormalizer(group *Schema, baseline *Filter, stat GroupStat) *Normalizer {
+	n := &Normalizer{baseline: baseline, stat: stat, buf: newGroupBuffer(group)}
+	n.buf.interleave = true
+	return n
+}
+
+// Add adds a copy of res to n, as for StatAnnotator.Add.
+func (n *Normalizer) Add(res *benchfmt.Result) {
+	n.add(res, true)
+}
+
+func (n *Normalizer) add(res *benchfmt.Result, clone bool) {
+	m := n.baseline.Match(res)
+	n.buf.add(res, clone, m.Test)
+}
+
+// Results returns the results added to n in the order they were
+// added. Each value of each result is followed by a value with unit
+// NormalizedUnit(unit) giving the ratio of the value to the baseline
+// of that unit in the result's group. Values with no baseline, or
+// with a baseline of 0, are not normalized. The baseline values
+// themselves are normalized like any other value, so they are near 1.
+//
+// Like StatAnnotator.Results, it should be called after all results
+// have been added, and it resets n.
+func (n *Normalizer) Results() []*benchfmt.Result {
+	return n.buf.annotate(func(vals []float64) valueAnnotator {
+		base := n.stat.Compute(vals)
+		if base == 0 || math.IsNaN(base) {
+			return nil
+		}
+		return func(val benchfmt.Value, out []benchfmt.Value) []benchfmt.Value {
+			return append(out, benchfmt.Value{Value: val.Value / base, Unit: NormalizedUnit(val.Unit)})
+		}
+	})
+}
+
+// Process adds res to n and takes ownership of it.
+func (n *Normalizer) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	n.add(res, false)
+	return nil
+}
+
+// Flush emits the normalized results of n.
+func (n *Normalizer) Flush(emit func(*benchfmt.Result)) error {
+	for _, res := range n.Results() {
+		emit(res)
+	}
+	return nil
+}
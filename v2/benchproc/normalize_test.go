@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestNormalizer(t *testing.T) {
+	// The baseline of A comes after another result, B has two
+	// baseline runs, and C has no baseline.
+	const input = `commit: new
+BenchmarkA 1 15 ns/op 200 B/op
+commit: base
+BenchmarkA 1 10 ns/op 100 B/op
+BenchmarkB 1 100 ns/op
+BenchmarkB 1 300 ns/op
+BenchmarkC 1 5 ns/op
+commit: new
+BenchmarkB 1 100 ns/op
+BenchmarkC 1 6 ns/op
+`
+	var p ProjectionParser
+	group, err := p.Parse(".name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := NewFilter("commit:base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNormalizer(group, baseline, StatMedian)
+	r := benchfmt.NewReader(strings.NewReader(input), "test")
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.Add(res)
+	}
+
+	var got []string
+	for _, res := range n.Results() {
+		var vals []string
+		for _, val := range res.Values {
+			vals = append(vals, fmt.Sprintf("%v %s", math.Round(val.Value*1000)/1000, val.Unit))
+		}
+		got = append(got, fmt.Sprintf("%s@%s: %s", res.FullName, res.GetFileConfig("commit"), strings.Join(vals, ", ")))
+	}
+	want := []string{
+		"A@new: 15 ns/op, 1.5 ns/op-norm, 200 B/op, 2 B/op-norm",
+		"A@base: 10 ns/op, 1 ns/op-norm, 100 B/op, 1 B/op-norm",
+		// B's baseline is the median of its baseline runs.
+		"B@base: 100 ns/op, 0.5 ns/op-norm",
+		"B@base: 300 ns/op, 1.5 ns/op-norm",
+		"C@base: 5 ns/op, 1 ns/op-norm",
+		"B@new: 100 ns/op, 0.5 ns/op-norm",
+		"C@new: 6 ns/op, 1.2 ns/op-norm",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if len(n.Results()) != 0 {
+		t.Errorf("want Results to reset the Normalizer")
+	}
+
+	// Values without a baseline aren't normalized.
+	baseline, _ = NewFilter("commit:base .unit:ns/op")
+	n = NewNormalizer(group, baseline, StatMedian)
+	n.Add(&benchfmt.Result{
+		FullName:   []byte("D"),
+		FileConfig: []benchfmt.Config{{Key: "commit", Value: []byte("base")}},
+		Values:     []benchfmt.Value{{Value: 4, Unit: "ns/op"}, {Value: 8, Unit: "B/op"}},
+	})
+	n.Add(&benchfmt.Result{FullName: []byte("E"), Values: []benchfmt.Value{{Value: 4, Unit: "ns/op"}}})
+	results := n.Results()
+	if v, ok := results[0].Value("ns/op-norm"); !ok || v != 1 {
+		t.Errorf("D: want ns/op-norm 1, got %v, %v", v, ok)
+	}
+	if _, ok := results[0].Value("B/op-norm"); ok {
+		t.Errorf("D: want no B/op-norm")
+	}
+	if len(results[1].Values) != 1 {
+		t.Errorf("E: want no normalized values, got %v", results[1].Values)
+	}
+}
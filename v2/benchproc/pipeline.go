@@ -0,0 +1,214 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"sync"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A Stage is one step of a Pipeline.
+//
+// Each Stage of a Pipeline runs in its own goroutine, so a Stage need
+// not be safe for concurrent use, but it must not share unsynchronized
+// state with other Stages.
+type Stage interface {
+	// Process is called for each Result that reaches this Stage,
+	// in order. It passes Results on to the next Stage by calling
+	// emit, which it may do any number of times, including zero.
+	// The Stage owns res: it may modify or retain it, but must
+	// not modify it after passing it to emit.
+	//
+	// If Process returns an error, the Pipeline stops.
+	Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error
+
+	// Flush is called after the last Result has been processed.
+	// This is useful for Stages that accumulate Results. It may
+	// call emit to pass any remaining Results on to the next
+	// Stage.
+	Flush(emit func(*benchfmt.Result)) error
+}
+
+// A ResultScanner is a source of benchmark Results, such as a
+// benchfmt.Reader or benchfmt.Files.
+type ResultScanner interface {
+	Scan() bool
+	Result() (*benchfmt.Result, error)
+	Err() error
+}
+
+// DefaultBuffer is the default number of Results that may be queued
+// between adjacent Stages of a Pipeline.
+const DefaultBuffer = 128
+
+// A Pipeline streams benchmark Results through a sequence of Stages.
+// Stages run concurrently, connected by bounded buffers, so a slow
+// Stage applies backpressure to the Stages before it rather than
+// causing Results to pile up in memory.
+type Pipeline struct {
+	stages []Stage
+
+	// Buffer is the number of Results that may be queued between
+	// adjacent Stages. If 0, it uses DefaultBuffer.
+	Buffer int
+
+	// ResultError, if non-nil, is called with the error for each
+	// malformed Result read from the input. Parse errors are not
+	// fatal, so by default malformed Results are simply skipped.
+	ResultError func(error)
+}
+
+// NewPipeline returns a new Pipeline with no Stages.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Then appends s to the Stages of p and returns p, so calls can be
+// chained, as in NewPipeline().Then(a).Then(b).
+func (p *Pipeline) Then(s Stage) *Pipeline {
+	p.stages = append(p.stages, s)
+	return p
+}
+
+// Run reads Results from src and streams them through the Stages of
+// p. Since the Stages own the Results they process, Run passes each
+// Stage a copy of the Result from src. Results emitted by the last
+// Stage are discarded, so the last Stage is typically a sink that
+// collects or writes Results.
+//
+// Run returns once all Stages have finished. It returns the first
+// error from src or any Stage. If a Stage fails, Run stops reading
+// from src and the other Stages stop passing on Results, but every
+// Stage that has already started is still allowed to finish.
+func (p *Pipeline) Run(src ResultScanner) error {
+	buffer := p.Buffer
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	var errOnce sync.Once
+	var firstErr error
+	done := make(chan struct{})
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+	// send sends res to out unless the Pipeline has failed.
+	send := func(out chan<- *benchfmt.Result, res *benchfmt.Result) {
+		select {
+		case out <- res:
+		case <-done:
+		}
+	}
+
+	// Start the Stages.
+	var wg sync.WaitGroup
+	first := make(chan *benchfmt.Result, buffer)
+	in := first
+	for i, stage := range p.stages {
+		var out chan *benchfmt.Result
+		emit := func(*benchfmt.Result) {}
+		if i < len(p.stages)-1 {
+			out = make(chan *benchfmt.Result, buffer)
+			emit = func(res *benchfmt.Result) { send(out, res) }
+		}
+		wg.Add(1)
+		go func(stage Stage, in <-chan *benchfmt.Result, out chan<- *benchfmt.Result, emit func(*benchfmt.Result)) {
+			defer wg.Done()
+			if out != nil {
+				defer close(out)
+			}
+			for res := range in {
+				if err := stage.Process(res, emit); err != nil {
+					// Earlier Stages stop sending
+					// once the Pipeline fails, so
+					// there's no need to drain in.
+					fail(err)
+					return
+				}
+			}
+			if err := stage.Flush(emit); err != nil {
+				fail(err)
+			}
+		}(stage, in, out, emit)
+		in = out
+	}
+	if len(p.stages) == 0 {
+		// Discard everything.
+		go func() {
+			for range first {
+			}
+		}()
+	}
+
+	// Feed the first Stage.
+feed:
+	for src.Scan() {
+		res, err := src.Result()
+		if err != nil {
+			if p.ResultError != nil {
+				p.ResultError(err)
+			}
+			continue
+		}
+		select {
+		case first <- res.Clone():
+		case <-done:
+			break feed
+		}
+	}
+	close(first)
+	if err := src.Err(); err != nil {
+		fail(err)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+type filterStage struct {
+	f *Filter
+}
+
+// FilterStage returns a Stage that filters Results using f. Like
+// Match.Apply, it removes values that don't match f and drops Results
+// with no matching values.
+func FilterStage(f *Filter) Stage {
+	return filterStage{f}
+}
+
+func (s filterStage) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	m := s.f.Match(res)
+	if m.Apply(res) {
+		emit(res)
+	}
+	return nil
+}
+
+func (s filterStage) Flush(emit func(*benchfmt.Result)) error {
+	return nil
+}
+
+type transformStage struct {
+	t Transform
+}
+
+// TransformStage returns a Stage that applies t to each Result.
+func TransformStage(t Transform) Stage {
+	return transformStage{t}
+}
+
+func (s transformStage) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	s.t(res)
+	emit(res)
+	return nil
+}
+
+func (s transformStage) Flush(emit func(*benchfmt.Result)) error {
+	return nil
+}
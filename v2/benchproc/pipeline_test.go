@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// countStage counts the Results and values that reach it.
+type countStage struct {
+	results, values int
+	flushed         bool
+}
+
+func (s *countStage) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	s.results++
+	s.values += len(res.Values)
+	emit(res)
+	return nil
+}
+
+func (s *countStage) Flush(emit func(*benchfmt.Result)) error {
+	s.flushed = true
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "goos: %s\nBenchmarkX 1 %d ns/op %d B/op\n", []string{"linux", "darwin"}[i%2], i, i)
+	}
+	input.WriteString("BenchmarkBad\n")
+
+	f, err := NewFilter("goos:linux .unit:ns/op")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count countStage
+	var resultErrs int
+	p := NewPipeline().Then(FilterStage(f)).Then(&count)
+	// Use a small buffer so stages have to wait on each other.
+	p.Buffer = 2
+	p.ResultError = func(error) { resultErrs++ }
+	if err := p.Run(benchfmt.NewReader(strings.NewReader(input.String()), "test")); err != nil {
+		t.Fatal(err)
+	}
+	if count.results != 500 || count.values != 500 {
+		t.Errorf("want 500 results with 500 values, got %d results with %d values", count.results, count.values)
+	}
+	if !count.flushed {
+		t.Errorf("last stage not flushed")
+	}
+	if resultErrs != 1 {
+		t.Errorf("want 1 malformed result, got %d", resultErrs)
+	}
+}
+
+// failStage fails on the n'th Result.
+type failStage struct {
+	n int
+}
+
+var errFail = errors.New("fail")
+
+func (s *failStage) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	s.n--
+	if s.n == 0 {
+		return errFail
+	}
+	emit(res)
+	return nil
+}
+
+func (s *failStage) Flush(emit func(*benchfmt.Result)) error {
+	return nil
+}
+
+func TestPipelineError(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "BenchmarkX 1 %d ns/op\n", i)
+	}
+
+	var count countStage
+	p := NewPipeline().Then(&failStage{10}).Then(&count)
+	p.Buffer = 1
+	err := p.Run(benchfmt.NewReader(strings.NewReader(input.String()), "test"))
+	if err != errFail {
+		t.Fatalf("want error %v, got %v", errFail, err)
+	}
+	if count.results != 9 {
+		t.Errorf("want 9 results before failure, got %d", count.results)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import "golang.org/x/perf/v2/benchfmt"
+
+// A ProjectionSet projects each Result into several Schemas at once,
+// sharing work between the Schemas. The benchmark name of each Result
+// is split into its parts only once for all of the name keys of all of
+// the Schemas, and keys that appear in more than one of the Schemas,
+// such as ".name" in both a row and a phase projection, are extracted
+// only once.
+//
+// A Schema may belong to at most one ProjectionSet.
+type ProjectionSet struct {
+	schemas []*Schema
+	cache   extractCache
+	cfgs    []Config
+}
+
+// An extractCache records the values of shared keys extracted from a
+// single Result while it is projected by a ProjectionSet.
+type extractCache struct {
+	// res is the Result being projected, or nil if no projection
+	// is in progress. Results are often reused, so the cache is
+	// only valid during a single call to ProjectionSet.Project.
+	res *benchfmt.Result
+
+	// vals is the extracted value of each slot, which is valid
+	// only if have is set for that slot.
+	vals [][]byte
+	have []bool
+
+	// base and parts are res's name split by benchfmt.NameParts,
+	// which are valid only if haveParts is set.
+	base      []byte
+	parts     [][]byte
+	haveParts bool
+}
+
+// NewProjectionSet returns a ProjectionSet of schemas.
+func NewProjectionSet(schemas ...*Schema) *ProjectionSet {
+	ps := &ProjectionSet{schemas: schemas}
+
+	// Find the keys shared by more than one Schema and assign
+	// each a slot in the cache.
+	count := make(map[string]int)
+	for _, s := range schemas {
+		if s.cache != nil {
+			panic("Schema already belongs to a ProjectionSet")
+		}
+		for _, key := range s.extractKeys {
+			count[key]++
+		}
+	}
+	slots := make(map[string]int)
+	for _, s := range schemas {
+		s.cache = &ps.cache
+		s.cacheSlots = make([]int, len(s.extractKeys))
+		for i, key := range s.extractKeys {
+			if count[key] < 2 {
+				s.cacheSlots[i] = -1
+				continue
+			}
+			slot, ok := slots[key]
+			if !ok {
+				slot = len(slots)
+				slots[key] = slot
+			}
+			s.cacheSlots[i] = slot
+		}
+	}
+	ps.cache.vals = make([][]byte, len(slots))
+	ps.cache.have = make([]bool, len(slots))
+	return ps
+}
+
+// Project projects r into each of the Schemas of ps and returns the
+// resulting Configs, in the order the Schemas were passed to
+// NewProjectionSet. Each Schema projects r exactly as Schema.Project
+// would. If any Schema filters r, it returns nil and false.
+//
+// The returned slice is only valid until the next call to Project.
+func (ps *ProjectionSet) Project(r *benchfmt.Result) ([]Config, bool) {
+	ps.cache.res = r
+	for i := range ps.cache.have {
+		ps.cache.have[i] = false
+	}
+	ps.cache.haveParts = false
+
+	ps.cfgs = ps.cfgs[:0]
+	ok := true
+	for _, s := range ps.schemas {
+		cfg, ok1 := s.Project(r)
+		ps.cfgs = append(ps.cfgs, cfg)
+		ok = ok && ok1
+	}
+	ps.cache.res = nil
+	if !ok {
+		return nil, false
+	}
+	return ps.cfgs, true
+}
+
+// extract returns the value of s's ki'th extracted key in r using
+// ext, sharing work with the other Schemas in s's ProjectionSet, if
+// any. nameExt, if non-nil, extracts the same key from the parts of
+// r's name.
+func (s *Schema) extract(ki int, ext benchfmt.Extractor, nameExt benchfmt.NameExtractor, r *benchfmt.Result) []byte {
+	c := s.cache
+	if c == nil || c.res != r {
+		return ext(r)
+	}
+	slot := s.cacheSlots[ki]
+	if slot >= 0 && c.have[slot] {
+		return c.vals[slot]
+	}
+	var val []byte
+	if nameExt != nil {
+		if !c.haveParts {
+			c.base, c.parts = benchfmt.NameParts(r.FullName)
+			c.haveParts = true
+		}
+		val = nameExt(c.base, c.parts)
+	} else {
+		val = ext(r)
+	}
+	if slot >= 0 {
+		c.vals[slot], c.have[slot] = val, true
+	}
+	return val
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// projSetSchemas returns three overlapping Schemas like those used by
+// benchstack's columns, rows, and phases.
+func projSetSchemas(t testing.TB) []*Schema {
+	var p ProjectionParser
+	var out []*Schema
+	for _, proj := range []string{"goos,commit", ".name,/size,.gomaxprocs", ".name,/kind"} {
+		s, err := p.Parse(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func projSetResults() []*benchfmt.Result {
+	var out []*benchfmt.Result
+	for i, name := range []string{"Encode/size=1/kind=cpu-8", "Decode/size=2/kind=mem-8", "Encode/size=1/kind=cpu-4", "Decode"} {
+		res := &benchfmt.Result{FullName: []byte(name)}
+		res.SetFileConfig("goos", "linux")
+		res.SetFileConfig("commit", fmt.Sprint("c", i%2))
+		out = append(out, res)
+	}
+	return out
+}
+
+func TestProjectionSet(t *testing.T) {
+	separate := projSetSchemas(t)
+	shared := projSetSchemas(t)
+	ps := NewProjectionSet(shared...)
+
+	for _, res := range projSetResults() {
+		got, ok := ps.Project(res)
+		if !ok {
+			t.Fatalf("%s: unexpectedly filtered", res.FullName)
+		}
+		if len(got) != len(separate) {
+			t.Fatalf("%s: want %d Configs, got %d", res.FullName, len(separate), len(got))
+		}
+		for i, s := range separate {
+			want, _ := s.Project(res)
+			if got[i].String() != want.String() {
+				t.Errorf("%s: schema %d: want %s, got %s", res.FullName, i, want, got[i])
+			}
+			if got[i].Schema() != shared[i] {
+				t.Errorf("%s: schema %d: Config has wrong Schema", res.FullName, i)
+			}
+		}
+	}
+
+	// Results are often reused, so a modified Result must not
+	// see stale values.
+	res := projSetResults()[0]
+	ps.Project(res)
+	res.FullName = []byte("Other/size=3")
+	cfgs, _ := ps.Project(res)
+	if want := ".name:Other /size:3 .gomaxprocs:1"; cfgs[1].String() != want {
+		t.Errorf("after reusing Result: want %s, got %s", want, cfgs[1])
+	}
+
+	// A Schema that filters the Result filters the whole set.
+	var p ProjectionParser
+	filter, err := p.Parse("goos:(darwin)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := NewProjectionSet(filter).Project(res); ok {
+		t.Errorf("want Result filtered")
+	}
+}
+
+func BenchmarkProjectionSet(b *testing.B) {
+	results := projSetResults()
+	b.Run("separate", func(b *testing.B) {
+		schemas := projSetSchemas(b)
+		for i := 0; i < b.N; i++ {
+			res := results[i%len(results)]
+			for _, s := range schemas {
+				s.Project(res)
+			}
+		}
+	})
+	b.Run("shared", func(b *testing.B) {
+		ps := NewProjectionSet(projSetSchemas(b)...)
+		for i := 0; i < b.N; i++ {
+			ps.Project(results[i%len(results)])
+		}
+	})
+}
@@ -28,13 +28,20 @@
 package benchproc
 
 import (
+	"bytes"
 	"fmt"
+	"hash/fnv"
 	"hash/maphash"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/perf/v2/benchfmt"
 	"golang.org/x/perf/v2/benchproc/internal/kvql"
+	"golang.org/x/perf/v2/benchunit"
 )
 
 // TODO: the "key:(val1 val2)" syntax looks like a filter expression,
@@ -60,15 +67,53 @@ import (
 // list. Each component of the tuple specifies a key and optionally a
 // sort order and a filter using the following syntax:
 //
-// - "{key}[@{order}]" specifies one of the built-in sort orders. If
-// order is omitted, it uses the default first-observation order.
+// - "{key}[@{order}]" specifies one of the built-in sort orders, or
+// an order registered with RegisterOrder. If order is omitted, it
+// uses the default first-observation order. The built-in orders are
+// "alpha", "numeric", and "date", which orders values that ParseDate
+// accepts chronologically.
+//
+// - "{key}@{transform}" normalizes each value of key before it is
+// grouped and sorted. The transform may be "lower" or "upper" to
+// change the case of the value, or "trim" to remove leading and
+// trailing white space. Multiple transforms and at most one sort
+// order may be given, as in "goos@trim@lower@alpha", and transforms
+// are applied in order.
+//
+// - "{key}@top({n})" limits key to its n most frequently observed
+// values. Projecting a Result still records its actual value, but
+// Schema.Bucket replaces all other values with "other". This can be
+// combined with transforms and a sort order, as in
+// "commit@top(10)@alpha". Ties in frequency are broken by the sort
+// order, and the "other" bucket always sorts last, even after an
+// actual value of "other".
 //
 // - "{key}:({val} {val}...)" specifies a fixed value order for key.
 // It also specifies a filter: if key has a value that isn't any of
 // the specified values, the benchfmt.Result is filtered out.
 //
-// The key can be any key accepted by benchfmt.NewExtractor, or
-// ".config", which is a group key for all file configuration keys.
+// - "{key}=?{default}" projects a benchfmt.Result that doesn't have
+// key as if key had value default, so it groups with Results that
+// have that value explicitly. For example, "gc=?on" treats a missing
+// "gc" key as "on". A key that is present with an empty value is not
+// missing. The default is transformed and matched against any fixed
+// value order like any other value, and takes precedence over
+// MissingValue. Defaults are not allowed for group keys such as
+// ".config". The default follows the key and precedes any "@" or ":"
+// suffixes, as in "gc=?on@alpha".
+//
+// An empty projection expression (or one consisting only of white
+// space) is the identity projection. It has no fields and projects
+// every benchfmt.Result to the same Config, which is useful for
+// computing a single summary over all Results.
+//
+// The key can be any key accepted by benchfmt.NewExtractor,
+// ".config", which is a group key for all file configuration keys, or
+// ".id", which projects the file configuration and full name of a
+// Result into a single opaque value. Two Results have the same ".id"
+// if they have the same full name and file configuration, regardless
+// of the order of their file configuration keys. Unlike ".config" and
+// ".fullname", ".id" is not affected by keys in other projections.
 //
 // Multiple projections can be parsed by one ProjectionParser, and
 // they form a mutually-exclusive group of projections in which
@@ -79,6 +124,21 @@ import (
 // configuration keys "commit" and "date" are excluded from the group
 // key ".config".
 type ProjectionParser struct {
+	// MissingValue, if non-empty, is the value projected for a
+	// specific key that is not present in a benchfmt.Result. By
+	// default, a missing key projects to "", which is
+	// indistinguishable from a key that is present with an empty
+	// value (for example, "/a" in the benchmark name "Name/a=").
+	// Setting MissingValue to a sentinel such as "<unset>" makes
+	// missing keys a distinct, visible value that sorts according
+	// to the field's order like any other value. This does not
+	// affect the .config group, since file configuration keys
+	// cannot have empty values.
+	//
+	// MissingValue must be set before calling Parse and applies
+	// to projections parsed after it is set.
+	MissingValue string
+
 	configKeys   map[string]bool // Specific .config keys (excluded from .config)
 	fullnameKeys []string        // Specific name keys (excluded from .fullname)
 	haveConfig   bool            // .config was projected
@@ -104,27 +164,68 @@ func (p *ProjectionParser) Parse(proj string) (*Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	if toks[0].Kind == 0 {
+		// Empty projection. This projects everything to the
+		// same Config.
+		return s, nil
+	}
 	for len(toks) > 0 {
 		// Process the key.
 		if !(toks[0].Kind == 'w' || toks[0].Kind == 'q') {
-			return nil, &kvql.SyntaxError{proj, toks[0].Off, "expected key"}
+			return nil, &kvql.SyntaxError{Query: proj, Off: toks[0].Off, Msg: "expected key"}
 		}
 		key := toks[0]
 		toks = toks[1:]
-		// Process the sort order.
+		// Process the default value.
+		var def []byte
+		if key.Kind == 'w' {
+			if i := strings.Index(key.Tok, "=?"); i >= 0 {
+				if i+2 == len(key.Tok) {
+					return nil, &kvql.SyntaxError{Query: proj, Off: key.Off + i + 2, Msg: "expected default value"}
+				}
+				def = []byte(key.Tok[i+2:])
+				key.Tok = key.Tok[:i]
+			}
+		}
+		// Process the sort order and value transforms.
 		order := "first"
+		haveOrder := false
+		var xforms []func([]byte) []byte
 		var exact []string
-		if toks[0].Kind == '@' {
+		top := 0
+		for toks[0].Kind == '@' {
 			if !(toks[1].Kind == 'w' || toks[1].Kind == 'q') {
-				return nil, &kvql.SyntaxError{proj, toks[1].Off, "expected sort order"}
+				return nil, &kvql.SyntaxError{Query: proj, Off: toks[1].Off, Msg: "expected sort order"}
+			}
+			if toks[1].Kind == 'w' && toks[1].Tok == "top" && toks[2].Kind == '(' {
+				if top != 0 {
+					return nil, &kvql.SyntaxError{Query: proj, Off: toks[1].Off, Msg: "multiple top limits"}
+				}
+				n, err := strconv.Atoi(toks[3].Tok)
+				if toks[3].Kind != 'w' || err != nil || n <= 0 {
+					return nil, &kvql.SyntaxError{Query: proj, Off: toks[3].Off, Msg: "expected positive count"}
+				}
+				if toks[4].Kind != ')' {
+					return nil, &kvql.SyntaxError{Query: proj, Off: toks[4].Off, Msg: "expected )"}
+				}
+				top = n
+				toks = toks[5:]
+				continue
+			}
+			if xform, ok := valueTransforms[toks[1].Tok]; ok {
+				xforms = append(xforms, xform)
+			} else if haveOrder {
+				return nil, &kvql.SyntaxError{Query: proj, Off: toks[1].Off, Msg: "multiple sort orders"}
+			} else {
+				order, haveOrder = toks[1].Tok, true
 			}
-			order = toks[1].Tok
 			toks = toks[2:]
-		} else if toks[0].Kind == ':' {
+		}
+		if toks[0].Kind == ':' && !haveOrder {
 			// TODO: For similarity with the filter
 			// syntax, should we accept a bare word here?
 			if toks[1].Kind != '(' {
-				return nil, &kvql.SyntaxError{proj, toks[1].Off, "expected ("}
+				return nil, &kvql.SyntaxError{Query: proj, Off: toks[1].Off, Msg: "expected ("}
 			}
 			start := toks[1].Off
 			toks = toks[2:]
@@ -133,19 +234,32 @@ func (p *ProjectionParser) Parse(proj string) (*Schema, error) {
 				toks = toks[1:]
 			}
 			if toks[0].Kind != ')' {
-				return nil, &kvql.SyntaxError{proj, toks[0].Off, "expected )"}
+				return nil, &kvql.SyntaxError{Query: proj, Off: toks[0].Off, Msg: "expected )"}
 			}
 			if len(exact) == 0 {
-				return nil, &kvql.SyntaxError{proj, start, "nothing to match"}
+				return nil, &kvql.SyntaxError{Query: proj, Off: start, Msg: "nothing to match"}
+			}
+			toks = toks[1:]
+		}
+
+		var xform func([]byte) []byte
+		if len(xforms) == 1 {
+			xform = xforms[0]
+		} else if len(xforms) > 1 {
+			xform = func(val []byte) []byte {
+				for _, f := range xforms {
+					val = f(val)
+				}
+				return val
 			}
 		}
 
-		if err := p.makeProjection(s, key.Tok, order, exact); err != nil {
-			return nil, &kvql.SyntaxError{proj, key.Off, err.Error()}
+		if err := p.makeProjection(s, key.Tok, order, exact, xform, top, def); err != nil {
+			return nil, &kvql.SyntaxError{Query: proj, Off: key.Off, Msg: err.Error()}
 		}
 
 		if !(toks[0].Kind == ',' || toks[0].Kind == 0) {
-			return nil, &kvql.SyntaxError{proj, toks[0].Off, "expected ,"}
+			return nil, &kvql.SyntaxError{Query: proj, Off: toks[0].Off, Msg: "expected ,"}
 		}
 		toks = toks[1:]
 	}
@@ -164,16 +278,27 @@ func (p *ProjectionParser) Remainder() *Schema {
 	// then these groups (with any specific keys excluded) exactly
 	// form the remainder.
 	if !p.haveConfig {
-		p.makeProjection(s, ".config", "first", nil)
+		p.makeProjection(s, ".config", "first", nil, nil, 0, nil)
 	}
 	if !p.haveFullname {
-		p.makeProjection(s, ".fullname", "first", nil)
+		p.makeProjection(s, ".fullname", "first", nil, nil, 0, nil)
 	}
 
 	return s
 }
 
-func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, exact []string) error {
+// makeProjection adds a projection of key to s. If exact is non-nil,
+// it gives the fixed order of key's values and the projection filters
+// out any other values. If xform is non-nil, it is applied to each
+// value of key before that value is matched or interned. If top is
+// positive, Schema.Bucket limits key to its top most frequent values.
+// If def is non-nil, it is the value of key in Results that don't
+// have key.
+func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, exact []string, xform func([]byte) []byte, top int, def []byte) error {
+	if def != nil && (key == ".config" || key == ".fullname" || key == ".id") {
+		return fmt.Errorf("default value not allowed for %s", key)
+	}
+
 	// Construct the order function.
 	var initField func(field Field)
 	var match func(a []byte) bool
@@ -186,6 +311,7 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 			field.less = func(a, b string) bool {
 				return exactMap[a] < exactMap[b]
 			}
+			field.orderName = "fixed"
 		}
 		match = func(a []byte) bool {
 			_, ok := exactMap[string(a)]
@@ -194,14 +320,23 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 	} else if order == "first" {
 		initField = func(field Field) {
 			field.order = make(map[string]int)
+			field.orderName = "first"
 		}
-	} else if less, ok := builtinOrders[order]; ok {
+	} else if less, ok := lookupOrder(order); ok {
 		initField = func(field Field) {
 			field.less = less
+			field.orderName = order
 		}
 	} else {
 		return fmt.Errorf("unknown order %q", order)
 	}
+	if top > 0 {
+		initOrder := initField
+		initField = func(field Field) {
+			initOrder(field)
+			field.top = top
+		}
+	}
 
 	var project func(*benchfmt.Result, *[]string) bool
 	switch key {
@@ -227,7 +362,11 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 					seen[cfg.Key] = field
 				}
 
-				(*row)[field.idx] = s.intern(cfg.Value)
+				val := cfg.Value
+				if xform != nil {
+					val = xform(val)
+				}
+				(*row)[field.idx] = s.intern(val)
 			}
 			return true
 		}
@@ -249,6 +388,47 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 				p.fullExtractor = benchfmt.NewExtractorFullName(p.fullnameKeys)
 			}
 			val := p.fullExtractor(r)
+			if xform != nil {
+				val = xform(val)
+			}
+			if match != nil && !match(val) {
+				return false
+			}
+			(*row)[field.idx] = s.intern(val)
+			return true
+		}
+
+	case ".id":
+		// The complete identity of the result. This
+		// deliberately ignores exclusions from other
+		// projections.
+		field := s.addField(s.root, ".id")
+		initField(field)
+		var buf []byte
+		var cfgs []benchfmt.Config
+		project = func(r *benchfmt.Result, row *[]string) bool {
+			cfgs = append(cfgs[:0], r.FileConfig...)
+			sort.Slice(cfgs, func(i, j int) bool {
+				return cfgs[i].Key < cfgs[j].Key
+			})
+			// Neither keys, values, nor names can contain
+			// newlines, so this encoding is unambiguous.
+			buf = buf[:0]
+			for _, cfg := range cfgs {
+				if len(cfg.Value) == 0 {
+					continue
+				}
+				buf = append(buf, cfg.Key...)
+				buf = append(buf, ": "...)
+				buf = append(buf, cfg.Value...)
+				buf = append(buf, '\n')
+			}
+			buf = append(buf, r.FullName...)
+
+			val := buf
+			if xform != nil {
+				val = xform(val)
+			}
 			if match != nil && !match(val) {
 				return false
 			}
@@ -261,6 +441,8 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 		// to the excludes.
 		if key == ".name" || strings.HasPrefix(key, "/") {
 			p.fullnameKeys = append(p.fullnameKeys, key)
+		} else if key == ".gomaxprocs" {
+			p.fullnameKeys = append(p.fullnameKeys, "/gomaxprocs")
 		} else {
 			p.configKeys[key] = true
 		}
@@ -268,10 +450,26 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 		if err != nil {
 			return err
 		}
+		nameExt, _ := benchfmt.NewNameExtractor(key)
+		var missing []byte
+		if p.MissingValue != "" {
+			missing = []byte(p.MissingValue)
+		}
 		field := s.addField(s.root, key)
 		initField(field)
+		ki := len(s.extractKeys)
+		s.extractKeys = append(s.extractKeys, key)
 		project = func(r *benchfmt.Result, row *[]string) bool {
-			val := ext(r)
+			val := s.extract(ki, ext, nameExt, r)
+			if val == nil {
+				val = def
+			}
+			if val != nil && xform != nil {
+				val = xform(val)
+			}
+			if val == nil && missing != nil {
+				val = missing
+			}
 			if match != nil && !match(val) {
 				return false
 			}
@@ -283,6 +481,71 @@ func (p *ProjectionParser) makeProjection(s *Schema, key string, order string, e
 	return nil
 }
 
+// valueTransforms is the value transforms that can be applied to a
+// key using "@".
+var valueTransforms = map[string]func([]byte) []byte{
+	"lower": bytes.ToLower,
+	"upper": bytes.ToUpper,
+	"trim":  trimSpace,
+}
+
+// trimSpace is like bytes.TrimSpace, but returns a non-nil empty
+// slice for an all-space value so it doesn't project as missing.
+func trimSpace(val []byte) []byte {
+	val = bytes.TrimSpace(val)
+	if val == nil {
+		return []byte{}
+	}
+	return val
+}
+
+// RegisterOrder registers a custom sort order called name, which
+// projection expressions can then use like a built-in order, as in
+// "key@name". less must report whether value a sorts before value b.
+//
+// It returns an error if name is already a built-in or registered
+// order, or is the name of a value transform.
+func RegisterOrder(name string, less func(a, b string) bool) error {
+	ordersLock.Lock()
+	defer ordersLock.Unlock()
+	if _, ok := builtinOrders[name]; ok || name == "first" {
+		return fmt.Errorf("order %q is a built-in order", name)
+	}
+	if _, ok := valueTransforms[name]; ok {
+		return fmt.Errorf("order %q is a value transform", name)
+	}
+	if _, ok := customOrders[name]; ok {
+		return fmt.Errorf("order %q is already registered", name)
+	}
+	customOrders[name] = less
+	return nil
+}
+
+// unregisterOrder removes the order registered as name. This is for
+// tests, since the registry is global.
+func unregisterOrder(name string) {
+	ordersLock.Lock()
+	defer ordersLock.Unlock()
+	delete(customOrders, name)
+}
+
+var (
+	ordersLock   sync.Mutex
+	customOrders = make(map[string]func(a, b string) bool)
+)
+
+// lookupOrder returns the comparison function of the built-in or
+// registered order called name.
+func lookupOrder(name string) (func(a, b string) bool, bool) {
+	if less, ok := builtinOrders[name]; ok {
+		return less, true
+	}
+	ordersLock.Lock()
+	defer ordersLock.Unlock()
+	less, ok := customOrders[name]
+	return less, ok
+}
+
 // builtinOrders is the built-in comparison functions.
 var builtinOrders = map[string]func(a, b string) bool{
 	"alpha": func(a, b string) bool {
@@ -301,6 +564,28 @@ var builtinOrders = map[string]func(a, b string) bool{
 			return erra == nil
 		}
 	},
+	"date": func(a, b string) bool {
+		ta, oka := ParseDate(a)
+		tb, okb := ParseDate(b)
+		if oka && okb {
+			return ta.Before(tb)
+		} else if !oka && !okb {
+			// Fall back to string order.
+			return a < b
+		} else {
+			// Put dates before non-dates.
+			return oka
+		}
+	},
+}
+
+// ParseDate parses s as a date and time, as understood by the "date"
+// sort order. It accepts RFC 3339 timestamps, as well as other common
+// formats such as "2006-01-02 15:04:05 -0700" (the format of "git log
+// --date=iso"), "2006-01-02", and the formats of the date command.
+// Times without a time zone are in UTC.
+func ParseDate(s string) (time.Time, bool) {
+	return kvql.ParseDate(s)
 }
 
 // A Schema projects some subset of the components in a
@@ -339,6 +624,31 @@ type Schema struct {
 
 	// configs are the interned Configs of this Schema.
 	configs map[uint64][]*configNode
+
+	// configOrder is the interned Configs of this Schema in the
+	// order they were first produced.
+	configOrder []Config
+
+	// buckets are the interned Configs produced by Bucket. These
+	// are kept separate from configs so they don't appear in
+	// configOrder.
+	buckets map[uint64][]*configNode
+
+	// nResults is the number of Results successfully projected by
+	// this Schema.
+	nResults int
+
+	// extractKeys is the keys this Schema extracts with
+	// benchfmt.NewExtractor, indexed by the order they were
+	// added.
+	extractKeys []string
+
+	// cache, if non-nil, is the extraction cache of the
+	// ProjectionSet this Schema belongs to, and cacheSlots maps
+	// from an index in extractKeys to a slot in cache, or -1 if
+	// that key isn't shared with other Schemas.
+	cache      *extractCache
+	cacheSlots []int
 }
 
 func newSchema() *Schema {
@@ -346,6 +656,7 @@ func newSchema() *Schema {
 	s.root.fieldInternal = &fieldInternal{idx: -1}
 	s.interns = make(map[string]string)
 	s.configs = make(map[uint64][]*configNode)
+	s.buckets = make(map[uint64][]*configNode)
 	return &s
 }
 
@@ -380,6 +691,19 @@ func (s *Schema) addGroup(group Field, name string) Field {
 // Typically, callers need to break out individual benchmark values on
 // some dimension of a set of Schemas. Adding a .unit field makes this
 // easy.
+//
+// The .unit field holds the canonical form of each value's unit, as
+// returned by benchunit.CanonicalUnit, so values whose units are
+// registered synonyms project to the same Config. Since aliases never
+// rescale a unit, these values can be combined directly.
+//
+// As a result, the .unit of a Config may not be the unit spelled in
+// the Result. For example, with "bytes" registered as an alias of
+// "B", a "bytes/op" value projects to a .unit of "B/op", so
+// res.Value(cfg.Get(unitField)) won't find it. The Configs returned
+// by ProjectValues correspond to r.Values by index, so callers that
+// need the original unit should use that. Likewise, callers looking
+// up unit metadata should compare canonical units.
 func (s *Schema) AddValues() Field {
 	if s.unitField.fieldInternal != nil {
 		panic("Schema already has a .unit field")
@@ -415,6 +739,48 @@ func (s *Schema) Fields() []Field {
 	return s.flatCache
 }
 
+// Describe returns a human-readable description of the fields of s
+// and their sort orders, as in "fields: goos(alpha), commit(first),
+// .unit". Fields limited with "@top(n)" also show their limit, as in
+// "commit(first, top 10)". Group fields, such as ".config", are shown with the fields
+// they have added so far in braces. Since projecting Results can add
+// fields to s, this reflects the current state of s.
+func (s *Schema) Describe() string {
+	var buf strings.Builder
+	var walk func(fields []Field)
+	walk = func(fields []Field) {
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(f.Name)
+			if f.idx == -1 {
+				buf.WriteString("{")
+				walk(f.sub)
+				buf.WriteString("}")
+			} else if f.top > 0 {
+				fmt.Fprintf(&buf, "(%s, top %d)", f.orderName, f.top)
+			} else if f.orderName != "" {
+				fmt.Fprintf(&buf, "(%s)", f.orderName)
+			}
+		}
+	}
+	buf.WriteString("fields: ")
+	if len(s.root.sub) == 0 {
+		buf.WriteString("none")
+	}
+	walk(s.root.sub)
+	return buf.String()
+}
+
+// Configs returns all of the distinct Configs s has produced so far,
+// in the order they were first produced. Each Config appears exactly
+// once. The caller may modify the returned slice; doing so does not
+// affect s.
+func (s *Schema) Configs() []Config {
+	return append([]Config(nil), s.configOrder...)
+}
+
 // A Field is a single dimension of a Schema.
 type Field struct {
 	Name string
@@ -443,6 +809,226 @@ type fieldInternal struct {
 	// order, if non-nil, records the observation order of this
 	// field.
 	order map[string]int
+
+	// format, if non-nil, formats values of this field for
+	// display.
+	format func(string) string
+
+	// orderName is the name of this field's sort order. See
+	// Field.OrderName.
+	orderName string
+
+	// counts records the number of projected Results that had
+	// each non-empty value of this field. It is nil until the
+	// field has a non-empty value.
+	counts map[string]int
+
+	// top, if positive, limits this field to its top most frequent
+	// values in Schema.Bucket. topSet caches these values as of
+	// when topResults Results had been projected.
+	top        int
+	topSet     map[string]bool
+	topResults int
+}
+
+// SetOrder sets the sort order of field f to a fixed order of
+// values. This is the programmatic equivalent of the "key:(val1
+// val2)" projection syntax, except that it does not filter out other
+// values: values not in order sort after all values in order, in
+// observation order. SetOrder replaces any order given by the
+// projection expression.
+func (f Field) SetOrder(order []string) {
+	if f.idx == -1 {
+		panic("cannot set the order of a group")
+	}
+	if f.order == nil {
+		// Reconstruct the observation order of values seen so
+		// far. This is exactly how internRow would have
+		// recorded them.
+		f.order = make(map[string]int)
+		for _, cfg := range f.schema.configOrder {
+			var val string
+			if f.idx < len(cfg.c.vals) {
+				val = cfg.c.vals[f.idx]
+			}
+			if _, ok := f.order[val]; !ok {
+				f.order[val] = len(f.order)
+			}
+		}
+	}
+	pos := make(map[string]int, len(order))
+	for i, val := range order {
+		if _, ok := pos[val]; !ok {
+			pos[val] = i
+		}
+	}
+	f.orderName = "fixed"
+	f.less = func(a, b string) bool {
+		ia, oka := pos[a]
+		ib, okb := pos[b]
+		switch {
+		case oka && okb:
+			return ia < ib
+		case oka != okb:
+			// Listed values sort first.
+			return oka
+		}
+		return f.order[a] < f.order[b]
+	}
+}
+
+// OrderName returns the name of the sort order of field f. This is
+// the order given in the projection expression, such as "alpha" or
+// "date", or "first" for the default first-observation order, or
+// "fixed" for a fixed value order given by a "key:(val1 val2)"
+// projection or SetOrder. Tools can use this to present values
+// appropriately, for example, to format dates compactly. It is "" for
+// fields that weren't produced by a projection expression, such as
+// .unit.
+func (f Field) OrderName() string {
+	return f.orderName
+}
+
+// SetFormat sets a function for formatting values of field f for
+// display, such as shortening a commit hash. This affects how values
+// are presented in ConfigHeaders, but not how Configs are grouped or
+// sorted, which always use the actual values. A nil format displays
+// values as they are.
+func (f Field) SetFormat(format func(value string) string) {
+	f.format = format
+}
+
+// ValueCount returns the number of Results projected by f's Schema so
+// far that had value for field f. This is useful for hiding rarely
+// seen values. Results that were filtered out by the projection are
+// not counted. For a ".unit" field, this counts benchmark values
+// rather than Results.
+func (f Field) ValueCount(value string) int {
+	if f.idx == -1 {
+		panic("cannot count the values of a group")
+	}
+	if value != "" {
+		return f.counts[value]
+	}
+	// Empty values aren't recorded, since a field added after
+	// some Results were projected implicitly had the empty value
+	// for all earlier Results. Instead, derive the count from
+	// the other values.
+	if f.schema.unitField.fieldInternal == f.fieldInternal {
+		// Every benchmark value has a unit.
+		return 0
+	}
+	n := f.schema.nResults
+	for _, c := range f.counts {
+		n -= c
+	}
+	return n
+}
+
+// OtherValue is the value that Schema.Bucket substitutes for values
+// outside the top values of a field projected with "@top(n)".
+const OtherValue = "other"
+
+// Bucket returns Config c with the value of each field projected with
+// "@top(n)" replaced by OtherValue, unless it is one of the n values
+// of that field that were most frequent among the Results projected
+// so far. Empty values are left as is. Since the most frequent values
+// can change as more Results are projected, callers should generally
+// project all Results before bucketing their Configs.
+//
+// Bucketed Configs are not produced by projecting Results, so they
+// are not included in Schema.Configs. A bucketed value is distinct
+// from an actual value of OtherValue: the two Configs are not equal,
+// and the bucketed value sorts after the actual value. Use
+// Config.Bucketed to tell them apart.
+//
+// If s has no "@top" fields, Bucket returns c.
+func (s *Schema) Bucket(c Config) Config {
+	if c.c.schema != s {
+		panic("Config is not from this Schema")
+	}
+	var other []bool
+	for _, field := range s.Fields() {
+		if field.top <= 0 || field.idx >= len(c.c.vals) || c.c.bucketed(field.idx) {
+			continue
+		}
+		val := c.c.vals[field.idx]
+		if val == "" || field.topValues()[val] {
+			continue
+		}
+		if other == nil {
+			other = make([]bool, len(c.c.vals))
+			copy(other, c.c.other)
+			for i := range s.row {
+				s.row[i] = ""
+			}
+			copy(s.row, c.c.vals)
+		}
+		s.row[field.idx] = OtherValue
+		other[field.idx] = true
+	}
+	if other == nil {
+		return c
+	}
+	return s.internBucket(other)
+}
+
+// internBucket interns s.row as a Config whose fields at the indexes
+// marked in other have been bucketed.
+func (s *Schema) internBucket(other []bool) Config {
+	row := trimRow(s.row)
+	hash := hashRow(row)
+	for _, config := range s.buckets[hash] {
+		if config.equalRow(row) && equalBools(config.other, other) {
+			return Config{config}
+		}
+	}
+	config := &configNode{schema: s, vals: append([]string(nil), row...), other: other}
+	s.buckets[hash] = append(s.buckets[hash], config)
+	return Config{config}
+}
+
+func equalBools(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// topValues returns the set of the f.top most frequent non-empty
+// values of f.
+func (f Field) topValues() map[string]bool {
+	if f.topSet != nil && f.topResults == f.schema.nResults {
+		return f.topSet
+	}
+	vals := make([]string, 0, len(f.counts))
+	for val := range f.counts {
+		vals = append(vals, val)
+	}
+	sort.Slice(vals, func(i, j int) bool {
+		ci, cj := f.counts[vals[i]], f.counts[vals[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		if f.less == nil {
+			return f.order[vals[i]] < f.order[vals[j]]
+		}
+		return f.less(vals[i], vals[j])
+	})
+	if len(vals) > f.top {
+		vals = vals[:f.top]
+	}
+	f.topSet = make(map[string]bool, len(vals))
+	for _, val := range vals {
+		f.topSet[val] = true
+	}
+	f.topResults = f.schema.nResults
+	return f.topSet
 }
 
 var configSeed = maphash.MakeSeed()
@@ -457,6 +1043,7 @@ func (s *Schema) Project(r *benchfmt.Result) (Config, bool) {
 	if !s.populateRow(r) {
 		return Config{}, false
 	}
+	s.countRow()
 	return s.internRow(), true
 }
 
@@ -471,6 +1058,7 @@ func (s *Schema) ProjectValues(r *benchfmt.Result) ([]Config, bool) {
 	if !s.populateRow(r) {
 		return nil, false
 	}
+	s.countRow()
 	out := make([]Config, len(r.Values))
 	if s.unitField.fieldInternal == nil {
 		// There's no .unit, so the Configs will all be the same.
@@ -482,7 +1070,9 @@ func (s *Schema) ProjectValues(r *benchfmt.Result) ([]Config, bool) {
 	}
 	// Vary the .unit field.
 	for i, val := range r.Values {
-		s.row[s.unitField.idx] = val.Unit
+		unit := benchunit.CanonicalUnit(val.Unit)
+		s.row[s.unitField.idx] = unit
+		s.unitField.count(unit)
 		out[i] = s.internRow()
 	}
 	return out, true
@@ -504,22 +1094,32 @@ func (s *Schema) populateRow(r *benchfmt.Result) bool {
 	return true
 }
 
+// countRow records the values in s.row in the value counts of each
+// field.
+func (s *Schema) countRow() {
+	s.nResults++
+	for _, field := range s.Fields() {
+		if field.idx < len(s.row) && s.row[field.idx] != "" {
+			field.count(s.row[field.idx])
+		}
+	}
+}
+
+func (f Field) count(val string) {
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+	}
+	f.counts[val]++
+}
+
 func (s *Schema) internRow() Config {
 	// Hash the configuration. This must be invariant to unused
 	// trailing fields: the schema can grow, and if those new
 	// fields are later cleared, we want configurations from
 	// before the growth to equal configurations from after the
 	// growth.
-	row := s.row
-	for len(row) > 0 && row[len(row)-1] == "" {
-		row = row[:len(row)-1]
-	}
-	var h maphash.Hash
-	h.SetSeed(configSeed)
-	for _, val := range row {
-		h.WriteString(val)
-	}
-	hash := h.Sum64()
+	row := trimRow(s.row)
+	hash := hashRow(row)
 
 	// Check if we already have this configuration.
 	configs := s.configs[hash]
@@ -545,11 +1145,29 @@ func (s *Schema) internRow() Config {
 	}
 
 	// Save the config.
-	config := &configNode{s, append([]string(nil), row...)}
+	config := &configNode{schema: s, vals: append([]string(nil), row...)}
 	s.configs[hash] = append(s.configs[hash], config)
+	s.configOrder = append(s.configOrder, Config{config})
 	return Config{config}
 }
 
+// trimRow returns row with trailing "" values removed.
+func trimRow(row []string) []string {
+	for len(row) > 0 && row[len(row)-1] == "" {
+		row = row[:len(row)-1]
+	}
+	return row
+}
+
+func hashRow(row []string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(configSeed)
+	for _, val := range row {
+		h.WriteString(val)
+	}
+	return h.Sum64()
+}
+
 func (s *Schema) intern(b []byte) string {
 	if str, ok := s.interns[string(b)]; ok {
 		return str
@@ -590,6 +1208,22 @@ func (c Config) Get(f Field) string {
 	return c.c.vals[idx]
 }
 
+// Bucketed returns whether Schema.Bucket replaced the value of Field
+// f in c with OtherValue, as opposed to c having the actual value
+// OtherValue.
+//
+// It panics if Field f does not come from the same Schema as the
+// Config.
+func (c Config) Bucketed(f Field) bool {
+	if c.IsZero() {
+		panic("zero Config has no fields")
+	}
+	if c.c.schema != f.schema {
+		panic("Config and Field have different Schemas")
+	}
+	return c.c.bucketed(f.idx)
+}
+
 // Schema returns the Schema describing Config c.
 func (c Config) Schema() *Schema {
 	if c.IsZero() {
@@ -623,6 +1257,39 @@ func (c Config) String() string {
 	return buf.String()
 }
 
+// StableHash returns a hash of the field names and values of c. Unlike
+// Config equality, which depends on the identity of c's Schema, this
+// hash is deterministic across Schemas and processes: Configs that
+// have the same values for the same field names have the same hash,
+// regardless of which Schema produced them or the order of their
+// fields. This makes it suitable for sharding or cache keys.
+//
+// Fields with empty values are ignored, consistent with how Config
+// treats missing fields.
+func (c Config) StableHash() uint64 {
+	if c.IsZero() {
+		return 0
+	}
+	type kv struct{ k, v string }
+	var kvs []kv
+	for _, field := range c.c.schema.Fields() {
+		if field.idx < len(c.c.vals) && c.c.vals[field.idx] != "" {
+			kvs = append(kvs, kv{field.Name, c.c.vals[field.idx]})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].k < kvs[j].k
+	})
+	h := fnv.New64a()
+	for _, kv := range kvs {
+		io.WriteString(h, kv.k)
+		h.Write([]byte{0})
+		io.WriteString(h, kv.v)
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
 // commonSchema returns the Schema that all configs have, or panics if
 // any Config has a different Schema. It returns nil if len(configs)
 // == 0.
@@ -652,6 +1319,13 @@ type configNode struct {
 	// of a schema on-the-fly, and we need to not invalidate
 	// existing Configs.
 	vals []string
+	// other, if non-nil, marks the indexes of vals that were
+	// bucketed by Schema.Bucket.
+	other []bool
+}
+
+func (n *configNode) bucketed(idx int) bool {
+	return idx < len(n.other) && n.other[idx]
 }
 
 func (n *configNode) equalRow(row []string) bool {
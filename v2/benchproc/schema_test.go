@@ -0,0 +1,743 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestProjectMissingValue(t *testing.T) {
+	project := func(t *testing.T, s *Schema, fullName string) Config {
+		t.Helper()
+		cfg, ok := s.Project(&benchfmt.Result{FullName: []byte(fullName)})
+		if !ok {
+			t.Fatalf("projecting %s: unexpectedly filtered", fullName)
+		}
+		return cfg
+	}
+
+	t.Run("default", func(t *testing.T) {
+		var p ProjectionParser
+		s, err := p.Parse("/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		missing := project(t, s, "Name")
+		empty := project(t, s, "Name/a=")
+		if missing != empty {
+			t.Errorf("want missing and empty to be the same Config, got %s and %s", missing, empty)
+		}
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		p := ProjectionParser{MissingValue: "<unset>"}
+		s, err := p.Parse("/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		field := s.Fields()[0]
+		missing := project(t, s, "Name")
+		empty := project(t, s, "Name/a=")
+		val := project(t, s, "Name/a=1")
+		if missing == empty {
+			t.Errorf("want missing and empty to be distinct Configs, both are %s", missing)
+		}
+		if got := missing.Get(field); got != "<unset>" {
+			t.Errorf("missing: got %q, want %q", got, "<unset>")
+		}
+		if got := empty.Get(field); got != "" {
+			t.Errorf("empty: got %q, want %q", got, "")
+		}
+		if got := val.Get(field); got != "1" {
+			t.Errorf("value: got %q, want %q", got, "1")
+		}
+		if missing != project(t, s, "Other") {
+			t.Errorf("want all missing values to project to the same Config")
+		}
+	})
+
+	t.Run("trim", func(t *testing.T) {
+		// Trimming an all-space value leaves it present.
+		p := ProjectionParser{MissingValue: "<unset>"}
+		s, err := p.Parse("/a@trim")
+		if err != nil {
+			t.Fatal(err)
+		}
+		field := s.Fields()[0]
+		if got := project(t, s, "Name/a= ").Get(field); got != "" {
+			t.Errorf("empty: got %q, want %q", got, "")
+		}
+		if got := project(t, s, "Name").Get(field); got != "<unset>" {
+			t.Errorf("missing: got %q, want %q", got, "<unset>")
+		}
+	})
+
+	t.Run("exact", func(t *testing.T) {
+		// The sentinel can be matched by an exact order.
+		p := ProjectionParser{MissingValue: "<unset>"}
+		s, err := p.Parse("/a:(1 <unset>)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := s.Project(&benchfmt.Result{FullName: []byte("Name")}); !ok {
+			t.Errorf("want missing key to match sentinel")
+		}
+		if _, ok := s.Project(&benchfmt.Result{FullName: []byte("Name/a=")}); ok {
+			t.Errorf("want empty key to be filtered")
+		}
+	})
+}
+
+func TestParseFixedOrder(t *testing.T) {
+	// Parse used to leave the closing ")" of a fixed order
+	// unconsumed, so any projection with a fixed order failed
+	// with "expected ,".
+	var p ProjectionParser
+	for _, proj := range []string{"goos:(linux darwin)", "goos:(linux darwin),.name"} {
+		s, err := p.Parse(proj)
+		if err != nil {
+			t.Errorf("%s: %v", proj, err)
+			continue
+		}
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("goos", "darwin")
+		if _, ok := s.Project(res); !ok {
+			t.Errorf("%s: want darwin to match", proj)
+		}
+	}
+}
+
+func TestSchemaConfigs(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse(".name,/n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Configs(); len(got) != 0 {
+		t.Errorf("new Schema: want no configs, got %v", got)
+	}
+
+	var want []Config
+	seen := make(map[Config]bool)
+	for _, name := range []string{"B/n=1", "A/n=1", "B/n=1", "A/n=2", "A/n=1", "B"} {
+		cfg, ok := s.Project(&benchfmt.Result{FullName: []byte(name)})
+		if !ok {
+			t.Fatalf("projecting %s: unexpectedly filtered", name)
+		}
+		if !seen[cfg] {
+			seen[cfg] = true
+			want = append(want, cfg)
+		}
+	}
+	got := s.Configs()
+	if len(got) != len(want) {
+		t.Fatalf("want %d configs %v, got %d configs %v", len(want), want, len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("config %d: want %s, got %s", i, want[i], got[i])
+		}
+	}
+
+	// Modifying the result must not affect the Schema.
+	got[0] = Config{}
+	if s.Configs()[0] != want[0] {
+		t.Errorf("modifying Configs result changed Schema")
+	}
+}
+
+func TestProjectID(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse(".id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other projections don't affect .id.
+	if _, err := p.Parse("goos,/n"); err != nil {
+		t.Fatal(err)
+	}
+
+	project := func(name string, kv ...string) Config {
+		t.Helper()
+		res := &benchfmt.Result{FullName: []byte(name)}
+		for i := 0; i < len(kv); i += 2 {
+			res.SetFileConfig(kv[i], kv[i+1])
+		}
+		cfg, ok := s.Project(res)
+		if !ok {
+			t.Fatalf("projecting %s: unexpectedly filtered", name)
+		}
+		return cfg
+	}
+
+	a := project("Name/n=1", "goos", "linux", "goarch", "amd64")
+	b := project("Name/n=1", "goarch", "amd64", "goos", "linux")
+	if a != b {
+		t.Errorf("want same .id regardless of config order, got:\n%s\n%s", a, b)
+	}
+	want := "goarch: amd64\ngoos: linux\nName/n=1"
+	if got := a.Get(s.Fields()[0]); got != want {
+		t.Errorf("want .id %q, got %q", want, got)
+	}
+
+	for _, c := range []Config{
+		project("Name/n=2", "goos", "linux", "goarch", "amd64"),
+		project("Name/n=1", "goos", "darwin", "goarch", "amd64"),
+		project("Name/n=1", "goos", "linux"),
+		project("Name/n=1", "goos", "linux", "goarch", "amd64", "commit", "abc"),
+	} {
+		if c == a {
+			t.Errorf("want different .id from %q, got same", c)
+		}
+	}
+}
+
+func TestStableHash(t *testing.T) {
+	results := []*benchfmt.Result{
+		{FullName: []byte("A/n=1"), FileConfig: []benchfmt.Config{{Key: "goos", Value: []byte("linux")}, {Key: "goarch", Value: []byte("amd64")}}},
+		{FullName: []byte("B/n=2"), FileConfig: []benchfmt.Config{{Key: "goarch", Value: []byte("arm64")}, {Key: "goos", Value: []byte("darwin")}}},
+		{FullName: []byte("A/n=1"), FileConfig: []benchfmt.Config{{Key: "goarch", Value: []byte("amd64")}, {Key: "goos", Value: []byte("linux")}}},
+	}
+	// Simulate independent processes with independent Schemas,
+	// which observe the results in different orders.
+	hashes := func(order []int) map[int]uint64 {
+		var p ProjectionParser
+		s, err := p.Parse(".config,.name,/n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[int]uint64)
+		for _, i := range order {
+			cfg, _ := s.Project(results[i].Clone())
+			out[i] = cfg.StableHash()
+		}
+		return out
+	}
+	h1 := hashes([]int{0, 1, 2})
+	h2 := hashes([]int{2, 1, 0})
+	for i := range results {
+		if h1[i] != h2[i] {
+			t.Errorf("result %d: hashes differ between schemas: %#x != %#x", i, h1[i], h2[i])
+		}
+	}
+	if h1[0] != h1[2] {
+		t.Errorf("want equal configs to have equal hashes, got %#x and %#x", h1[0], h1[2])
+	}
+	if h1[0] == h1[1] {
+		t.Errorf("want different configs to have different hashes, both are %#x", h1[0])
+	}
+	// The hash must not depend on per-process state.
+	const want = 0x8a069a311db5dd7
+	if h1[0] != want {
+		t.Errorf("want stable hash %#x, got %#x", uint64(want), h1[0])
+	}
+}
+
+func TestProjectTransform(t *testing.T) {
+	res := func(goos, commit string) *benchfmt.Result {
+		return &benchfmt.Result{
+			FullName: []byte("Name"),
+			FileConfig: []benchfmt.Config{
+				{Key: "goos", Value: []byte(goos)},
+				{Key: "commit", Value: []byte(commit)},
+			},
+		}
+	}
+	project := func(t *testing.T, proj string, results ...*benchfmt.Result) []Config {
+		t.Helper()
+		var p ProjectionParser
+		s, err := p.Parse(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfgs []Config
+		for _, r := range results {
+			cfg, ok := s.Project(r)
+			if !ok {
+				t.Fatalf("unexpectedly filtered %v", r)
+			}
+			cfgs = append(cfgs, cfg)
+		}
+		return cfgs
+	}
+
+	t.Run("lower", func(t *testing.T) {
+		cfgs := project(t, "goos@lower", res("Linux", "a"), res("linux", "a"), res("LINUX", "a"), res("darwin", "a"))
+		if cfgs[0] != cfgs[1] || cfgs[0] != cfgs[2] {
+			t.Errorf("want Linux, linux, and LINUX to group together, got %s, %s, %s", cfgs[0], cfgs[1], cfgs[2])
+		}
+		if cfgs[0] == cfgs[3] {
+			t.Errorf("want linux and darwin to be distinct")
+		}
+		if got := cfgs[0].String(); got != "goos:linux" {
+			t.Errorf("want goos:linux, got %s", got)
+		}
+	})
+
+	t.Run("upper", func(t *testing.T) {
+		cfgs := project(t, "goos@upper", res("Linux", "a"))
+		if got := cfgs[0].String(); got != "goos:LINUX" {
+			t.Errorf("want goos:LINUX, got %s", got)
+		}
+	})
+
+	t.Run("trim", func(t *testing.T) {
+		cfgs := project(t, "commit@trim", res("linux", " a\t"), res("linux", "a"))
+		if cfgs[0] != cfgs[1] {
+			t.Errorf("want trimmed commits to group together, got %s and %s", cfgs[0], cfgs[1])
+		}
+	})
+
+	t.Run("compose", func(t *testing.T) {
+		cfgs := project(t, "goos@trim@lower@alpha", res(" Linux ", "a"), res("linux", "a"), res("Darwin", "a"))
+		if cfgs[0] != cfgs[1] {
+			t.Errorf("want composed transforms to group together, got %s and %s", cfgs[0], cfgs[1])
+		}
+		if !cfgs[2].Less(cfgs[0]) {
+			t.Errorf("want alpha order to sort %s before %s", cfgs[2], cfgs[0])
+		}
+	})
+
+	t.Run("exact", func(t *testing.T) {
+		// Transforms apply before exact matching.
+		cfgs := project(t, "goos@lower:(linux)", res("Linux", "a"))
+		if got := cfgs[0].String(); got != "goos:linux" {
+			t.Errorf("want goos:linux, got %s", got)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		cfgs := project(t, ".config@lower", res("Linux", "A"), res("linux", "a"))
+		if cfgs[0] != cfgs[1] {
+			t.Errorf("want .config transforms to group together, got %s and %s", cfgs[0], cfgs[1])
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var p ProjectionParser
+		if _, err := p.Parse("goos@alpha@numeric"); err == nil || !strings.Contains(err.Error(), "multiple sort orders") {
+			t.Errorf("want multiple sort orders error, got %v", err)
+		}
+	})
+}
+
+func TestRegisterOrder(t *testing.T) {
+	tiers := map[string]int{"dev": 0, "staging": 1, "prod": 2}
+	err := RegisterOrder("tier", func(a, b string) bool {
+		return tiers[a] < tiers[b]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterOrder("tier")
+
+	// Collisions are errors.
+	for _, name := range []string{"tier", "alpha", "numeric", "first", "lower"} {
+		if err := RegisterOrder(name, func(a, b string) bool { return a < b }); err == nil {
+			t.Errorf("registering %q: want error", name)
+		}
+	}
+
+	var p ProjectionParser
+	s, err := p.Parse("env@tier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfgs []Config
+	for _, env := range []string{"prod", "dev", "staging"} {
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("env", env)
+		cfg, _ := s.Project(res)
+		cfgs = append(cfgs, cfg)
+	}
+	SortConfigs(cfgs)
+	var got []string
+	for _, cfg := range cfgs {
+		got = append(got, cfg.Get(s.Fields()[0]))
+	}
+	if want := "dev staging prod"; strings.Join(got, " ") != want {
+		t.Errorf("want order %s, got %s", want, strings.Join(got, " "))
+	}
+
+	if _, err := p.Parse("env@nonesuch"); err == nil {
+		t.Errorf("unregistered order: want error")
+	}
+}
+
+func TestFieldSetOrder(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := s.Fields()[0]
+	project := func(env string) Config {
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("env", env)
+		cfg, ok := s.Project(res)
+		if !ok {
+			t.Fatalf("projecting %s: unexpectedly filtered", env)
+		}
+		return cfg
+	}
+	// Observe some values before setting the order.
+	var cfgs []Config
+	for _, env := range []string{"z", "prod", "y"} {
+		cfgs = append(cfgs, project(env))
+	}
+	field.SetOrder([]string{"dev", "staging", "prod"})
+	// And some after.
+	for _, env := range []string{"x", "staging", "dev"} {
+		cfgs = append(cfgs, project(env))
+	}
+
+	SortConfigs(cfgs)
+	var got []string
+	for _, cfg := range cfgs {
+		got = append(got, cfg.Get(field))
+	}
+	if want := "dev staging prod z y x"; strings.Join(got, " ") != want {
+		t.Errorf("want order %s, got %s", want, strings.Join(got, " "))
+	}
+}
+
+func TestProjectEmpty(t *testing.T) {
+	for _, proj := range []string{"", "  "} {
+		var p ProjectionParser
+		s, err := p.Parse(proj)
+		if err != nil {
+			t.Fatalf("%q: %s", proj, err)
+		}
+		if len(s.Fields()) != 0 {
+			t.Errorf("%q: want no fields, got %v", proj, s.Fields())
+		}
+		var cfgs []Config
+		for _, name := range []string{"One", "Two/a=1", "Two/a=2-4"} {
+			res := &benchfmt.Result{FullName: []byte(name)}
+			res.SetFileConfig("goos", name)
+			cfg, ok := s.Project(res)
+			if !ok {
+				t.Fatalf("%q: projecting %s: unexpectedly filtered", proj, name)
+			}
+			cfgs = append(cfgs, cfg)
+		}
+		for _, cfg := range cfgs[1:] {
+			if cfg != cfgs[0] {
+				t.Errorf("%q: want all Configs equal, got %s and %s", proj, cfgs[0], cfg)
+			}
+		}
+		if got := s.Configs(); len(got) != 1 {
+			t.Errorf("%q: want 1 distinct Config, got %d", proj, len(got))
+		}
+
+		// The empty projection doesn't exclude anything from
+		// the remainder.
+		if got := len(p.Remainder().Fields()); got != 1 {
+			t.Errorf("%q: want 1 remainder field, got %d", proj, got)
+		}
+	}
+
+	// A trailing comma is still an error.
+	var p ProjectionParser
+	if _, err := p.Parse("goos,"); err == nil {
+		t.Errorf("want error for trailing comma")
+	}
+}
+
+func TestFieldValueCount(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("goos:(linux darwin),.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := s.AddValues()
+	for _, cfg := range [][2]string{{"linux", ""}, {"linux", ""}, {"darwin", ""}, {"windows", ""}, {"linux", "a"}, {"darwin", "b"}, {"linux", "b"}} {
+		res := &benchfmt.Result{FullName: []byte("Name"), Values: []benchfmt.Value{{Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}}}
+		res.SetFileConfig("goos", cfg[0])
+		res.SetFileConfig("x", cfg[1])
+		s.ProjectValues(res)
+	}
+
+	fields := make(map[string]Field)
+	for _, f := range s.Fields() {
+		fields[f.Name] = f
+	}
+	check := func(field, value string, want int) {
+		t.Helper()
+		if got := fields[field].ValueCount(value); got != want {
+			t.Errorf("%s=%q: want count %d, got %d", field, value, want, got)
+		}
+	}
+	check("goos", "linux", 4)
+	check("goos", "darwin", 2)
+	// Filtered out by the projection.
+	check("goos", "windows", 0)
+	check("goos", "", 0)
+	// x was added after several Results had been projected.
+	check("x", "a", 1)
+	check("x", "b", 2)
+	check("x", "", 3)
+	check("x", "c", 0)
+	check(unit.Name, "ns/op", 6)
+	check(unit.Name, "", 0)
+}
+
+func TestProjectDateOrder(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("date@date,goos,arch:(amd64 arm64)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfgs []Config
+	for _, date := range []string{"2021-03-01", "unknown", "2020-12-31T23:00:00-05:00", "2021-01-01 03:00:00 +0000", "Mon Jan  4 10:00:00 UTC 2021"} {
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("date", date)
+		res.SetFileConfig("arch", "amd64")
+		cfg, _ := s.Project(res)
+		cfgs = append(cfgs, cfg)
+	}
+	SortConfigs(cfgs)
+	fields := s.Fields()
+	var got []string
+	for _, cfg := range cfgs {
+		got = append(got, cfg.Get(fields[0]))
+	}
+	// 2020-12-31T23:00:00-05:00 is after 2021-01-01 03:00 UTC.
+	want := []string{"2021-01-01 03:00:00 +0000", "2020-12-31T23:00:00-05:00", "Mon Jan  4 10:00:00 UTC 2021", "2021-03-01", "unknown"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("want order %q, got %q", want, got)
+	}
+
+	for i, want := range []string{"date", "first", "fixed"} {
+		if got := fields[i].OrderName(); got != want {
+			t.Errorf("field %s: want order %q, got %q", fields[i].Name, want, got)
+		}
+	}
+	if got := s.AddValues().OrderName(); got != "" {
+		t.Errorf(".unit: want order \"\", got %q", got)
+	}
+}
+
+func TestSchemaDescribe(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("goos:(linux darwin),/n@numeric,.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(want string) {
+		t.Helper()
+		if got := s.Describe(); got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	}
+	check("fields: goos(fixed), /n(numeric), .config{}")
+
+	// Projecting adds fields to .config.
+	res := &benchfmt.Result{FullName: []byte("Name/n=1")}
+	res.SetFileConfig("goos", "linux")
+	res.SetFileConfig("commit", "abc")
+	res.SetFileConfig("branch", "master")
+	s.Project(res)
+	s.AddValues()
+	check("fields: goos(fixed), /n(numeric), .config{commit(first), branch(first)}, .unit")
+
+	s, _ = p.Parse("")
+	check("fields: none")
+}
+
+func TestProjectTop(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("commit@top(2),goos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfgs []Config
+	for _, commit := range []string{"a", "b", "b", "c", "c", "c", "d", ""} {
+		res := &benchfmt.Result{FullName: []byte("Name")}
+		res.SetFileConfig("commit", commit)
+		res.SetFileConfig("goos", "linux")
+		cfg, _ := s.Project(res)
+		cfgs = append(cfgs, cfg)
+	}
+
+	// Only the two most frequent commits keep their identity.
+	seen := make(map[Config]bool)
+	var got []Config
+	for _, cfg := range cfgs {
+		cfg = s.Bucket(cfg)
+		if !seen[cfg] {
+			seen[cfg] = true
+			got = append(got, cfg)
+		}
+	}
+	SortConfigs(got)
+	var names []string
+	for _, cfg := range got {
+		names = append(names, cfg.String())
+	}
+	// "" is never bucketed, and "other" sorts last.
+	want := "commit:b goos:linux|commit:c goos:linux|goos:linux|commit:other goos:linux"
+	if strings.Join(names, "|") != want {
+		t.Errorf("want %s, got %v", want, names)
+	}
+	if got := s.Describe(); got != "fields: commit(first, top 2), goos(first)" {
+		t.Errorf("want top in description, got %s", got)
+	}
+
+	// Ties are broken by sort order.
+	s, _ = p.Parse("x@top(1)@alpha")
+	for _, x := range []string{"z", "y"} {
+		res := &benchfmt.Result{}
+		res.SetFileConfig("x", x)
+		s.Project(res)
+	}
+	res := &benchfmt.Result{}
+	res.SetFileConfig("x", "z")
+	cfg, _ := s.Project(res)
+	// z now has more observations than y.
+	if got := s.Bucket(cfg).String(); got != "x:z" {
+		t.Errorf("want x:z, got %s", got)
+	}
+	res.SetFileConfig("x", "y")
+	cfg, _ = s.Project(res)
+	if got := s.Bucket(cfg).String(); got != "x:y" {
+		t.Errorf("after tie, want x:y, got %s", got)
+	}
+
+	// A bucketed value is distinct from an actual value of
+	// "other", sorts after it, and isn't listed by Configs.
+	s, _ = p.Parse("x@top(1)")
+	var actual Config
+	for _, x := range []string{"other", "other", "y"} {
+		res := &benchfmt.Result{}
+		res.SetFileConfig("x", x)
+		actual, _ = s.Project(res)
+	}
+	bucket := s.Bucket(actual)
+	res = &benchfmt.Result{}
+	res.SetFileConfig("x", "other")
+	actual, _ = s.Project(res)
+	x := s.Fields()[0]
+	if bucket == actual || s.Bucket(actual) != actual {
+		t.Errorf("bucketed value equals actual value %q", OtherValue)
+	}
+	if !bucket.Bucketed(x) || actual.Bucketed(x) {
+		t.Errorf("want only the bucketed Config to be bucketed")
+	}
+	if s.Bucket(bucket) != bucket {
+		t.Errorf("re-bucketing a bucketed Config changed it")
+	}
+	if !actual.Less(bucket) || bucket.Less(actual) {
+		t.Errorf("want bucketed value to sort after actual value %q", OtherValue)
+	}
+	for _, cfg := range s.Configs() {
+		if cfg == bucket {
+			t.Errorf("Configs includes bucketed Config %s", cfg)
+		}
+	}
+
+	for _, bad := range []string{"x@top", "x@top(", "x@top(0)", "x@top(a)", "x@top(1", "x@top(1)@top(2)"} {
+		if _, err := p.Parse(bad); err == nil {
+			t.Errorf("%s: want error", bad)
+		}
+	}
+}
+
+func TestProjectDefault(t *testing.T) {
+	var p ProjectionParser
+	s, err := p.Parse("gc=?on,/procs=?1@numeric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := func(gc, fullName string) Config {
+		t.Helper()
+		res := &benchfmt.Result{FullName: []byte(fullName)}
+		res.SetFileConfig("gc", gc)
+		cfg, ok := s.Project(res)
+		if !ok {
+			t.Fatalf("projecting gc=%q %s: unexpectedly filtered", gc, fullName)
+		}
+		return cfg
+	}
+
+	// Missing keys group with explicit occurrences of the default.
+	missing := project("", "Name")
+	explicit := project("on", "Name/procs=1")
+	if missing != explicit {
+		t.Errorf("want missing and default values to be the same Config, got %s and %s", missing, explicit)
+	}
+	if got := missing.String(); got != "gc:on /procs:1" {
+		t.Errorf("want gc:on /procs:1, got %s", got)
+	}
+	if other := project("off", "Name/procs=2"); other == missing {
+		t.Errorf("want other values to be distinct from the default")
+	}
+	// A present but empty name key is not missing.
+	if got := project("", "Name/procs=").String(); got != "gc:on" {
+		t.Errorf("want gc:on, got %s", got)
+	}
+
+	// Defaults are transformed and matched like other values.
+	s, err = p.Parse("goos=?LINUX@lower:(linux darwin)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, ok := s.Project(&benchfmt.Result{})
+	if !ok {
+		t.Fatalf("want default to match exact order")
+	}
+	if got := cfg.String(); got != "goos:linux" {
+		t.Errorf("want goos:linux, got %s", got)
+	}
+
+	for _, bad := range []string{"gc=?", ".config=?x", ".fullname=?x"} {
+		if _, err := p.Parse(bad); err == nil {
+			t.Errorf("%s: want error", bad)
+		}
+	}
+}
+
+func TestProjectValuesCanonicalUnit(t *testing.T) {
+	benchunit.RegisterAlias("bytes", "B")
+	defer benchunit.UnregisterAlias("bytes")
+
+	var p ProjectionParser
+	s, err := p.Parse(".name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := s.AddValues()
+	dists := make(map[Config][]float64)
+	var order []Config
+	for _, val := range []benchfmt.Value{{Value: 1, Unit: "B/op"}, {Value: 2, Unit: "bytes/op"}, {Value: 3, Unit: "ns/op"}} {
+		res := &benchfmt.Result{FullName: []byte("Name"), Values: []benchfmt.Value{val}}
+		cfgs, ok := s.ProjectValues(res)
+		if !ok {
+			t.Fatal("unexpectedly filtered")
+		}
+		if _, ok := dists[cfgs[0]]; !ok {
+			order = append(order, cfgs[0])
+		}
+		dists[cfgs[0]] = append(dists[cfgs[0]], val.Value)
+	}
+
+	// "B/op" and "bytes/op" are synonyms, so they should combine
+	// into one distribution.
+	var got []string
+	for _, cfg := range order {
+		got = append(got, fmt.Sprintf("%s=%v", cfg.Get(unit), dists[cfg]))
+	}
+	if want := "B/op=[1 2] ns/op=[3]"; strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+	if got := unit.ValueCount("B/op"); got != 2 {
+		t.Errorf("want .unit count 2 for B/op, got %d", got)
+	}
+}
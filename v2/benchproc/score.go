@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"math"
+	"sort"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A ValueScore scores each value of a unit relative to the other
+// values of that unit in its group of results.
+type ValueScore struct {
+	// Name is appended to a unit, separated by "-", to form the
+	// unit of the annotation, as in "ns/op-z".
+	Name string
+
+	// Prepare returns a function that computes the score of a
+	// value in a group whose values of the unit are vals. vals is
+	// non-empty and sorted in increasing order.
+	Prepare func(vals []float64) func(val float64) float64
+}
+
+// Scores for ScoreAnnotator.
+var (
+	// ScoreZ is the z-score of a value: the number of standard
+	// deviations the value is from the mean of its group, using
+	// the sample standard deviation. If all of the values in a
+	// group are equal, including in a group with only one value,
+	// their z-scores are 0.
+	ScoreZ = ValueScore{"z", func(vals []float64) func(float64) float64 {
+		mean := StatMean.Compute(vals)
+		var ss float64
+		for _, v := range vals {
+			ss += (v - mean) * (v - mean)
+		}
+		if ss == 0 {
+			return func(float64) float64 { return 0 }
+		}
+		stddev := math.Sqrt(ss / float64(len(vals)-1))
+		return func(val float64) float64 {
+			return (val - mean) / stddev
+		}
+	}}
+
+	// ScoreRank is the rank of a value in its group in
+	// increasing order, starting at 1. Equal values have the same
+	// rank, which is the lowest rank of those values.
+	ScoreRank = ValueScore{"rank", func(vals []float64) func(float64) float64 {
+		return func(val float64) float64 {
+			return float64(sort.SearchFloat64s(vals, val) + 1)
+		}
+	}}
+)
+
+// A ScoreAnnotator annotates each value of each result with scores
+// relative to the values of the same unit in the result's group, where
+// results are grouped by a projection. For example, it can add to
+// each result a "ns/op-z" value giving how many standard deviations
+// its "ns/op" is from the mean "ns/op" of all results of the same
+// benchmark, so downstream tools can flag outlier runs.
+//
+// Like a StatAnnotator, a ScoreAnnotator must see all results before
+// it can return any, and it is a Stage that emits its results when
+// flushed.
+type ScoreAnnotator struct {
+	scores []ValueScore
+	buf    groupBuffer
+}
+
+// NewScoreAnnotator returns a ScoreAnnotator that groups results by
+// group and annotates them with each of scores.
+func NewScoreAnnotator(group *Schema, scores ...ValueScore) *ScoreAnnotator {
+	return &ScoreAnnotator{scores: scores, buf: newGroupBuffer(group)}
+}
+
+// Add adds a copy of res to a, as for StatAnnotator.Add.
+func (a *ScoreAnnotator) Add(res *benchfmt.Result) {
+	a.buf.add(res, true, nil)
+}
+
+// Results returns the results added to a in the order they were
+// added, each annotated with a value for each score of a for each of
+// its values. Like StatAnnotator.Results, it should be called after
+// all results have been added, and it resets a.
+func (a *ScoreAnnotator) Results() []*benchfmt.Result {
+	return a.buf.annotate(func(vals []float64) valueAnnotator {
+		prepared := make([]func(float64) float64, len(a.scores))
+		for i, score := range a.scores {
+			prepared[i] = score.Prepare(vals)
+		}
+		return func(val benchfmt.Value, out []benchfmt.Value) []benchfmt.Value {
+			for i, score := range a.scores {
+				out = append(out, benchfmt.Value{Value: prepared[i](val.Value), Unit: val.Unit + "-" + score.Name})
+			}
+			return out
+		}
+	})
+}
+
+// Process adds res to a and takes ownership of it.
+func (a *ScoreAnnotator) Process(res *benchfmt.Result, emit func(*benchfmt.Result)) error {
+	a.buf.add(res, false, nil)
+	return nil
+}
+
+// Flush emits the scored results of a.
+func (a *ScoreAnnotator) Flush(emit func(*benchfmt.Result)) error {
+	for _, res := range a.Results() {
+		emit(res)
+	}
+	return nil
+}
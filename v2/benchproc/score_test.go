@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestScoreAnnotator(t *testing.T) {
+	// Run 5 of A is an outlier.
+	const input = `BenchmarkA 1 10 ns/op
+BenchmarkA 1 11 ns/op
+BenchmarkA 1 9 ns/op
+BenchmarkB 1 100 ns/op
+BenchmarkA 1 10 ns/op
+BenchmarkA 1 30 ns/op
+BenchmarkA 1 10 ns/op
+`
+	var p ProjectionParser
+	group, err := p.Parse(".name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewScoreAnnotator(group, ScoreZ, ScoreRank)
+	r := benchfmt.NewReader(strings.NewReader(input), "test")
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.Add(res)
+	}
+
+	results := a.Results()
+	if len(results) != 7 {
+		t.Fatalf("want 7 results, got %d", len(results))
+	}
+	// The sample standard deviation of A is sqrt(335.33/5), so
+	// the outlier's z-score is (30-13.33)/8.19.
+	wantZ := (30 - 80.0/6) / math.Sqrt((1006.0/3)/5)
+	for i, res := range results {
+		z, ok1 := res.Value("ns/op-z")
+		rank, ok2 := res.Value("ns/op-rank")
+		if !ok1 || !ok2 {
+			t.Errorf("result %d: missing scores in %v", i, res.Values)
+			continue
+		}
+		switch i {
+		case 3:
+			// B is alone in its group.
+			if z != 0 || rank != 1 {
+				t.Errorf("result %d: want z 0 rank 1, got z %v rank %v", i, z, rank)
+			}
+		case 5:
+			if math.Abs(z-wantZ) > 1e-9 || z < 2 {
+				t.Errorf("outlier: want z %v, got %v", wantZ, z)
+			}
+			if rank != 6 {
+				t.Errorf("outlier: want rank 6, got %v", rank)
+			}
+		default:
+			if math.Abs(z) >= 1 {
+				t.Errorf("result %d: want |z| < 1, got %v", i, z)
+			}
+		}
+	}
+	// Equal values share the lowest rank.
+	for _, i := range []int{0, 4, 6} {
+		if rank, _ := results[i].Value("ns/op-rank"); rank != 2 {
+			t.Errorf("result %d: want rank 2, got %v", i, rank)
+		}
+	}
+
+	if len(a.Results()) != 0 {
+		t.Errorf("want no results after Results")
+	}
+}
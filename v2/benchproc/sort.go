@@ -12,12 +12,20 @@ func (c Config) Less(o Config) bool {
 	if c.c.schema != o.c.schema {
 		panic("cannot compare Configs from different Schemas")
 	}
-	return less(c.c.schema.Fields(), c.c.vals, o.c.vals)
+	return less(c.c.schema.Fields(), c.c, o.c)
 }
 
-func less(flat []Field, a, b []string) bool {
+func less(flat []Field, an, bn *configNode) bool {
+	a, b := an.vals, bn.vals
 	// Walk the tuples in schema order.
 	for _, node := range flat {
+		if ao, bo := an.bucketed(node.idx), bn.bucketed(node.idx); ao || bo {
+			if ao != bo {
+				// Bucketed values sort last.
+				return bo
+			}
+			continue
+		}
 		var aa, bb string
 		if node.idx < len(a) {
 			aa = a[node.idx]
@@ -53,6 +61,6 @@ func SortConfigs(configs []Config) {
 	flat := s.Fields()
 
 	sort.Slice(configs, func(i, j int) bool {
-		return less(flat, configs[i].c.vals, configs[j].c.vals)
+		return less(flat, configs[i].c, configs[j].c)
 	})
 }
@@ -0,0 +1,96 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A GroupPolicy determines when StreamGroups considers a group to be
+// complete.
+type GroupPolicy int
+
+const (
+	// GroupsAtEnd buffers every group until the end of the input.
+	// This works for input in any order, but buffers the whole
+	// input.
+	GroupsAtEnd GroupPolicy = iota
+
+	// GroupsContiguous assumes the Results in each group are
+	// contiguous in the input, for example, because the input is
+	// sorted by the group projection. A group is complete as soon
+	// as a Result from a different group arrives, so StreamGroups
+	// buffers at most one group at a time. If a group reappears
+	// after it was complete, StreamGroups returns an error.
+	GroupsContiguous
+)
+
+// StreamGroups reads Results from src, groups them by projecting them
+// with the group Schema, and calls fn once for each group with the
+// group's Config and all of its Results. When fn is called depends
+// on policy, but in either case, groups are passed to fn in the order
+// they first appear in the input. Results filtered out by the
+// projection are skipped, as are malformed Results.
+//
+// fn owns the Results passed to it: they are copies of the Results
+// read from src.
+//
+// StreamGroups returns any I/O error from src, or an error if the
+// input violates policy.
+func StreamGroups(src ResultScanner, group *Schema, policy GroupPolicy, fn func(cfg Config, results []*benchfmt.Result)) error {
+	switch policy {
+	case GroupsAtEnd, GroupsContiguous:
+	default:
+		return fmt.Errorf("unknown GroupPolicy %d", policy)
+	}
+
+	var order []Config
+	groups := make(map[Config][]*benchfmt.Result)
+	// done records groups that have been passed to fn, for
+	// GroupsContiguous.
+	done := make(map[Config]bool)
+	var cur Config
+	for src.Scan() {
+		res, err := src.Result()
+		if err != nil {
+			continue
+		}
+		cfg, ok := group.Project(res)
+		if !ok {
+			continue
+		}
+		if policy == GroupsContiguous && cfg != cur {
+			if done[cfg] {
+				return fmt.Errorf("group %s is not contiguous in the input", cfg)
+			}
+			if !cur.IsZero() {
+				fn(cur, groups[cur])
+				delete(groups, cur)
+				done[cur] = true
+			}
+			cur = cfg
+		}
+		if _, ok := groups[cfg]; !ok && policy == GroupsAtEnd {
+			order = append(order, cfg)
+		}
+		groups[cfg] = append(groups[cfg], res.Clone())
+	}
+	if err := src.Err(); err != nil {
+		return err
+	}
+
+	if policy == GroupsContiguous {
+		if !cur.IsZero() {
+			fn(cur, groups[cur])
+		}
+		return nil
+	}
+	for _, cfg := range order {
+		fn(cfg, groups[cfg])
+	}
+	return nil
+}
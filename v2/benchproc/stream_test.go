@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestStreamGroups(t *testing.T) {
+	run := func(input string, policy GroupPolicy) (string, error) {
+		var p ProjectionParser
+		s, err := p.Parse(".name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		err = StreamGroups(benchfmt.NewReader(strings.NewReader(input), "test"), s, policy, func(cfg Config, results []*benchfmt.Result) {
+			var vals []string
+			for _, res := range results {
+				vals = append(vals, fmt.Sprint(res.Values[0].Value))
+			}
+			got = append(got, cfg.String()+"="+strings.Join(vals, ","))
+		})
+		return strings.Join(got, " "), err
+	}
+
+	const sorted = `BenchmarkA 1 1 ns/op
+BenchmarkA 1 2 ns/op
+BenchmarkBad 1
+BenchmarkB 1 3 ns/op
+BenchmarkC 1 4 ns/op
+BenchmarkC 1 5 ns/op
+`
+	const unsorted = `BenchmarkA 1 1 ns/op
+BenchmarkB 1 3 ns/op
+BenchmarkA 1 2 ns/op
+`
+	check := func(input string, policy GroupPolicy, want string) {
+		t.Helper()
+		got, err := run(input, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	}
+	// Each group is passed once with all of its members. The
+	// malformed result is skipped.
+	check(sorted, GroupsAtEnd, ".name:A=1,2 .name:B=3 .name:C=4,5")
+	check(sorted, GroupsContiguous, ".name:A=1,2 .name:B=3 .name:C=4,5")
+	check(unsorted, GroupsAtEnd, ".name:A=1,2 .name:B=3")
+	check("", GroupsContiguous, "")
+
+	// Unsorted input violates GroupsContiguous.
+	got, err := run(unsorted, GroupsContiguous)
+	if err == nil || !strings.Contains(err.Error(), "not contiguous") {
+		t.Errorf("want contiguity error, got %v", err)
+	}
+	// Groups completed before the violation were already passed
+	// on.
+	if got != ".name:A=1" {
+		t.Errorf("want groups before error, got %s", got)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import "golang.org/x/perf/v2/benchfmt"
+
+// A Transform modifies a benchfmt.Result in place. Transforms are
+// typically applied to each Result as it is read, before the Result
+// is filtered or projected.
+type Transform func(res *benchfmt.Result)
+
+// ChainTransforms returns a Transform that applies each of ts in
+// order. Later Transforms see the changes made by earlier ones; for
+// example, a value added by AddValue can be used to derive another
+// value.
+func ChainTransforms(ts ...Transform) Transform {
+	return func(res *benchfmt.Result) {
+		for _, t := range ts {
+			t(res)
+		}
+	}
+}
+
+// AddValue returns a Transform that derives a new value with the
+// given unit from existing values of a Result. The Transform calls f
+// on each Result. If f returns false, for example because a value it
+// depends on is missing, the Result is left alone. Otherwise, the
+// Transform appends the derived value to the Result's Values, or
+// replaces the Result's existing value with that unit, if any.
+//
+// For example, this derives a "ns/alloc" value:
+//
+//	AddValue("ns/alloc", func(res *benchfmt.Result) (float64, bool) {
+//		ns, ok1 := res.Value("ns/op")
+//		allocs, ok2 := res.Value("allocs/op")
+//		return ns / allocs, ok1 && ok2 && allocs != 0
+//	})
+func AddValue(unit string, f func(res *benchfmt.Result) (float64, bool)) Transform {
+	return func(res *benchfmt.Result) {
+		val, ok := f(res)
+		if !ok {
+			return
+		}
+		for i := range res.Values {
+			if res.Values[i].Unit == unit {
+				// Replace the whole Value so a stale
+				// Err doesn't carry over.
+				res.Values[i] = benchfmt.Value{Value: val, Unit: unit}
+				return
+			}
+		}
+		res.Values = append(res.Values, benchfmt.Value{Value: val, Unit: unit})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestAddValue(t *testing.T) {
+	nsPerAlloc := AddValue("ns/alloc", func(res *benchfmt.Result) (float64, bool) {
+		ns, ok1 := res.Value("ns/op")
+		allocs, ok2 := res.Value("allocs/op")
+		return ns / allocs, ok1 && ok2 && allocs != 0
+	})
+	// A value derived from a derived value.
+	allocsPerSec := AddValue("allocs/sec", func(res *benchfmt.Result) (float64, bool) {
+		ns, ok := res.Value("ns/alloc")
+		return 1e9 / ns, ok
+	})
+	xform := ChainTransforms(nsPerAlloc, allocsPerSec)
+
+	check := func(vals []benchfmt.Value, want string) {
+		t.Helper()
+		res := &benchfmt.Result{FullName: []byte("Name"), Iters: 1, Values: vals}
+		xform(res)
+		if got := fmt.Sprint(res.Values); got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	}
+	check([]benchfmt.Value{{Value: 100, Unit: "ns/op"}, {Value: 4, Unit: "allocs/op"}},
+		"[{100 ns/op 0} {4 allocs/op 0} {25 ns/alloc 0} {4e+07 allocs/sec 0}]")
+	// Missing dependency.
+	check([]benchfmt.Value{{Value: 100, Unit: "ns/op"}},
+		"[{100 ns/op 0}]")
+	check([]benchfmt.Value{{Value: 100, Unit: "ns/op"}, {Value: 0, Unit: "allocs/op"}},
+		"[{100 ns/op 0} {0 allocs/op 0}]")
+	// An existing value is replaced.
+	check([]benchfmt.Value{{Value: 100, Unit: "ns/op"}, {Value: 1, Unit: "ns/alloc"}, {Value: 2, Unit: "allocs/op"}},
+		"[{100 ns/op 0} {50 ns/alloc 0} {2 allocs/op 0} {2e+07 allocs/sec 0}]")
+	// Replacing a value drops its old uncertainty.
+	check([]benchfmt.Value{{Value: 100, Unit: "ns/op"}, {Value: 1, Unit: "ns/alloc", Err: 0.5}, {Value: 2, Unit: "allocs/op"}},
+		"[{100 ns/op 0} {50 ns/alloc 0} {2 allocs/op 0} {2e+07 allocs/sec 0}]")
+}
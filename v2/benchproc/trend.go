@@ -0,0 +1,135 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"sort"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// Trend computes a smoothed trend of a unit across an ordered axis,
+// such as commit date, separately for each group of results. For
+// example, with a group projection of ".fullname" and an order
+// projection of "commit-date@alpha", it computes the trend of each
+// benchmark over time.
+//
+// Trend buffers all of its results, so the input does not need to be
+// sorted. Results computes the trend by sorting each group by the
+// order projection, breaking ties in input order, and adds the trend
+// to each result as a value with unit TrendUnit(unit).
+type Trend struct {
+	group, order *Schema
+	unit         string
+
+	// step returns the next smoothed value given the previous
+	// values in the group, most recent last, and the current
+	// value.
+	step func(prev []float64, smoothed float64, val float64) float64
+
+	entries []trendEntry
+}
+
+type trendEntry struct {
+	res   *benchfmt.Result
+	group Config
+	order Config
+	val   float64
+}
+
+// TrendUnit returns the unit of the trend values Trend computes for
+// unit.
+func TrendUnit(unit string) string {
+	return unit + "-trend"
+}
+
+// NewTrendEWMA returns a Trend that computes the exponentially
+// weighted moving average of unit with smoothing factor alpha, which
+// must be in (0, 1]. The first value in each group is its own
+// average, and each later average is alpha*value + (1-alpha)*previous
+// average. Larger alpha discounts older values more quickly.
+func NewTrendEWMA(group, order *Schema, unit string, alpha float64) *Trend {
+	step := func(prev []float64, smoothed, val float64) float64 {
+		if len(prev) == 0 {
+			return val
+		}
+		return alpha*val + (1-alpha)*smoothed
+	}
+	return &Trend{group: group, order: order, unit: unit, step: step}
+}
+
+// NewTrendMovingAverage returns a Trend that computes the mean of
+// each value of unit and up to k-1 values before it in its group. If
+// k is less than 1, it is treated as 1.
+func NewTrendMovingAverage(group, order *Schema, unit string, k int) *Trend {
+	if k < 1 {
+		k = 1
+	}
+	step := func(prev []float64, smoothed, val float64) float64 {
+		if len(prev) > k-1 {
+			prev = prev[len(prev)-(k-1):]
+		}
+		sum := val
+		for _, v := range prev {
+			sum += v
+		}
+		return sum / float64(len(prev)+1)
+	}
+	return &Trend{group: group, order: order, unit: unit, step: step}
+}
+
+// Add adds res to t. Results that are filtered by the group or order
+// projection or that have no value for t's unit are dropped. Add
+// retains a copy of res, so the caller may reuse res.
+func (t *Trend) Add(res *benchfmt.Result) {
+	val, ok := res.Value(t.unit)
+	if !ok {
+		return
+	}
+	group, ok := t.group.Project(res)
+	if !ok {
+		return
+	}
+	order, ok := t.order.Project(res)
+	if !ok {
+		return
+	}
+	t.entries = append(t.entries, trendEntry{res.Clone(), group, order, val})
+}
+
+// Results returns the results added to t, in the order they were
+// added, each with an additional trend value.
+func (t *Trend) Results() []*benchfmt.Result {
+	// Split into groups, keeping input order within each group.
+	groups := make(map[Config][]int)
+	var groupOrder []Config
+	for i, e := range t.entries {
+		if _, ok := groups[e.group]; !ok {
+			groupOrder = append(groupOrder, e.group)
+		}
+		groups[e.group] = append(groups[e.group], i)
+	}
+
+	out := make([]*benchfmt.Result, len(t.entries))
+	unit := TrendUnit(t.unit)
+	for _, g := range groupOrder {
+		idxs := groups[g]
+		sort.SliceStable(idxs, func(i, j int) bool {
+			return t.entries[idxs[i]].order.Less(t.entries[idxs[j]].order)
+		})
+		var prev []float64
+		var smoothed float64
+		for _, i := range idxs {
+			e := t.entries[i]
+			smoothed = t.step(prev, smoothed, e.val)
+			prev = append(prev, e.val)
+
+			res := e.res.Clone()
+			res.Values = append(res.Values, benchfmt.Value{Value: smoothed, Unit: unit})
+			out[i] = res
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestTrend(t *testing.T) {
+	type in struct {
+		name, date string
+		val        float64
+	}
+	// Deliberately out of order.
+	inputs := []in{
+		{"A", "2020-01-03", 40},
+		{"B", "2020-01-01", 5},
+		{"A", "2020-01-01", 10},
+		{"A", "2020-01-02", 20},
+		{"B", "2020-01-02", 15},
+		{"A", "2020-01-04", 30},
+	}
+	run := func(newTrend func(group, order *Schema) *Trend) string {
+		t.Helper()
+		var p ProjectionParser
+		group, err := p.Parse(".name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := p.Parse("date@alpha")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := newTrend(group, order)
+		for _, in := range inputs {
+			res := &benchfmt.Result{FullName: []byte(in.name), Values: []benchfmt.Value{{Value: in.val, Unit: "ns/op"}}}
+			res.SetFileConfig("date", in.date)
+			tr.Add(res)
+		}
+		// No ns/op, so this should be dropped.
+		tr.Add(&benchfmt.Result{FullName: []byte("A"), Values: []benchfmt.Value{{Value: 1, Unit: "B/op"}}})
+
+		var out []string
+		for _, res := range tr.Results() {
+			val, ok := res.Value(TrendUnit("ns/op"))
+			if !ok {
+				t.Fatalf("result %s missing trend value", res.FullName)
+			}
+			// Round to avoid floating point noise.
+			out = append(out, fmt.Sprintf("%s=%g", res.FullName, math.Round(val*1000)/1000))
+		}
+		return strings.Join(out, " ")
+	}
+
+	// Hand-computed with alpha=0.5 in date order:
+	//   A: 10 -> 10, 20 -> 15, 40 -> 27.5, 30 -> 28.75
+	//   B: 5 -> 5, 15 -> 10
+	got := run(func(group, order *Schema) *Trend {
+		return NewTrendEWMA(group, order, "ns/op", 0.5)
+	})
+	if want := "A=27.5 B=5 A=10 A=15 B=10 A=28.75"; got != want {
+		t.Errorf("EWMA: got %s, want %s", got, want)
+	}
+
+	// Moving average of the last 2 values:
+	//   A: 10 -> 10, 20 -> 15, 40 -> 30, 30 -> 35
+	//   B: 5 -> 5, 15 -> 10
+	got = run(func(group, order *Schema) *Trend {
+		return NewTrendMovingAverage(group, order, "ns/op", 2)
+	})
+	if want := "A=30 B=5 A=10 A=15 B=10 A=35"; got != want {
+		t.Errorf("moving average: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// Aggregated results can be written in the benchmark format so they
+// can be reloaded later without the original measurements. By
+// convention, each Value of an aggregated Result holds the center of
+// a Distribution and, in Value.Err, the half-width of its confidence
+// interval, and the number of measurements summarized is recorded in
+// the SampleCountKey file configuration key. Distribution.EncodeValue
+// and SetSampleCount encode a Result this way, a benchfmt.Writer with
+// SetUncertainty enabled writes it, and a benchfmt.Reader with
+// Uncertainty set and SampleCount decode it.
+
+// SampleCountKey is the file configuration key that records the
+// number of measurements summarized by an aggregated Result.
+const SampleCountKey = "samples"
+
+// MedianCI returns a distribution-free confidence interval for the
+// median of d at the given confidence level, such as 0.95. The bounds
+// are values of d chosen using the binomial distribution of order
+// statistics, so the interval covers the true median with at least
+// the requested confidence. If d has too few values to achieve
+// confidence, MedianCI returns NaN, NaN. For example, this requires
+// at least 6 values for a confidence level of 0.95.
+func (d *Distribution) MedianCI(confidence float64) (lo, hi float64) {
+	xs := d.Values
+	if !sort.Float64sAreSorted(xs) {
+		xs = append([]float64(nil), xs...)
+		sort.Float64s(xs)
+	}
+	n := len(xs)
+
+	// The interval [xs[k], xs[n-1-k]] covers the median with
+	// probability 1 - 2 P(B <= k), where B ~ Binomial(n, 1/2).
+	// Find the largest k that achieves confidence.
+	alpha := (1 - confidence) / 2
+	k, cdf := -1, 0.0
+	for i := 0; i < n/2; i++ {
+		cdf += binomHalfPMF(n, i)
+		if cdf > alpha {
+			break
+		}
+		k = i
+	}
+	if k < 0 {
+		return math.NaN(), math.NaN()
+	}
+	return xs[k], xs[n-1-k]
+}
+
+// binomHalfPMF returns P(B = k) where B ~ Binomial(n, 1/2).
+func binomHalfPMF(n, k int) float64 {
+	lgamma := func(x int) float64 {
+		v, _ := math.Lgamma(float64(x))
+		return v
+	}
+	return math.Exp(lgamma(n+1) - lgamma(k+1) - lgamma(n-k+1) - float64(n)*math.Ln2)
+}
+
+// EncodeValue returns a benchfmt.Value with the given unit that
+// summarizes d. Its Value is d.Center. If d's Center is the median,
+// its Err is the half-width of d's MedianCI at the given confidence
+// level. Since Err is symmetric, it is the larger of the distances
+// from Center to the bounds of the interval, so Value ± Err covers
+// the whole interval. If the interval is undefined, or Center is
+// another reduction, which MedianCI doesn't bound, Err is 0.
+func (d *Distribution) EncodeValue(unit string, confidence float64) benchfmt.Value {
+	val := benchfmt.Value{Value: d.Center, Unit: unit}
+	if d.reduction != ReduceMedian {
+		return val
+	}
+	lo, hi := d.MedianCI(confidence)
+	if !math.IsNaN(lo) {
+		val.Err = math.Max(d.Center-lo, hi-d.Center)
+	}
+	return val
+}
+
+// SetSampleCount records in res that it summarizes n measurements.
+func SetSampleCount(res *benchfmt.Result, n int) {
+	res.SetFileConfig(SampleCountKey, strconv.Itoa(n))
+}
+
+// SampleCount returns the number of measurements summarized by res,
+// as recorded by SetSampleCount. It returns 0, false if res doesn't
+// record a valid sample count.
+func SampleCount(res *benchfmt.Result) (int, bool) {
+	n, err := strconv.Atoi(res.GetFileConfig(SampleCountKey))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
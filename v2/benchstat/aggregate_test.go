@@ -0,0 +1,97 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestMedianCI(t *testing.T) {
+	check := func(xs []float64, confidence, wantLo, wantHi float64) {
+		t.Helper()
+		d := NewDistribution(xs, DistributionOptions{})
+		lo, hi := d.MedianCI(confidence)
+		if math.IsNaN(wantLo) {
+			if !math.IsNaN(lo) || !math.IsNaN(hi) {
+				t.Errorf("%v at %v: want NaN, got [%v, %v]", xs, confidence, lo, hi)
+			}
+			return
+		}
+		if lo != wantLo || hi != wantHi {
+			t.Errorf("%v at %v: want [%v, %v], got [%v, %v]", xs, confidence, wantLo, wantHi, lo, hi)
+		}
+	}
+	// With 5 values, even the full range only has 93.75%
+	// confidence.
+	check([]float64{1, 2, 3, 4, 5}, 0.95, math.NaN(), math.NaN())
+	check([]float64{1, 2, 3, 4, 5}, 0.9, 1, 5)
+	check([]float64{6, 1, 5, 2, 4, 3}, 0.95, 1, 6)
+	// With 10 values, P(B <= 1) = 11/1024, which is within the
+	// 2.5% tail, but P(B <= 2) = 56/1024 is not.
+	check([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0.95, 2, 9)
+	check(nil, 0.95, math.NaN(), math.NaN())
+}
+
+func TestAggregateRoundTrip(t *testing.T) {
+	vals := []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 30}
+	d := NewDistribution(vals, DistributionOptions{})
+	few := NewDistribution([]float64{1, 2}, DistributionOptions{})
+
+	res := &benchfmt.Result{FullName: []byte("Agg"), Iters: 1}
+	res.Values = []benchfmt.Value{d.EncodeValue("sec/op", 0.95), few.EncodeValue("B/op", 0.95)}
+	SetSampleCount(res, len(vals))
+
+	// The center is the median and the interval is [11, 18], so
+	// the half-width covers the wider side.
+	if v := res.Values[0]; v.Value != 14.5 || v.Err != 3.5 {
+		t.Errorf("want 14.5 ±3.5, got %v ±%v", v.Value, v.Err)
+	}
+	// There's no interval for 2 values.
+	if v := res.Values[1]; v.Value != 1.5 || v.Err != 0 {
+		t.Errorf("want 1.5 ±0, got %v ±%v", v.Value, v.Err)
+	}
+
+	// The median's interval doesn't apply to other centers.
+	mean := NewDistribution(vals, DistributionOptions{Center: ReduceMean})
+	if v := mean.EncodeValue("sec/op", 0.95); v.Value != 15.6 || v.Err != 0 {
+		t.Errorf("mean: want 15.6 ±0, got %v ±%v", v.Value, v.Err)
+	}
+
+	var buf bytes.Buffer
+	w := benchfmt.NewWriter(&buf)
+	w.SetUncertainty(true)
+	if err := w.Write(res); err != nil {
+		t.Fatal(err)
+	}
+	const want = "samples: 10\n\nBenchmarkAgg 1 14.5 ±3.5 sec/op 1.5 B/op\n"
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	r := benchfmt.NewReader(&buf, "test")
+	r.Uncertainty = true
+	if !r.Scan() {
+		t.Fatal("no result read")
+	}
+	got, err := r.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(res) {
+		t.Errorf("want %+v, got %+v", res, got)
+	}
+	if n, ok := SampleCount(got); !ok || n != len(vals) {
+		t.Errorf("want sample count %d, got %d, %v", len(vals), n, ok)
+	}
+
+	// Results without a sample count don't have one.
+	if _, ok := SampleCount(&benchfmt.Result{}); ok {
+		t.Errorf("want no sample count")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// CompareColumns compares every column of cells to a reference
+// column. cells is indexed by row and then column, and cols gives the
+// Config of each column. ref is the Config of the reference column.
+//
+// It returns the Configs of the other columns, in their original
+// order, and a table of Comparisons with one row for each row of
+// cells and one column for each of the other columns. Each Comparison
+// compares the cell in the reference column to the cell in the other
+// column, so its Ratio is the other cell's Center divided by the
+// reference cell's Center. A Comparison is nil if either cell is
+// missing (nil).
+func CompareColumns(cells [][]*Distribution, cols []benchproc.Config, ref benchproc.Config) (otherCols []benchproc.Config, cmps [][]*Comparison, err error) {
+	refIdx := -1
+	for i, col := range cols {
+		if col == ref {
+			refIdx = i
+		} else {
+			otherCols = append(otherCols, col)
+		}
+	}
+	if refIdx < 0 {
+		return nil, nil, fmt.Errorf("reference column %s not found", ref)
+	}
+
+	cmps = make([][]*Comparison, len(cells))
+	for r, row := range cells {
+		cmps[r] = make([]*Comparison, 0, len(otherCols))
+		var base *Distribution
+		if refIdx < len(row) {
+			base = row[refIdx]
+		}
+		for c := range cols {
+			if c == refIdx {
+				continue
+			}
+			var cmp *Comparison
+			if c < len(row) && row[c] != nil && base != nil {
+				x := base.Compare(row[c])
+				cmp = &x
+			}
+			cmps[r] = append(cmps[r], cmp)
+		}
+	}
+	return otherCols, cmps, nil
+}
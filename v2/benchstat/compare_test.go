@@ -0,0 +1,85 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+func TestCompareColumns(t *testing.T) {
+	var p benchproc.ProjectionParser
+	s, err := p.Parse("col")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cols []benchproc.Config
+	for _, name := range []string{"old", "new", "newer"} {
+		res := &benchfmt.Result{}
+		res.SetFileConfig("col", name)
+		cfg, _ := s.Project(res)
+		cols = append(cols, cfg)
+	}
+
+	d := func(xs ...float64) *Distribution {
+		return NewDistribution(xs, DistributionOptions{})
+	}
+	cells := [][]*Distribution{
+		{d(10, 11, 12, 13, 14), d(20, 21, 22, 23, 24), d(5, 6, 7, 8, 9)},
+		{d(100, 101, 102), d(100, 101, 102), nil},
+	}
+
+	// Compare against the middle column.
+	otherCols, cmps, err := CompareColumns(cells, cols, cols[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(otherCols) != 2 || otherCols[0] != cols[0] || otherCols[1] != cols[2] {
+		t.Fatalf("want other columns old, newer, got %v", otherCols)
+	}
+	if len(cmps) != 2 || len(cmps[0]) != 2 || len(cmps[1]) != 2 {
+		t.Fatalf("want 2x2 comparisons, got %v", cmps)
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	}
+	check := func(row, col int, wantRatio float64, wantSig bool) {
+		t.Helper()
+		c := cmps[row][col]
+		if !near(c.Ratio, wantRatio) || !near(c.Delta, wantRatio-1) {
+			t.Errorf("%d,%d: want ratio %v, got %v (delta %v)", row, col, wantRatio, c.Ratio, c.Delta)
+		}
+		if c.Significant(0.05) != wantSig {
+			t.Errorf("%d,%d: want significant %v, got p=%v", row, col, wantSig, c.P)
+		}
+	}
+	// Medians are 12, 22, 7 and 101, 101.
+	check(0, 0, 12.0/22, true)
+	check(0, 1, 7.0/22, true)
+	check(1, 0, 1, false)
+	if cmps[1][1] != nil {
+		t.Errorf("want nil comparison for missing cell, got %+v", cmps[1][1])
+	}
+	// Completely separated samples of 5 have an exact p-value of
+	// 2/C(10,5).
+	if want := 2.0 / 252; !near(cmps[0][0].P, want) {
+		t.Errorf("want p=%v, got %v", want, cmps[0][0].P)
+	}
+	if c := cmps[0][0]; c.N1 != 5 || c.N2 != 5 {
+		t.Errorf("want N1=N2=5, got %d, %d", c.N1, c.N2)
+	}
+
+	// The reference column must be one of the columns.
+	res := &benchfmt.Result{}
+	res.SetFileConfig("col", "other")
+	other, _ := s.Project(res)
+	if _, _, err := CompareColumns(cells, cols, other); err == nil {
+		t.Errorf("want error for unknown reference column")
+	}
+}
@@ -4,33 +4,165 @@
 
 package benchstat
 
-import "github.com/aclements/go-moremath/stats"
+import (
+	"fmt"
+	"math"
+
+	"github.com/aclements/go-moremath/stats"
+)
 
 type Distribution struct {
 	Values []float64
 	Center float64
+
+	// reduction is the Reduction that computed Center.
+	reduction Reduction
+}
+
+type DistributionOptions struct {
+	// DiscardFirst and DiscardLast are the number of values to
+	// discard from the beginning and end of the values passed to
+	// NewDistribution, in their original order. This is useful
+	// for dropping warmup measurements. If these discard all of
+	// the values, the Distribution is empty and its Center is NaN.
+	DiscardFirst, DiscardLast int
+
+	// Center is the reduction used to compute the Distribution's
+	// Center. The default is the median.
+	Center Reduction
+}
+
+// A Reduction reduces the values of a Distribution to a single value.
+type Reduction int
+
+const (
+	// ReduceMedian reduces values to their median. This is
+	// robust to outliers, so it's usually the right choice for
+	// benchmark results.
+	ReduceMedian Reduction = iota
+	// ReduceMean reduces values to their arithmetic mean.
+	ReduceMean
+	// ReduceGeomean reduces values to their geometric mean.
+	ReduceGeomean
+	// ReduceSum reduces values to their sum. This is useful for
+	// metrics that accumulate across runs, such as total
+	// allocations.
+	ReduceSum
+	// ReduceMin reduces values to their minimum.
+	ReduceMin
+	// ReduceMax reduces values to their maximum. This is useful
+	// for metrics such as peak memory.
+	ReduceMax
+)
+
+var reductionNames = []string{"median", "mean", "geomean", "sum", "min", "max"}
+
+func (r Reduction) String() string {
+	if 0 <= int(r) && int(r) < len(reductionNames) {
+		return reductionNames[r]
+	}
+	return fmt.Sprintf("Reduction(%d)", int(r))
+}
+
+// ParseReduction parses the name of a Reduction, which is one of
+// "median", "mean", "geomean", "sum", "min", or "max".
+func ParseReduction(name string) (Reduction, error) {
+	for i, n := range reductionNames {
+		if n == name {
+			return Reduction(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown reduction %q", name)
 }
 
-type DistributionOptions struct{}
+// reduce applies r to samp, which must be sorted. It returns NaN if
+// samp is empty.
+func (r Reduction) reduce(samp *stats.Sample) float64 {
+	xs := samp.Xs
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	switch r {
+	case ReduceMedian:
+		return samp.Quantile(0.5)
+	case ReduceMean:
+		return stats.Mean(xs)
+	case ReduceGeomean:
+		return stats.GeoMean(xs)
+	case ReduceSum:
+		var sum float64
+		for _, x := range xs {
+			sum += x
+		}
+		return sum
+	case ReduceMin:
+		return xs[0]
+	case ReduceMax:
+		return xs[len(xs)-1]
+	}
+	panic(fmt.Sprintf("bad Reduction %v", r))
+}
 
 func NewDistribution(values []float64, opts DistributionOptions) *Distribution {
+	if opts.DiscardFirst >= len(values) {
+		values = nil
+	} else if opts.DiscardFirst > 0 {
+		values = values[opts.DiscardFirst:]
+	}
+	if opts.DiscardLast >= len(values) {
+		values = nil
+	} else if opts.DiscardLast > 0 {
+		values = values[:len(values)-opts.DiscardLast]
+	}
+
 	samp := stats.Sample{Xs: values}
 	// Speed up order statistics.
 	samp.Sort()
 	return &Distribution{
-		Values: samp.Xs,
-		Center: samp.Quantile(0.5),
+		Values:    samp.Xs,
+		Center:    opts.Center.reduce(&samp),
+		reduction: opts.Center,
 	}
 }
 
+// Reduction returns the Reduction that computed d's Center.
+func (d *Distribution) Reduction() Reduction {
+	return d.reduction
+}
+
+// A Comparison is the result of comparing two Distributions.
 type Comparison struct {
+	// P is the p-value of a two-sided Mann-Whitney U-test of
+	// whether the two Distributions differ. It is NaN if either
+	// Distribution has no values.
 	P float64
 
-	Delta float64
+	// Ratio is the ratio of the Center of the second Distribution
+	// to the Center of the first, and Delta is the relative change,
+	// Ratio - 1.
+	Ratio, Delta float64
 
+	// N1 and N2 are the number of values in each Distribution.
 	N1, N2 int
 }
 
+// Significant reports whether c is statistically significant at
+// significance level alpha, such as 0.05.
+func (c Comparison) Significant(alpha float64) bool {
+	return c.P < alpha
+}
+
+// Compare compares d to d2, where d is the baseline.
 func (d *Distribution) Compare(d2 *Distribution) Comparison {
-	return Comparison{}
+	c := Comparison{
+		P:     math.NaN(),
+		Ratio: d2.Center / d.Center,
+		N1:    len(d.Values),
+		N2:    len(d2.Values),
+	}
+	c.Delta = c.Ratio - 1
+	if res, err := stats.MannWhitneyUTest(d.Values, d2.Values, stats.LocationDiffers); err == nil {
+		c.P = res.P
+	}
+	return c
 }
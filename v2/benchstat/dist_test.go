@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistributionDiscard(t *testing.T) {
+	check := func(opts DistributionOptions, wantN int, wantCenter float64) {
+		t.Helper()
+		values := []float64{100, 1, 2, 3, 50}
+		d := NewDistribution(values, opts)
+		if len(d.Values) != wantN {
+			t.Errorf("%+v: want %d values, got %v", opts, wantN, d.Values)
+		}
+		if math.IsNaN(wantCenter) {
+			if !math.IsNaN(d.Center) {
+				t.Errorf("%+v: want NaN center, got %v", opts, d.Center)
+			}
+		} else if d.Center != wantCenter {
+			t.Errorf("%+v: want center %v, got %v", opts, wantCenter, d.Center)
+		}
+	}
+
+	check(DistributionOptions{}, 5, 3)
+	// Discarding the warmup value moves the center down.
+	check(DistributionOptions{DiscardFirst: 1}, 4, 2.5)
+	check(DistributionOptions{DiscardFirst: 1, DiscardLast: 1}, 3, 2)
+	check(DistributionOptions{DiscardLast: 2}, 3, 2)
+
+	// Discarding everything yields an empty distribution.
+	check(DistributionOptions{DiscardFirst: 5}, 0, math.NaN())
+	check(DistributionOptions{DiscardFirst: 10}, 0, math.NaN())
+	check(DistributionOptions{DiscardLast: 10}, 0, math.NaN())
+	check(DistributionOptions{DiscardFirst: 3, DiscardLast: 3}, 0, math.NaN())
+}
+
+func TestDistributionReduce(t *testing.T) {
+	check := func(r Reduction, want float64) {
+		t.Helper()
+		d := NewDistribution([]float64{4, 1, 2, 1, 8}, DistributionOptions{Center: r})
+		if d.Center != want {
+			t.Errorf("%v: want %v, got %v", r, want, d.Center)
+		}
+	}
+	check(ReduceMedian, 2)
+	check(ReduceMean, 16.0/5)
+	check(ReduceSum, 16)
+	check(ReduceMin, 1)
+	check(ReduceMax, 8)
+	if d := NewDistribution([]float64{2, 8}, DistributionOptions{Center: ReduceGeomean}); math.Abs(d.Center-4) > 1e-9 {
+		t.Errorf("geomean: want 4, got %v", d.Center)
+	}
+
+	// Reductions apply after discarding values.
+	d := NewDistribution([]float64{100, 1, 2, 3}, DistributionOptions{DiscardFirst: 1, Center: ReduceSum})
+	if d.Center != 6 {
+		t.Errorf("sum after discard: want 6, got %v", d.Center)
+	}
+	d = NewDistribution([]float64{100, 1, 2, 3}, DistributionOptions{DiscardFirst: 1, Center: ReduceMax})
+	if d.Center != 3 {
+		t.Errorf("max after discard: want 3, got %v", d.Center)
+	}
+	// Every reduction of nothing is NaN.
+	for _, r := range []Reduction{ReduceMedian, ReduceSum, ReduceMax} {
+		if d := NewDistribution(nil, DistributionOptions{Center: r}); !math.IsNaN(d.Center) {
+			t.Errorf("%v of nothing: want NaN, got %v", r, d.Center)
+		}
+	}
+}
+
+func TestParseReduction(t *testing.T) {
+	for _, r := range []Reduction{ReduceMedian, ReduceMean, ReduceGeomean, ReduceSum, ReduceMin, ReduceMax} {
+		got, err := ParseReduction(r.String())
+		if err != nil || got != r {
+			t.Errorf("%v: got %v, %v", r, got, err)
+		}
+	}
+	if _, err := ParseReduction("mode"); err == nil {
+		t.Errorf("want error for unknown reduction")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// A SpeedupTable compares an old and a new set of measurements of a
+// single unit, with one row per benchmark and a geomean summary row.
+// This is the structure of the classic benchstat old/new/delta
+// table.
+type SpeedupTable struct {
+	// Unit is the unit of the measurements in the table.
+	Unit string
+
+	// OldLabel and NewLabel label the old and new columns. These
+	// are only used for presentation.
+	OldLabel, NewLabel string
+
+	// Rows are the rows of the table, in the order of their
+	// Configs.
+	Rows []SpeedupRow
+
+	// Geomean summarizes the rows that have both old and new
+	// measurements. It is nil if there are no such rows. Its Old
+	// and New are nil and its Comparison's P is NaN, since the
+	// summary isn't a sample. Its Comparison's N1 and N2 are the
+	// number of rows summarized.
+	Geomean *SpeedupRow
+
+	// Skipped is the number of rows omitted from Geomean because
+	// they were missing old or new measurements.
+	Skipped int
+}
+
+// A SpeedupRow is one row of a SpeedupTable.
+type SpeedupRow struct {
+	// Config is the configuration of this row. It is the zero
+	// Config for the geomean row.
+	Config benchproc.Config
+
+	// Old and New are the distributions of the old and new
+	// measurements. Either may be nil if the benchmark is missing
+	// from that side.
+	Old, New *Distribution
+
+	// OldCenter and NewCenter are the centers of Old and New, or
+	// the geometric means of the centers in the geomean row. They
+	// are NaN if the corresponding Distribution is missing.
+	OldCenter, NewCenter float64
+
+	// Comparison compares Old to New. It is nil if either is
+	// missing.
+	Comparison *Comparison
+}
+
+// NewSpeedupTable returns a SpeedupTable for the given rows. old[i]
+// and new[i] are the distributions of rows[i] and may be nil if that
+// row is missing old or new measurements. The table's labels default
+// to "old" and "new".
+func NewSpeedupTable(unit string, rows []benchproc.Config, old, new []*Distribution) *SpeedupTable {
+	t := &SpeedupTable{Unit: unit, OldLabel: "old", NewLabel: "new"}
+	cells := make([][]*Distribution, len(rows))
+	for i, cfg := range rows {
+		row := SpeedupRow{Config: cfg, Old: old[i], New: new[i], OldCenter: math.NaN(), NewCenter: math.NaN()}
+		if row.Old != nil {
+			row.OldCenter = row.Old.Center
+		}
+		if row.New != nil {
+			row.NewCenter = row.New.Center
+		}
+		if row.Old != nil && row.New != nil {
+			c := row.Old.Compare(row.New)
+			row.Comparison = &c
+		}
+		t.Rows = append(t.Rows, row)
+		cells[i] = []*Distribution{row.Old, row.New}
+	}
+
+	// Summarize the complete rows.
+	var cols []float64
+	cols, _, t.Skipped = SummarizeColumns(cells, SummaryGeomean)
+	if n := len(rows) - t.Skipped; n > 0 {
+		ratio := cols[1] / cols[0]
+		t.Geomean = &SpeedupRow{
+			OldCenter: cols[0],
+			NewCenter: cols[1],
+			Comparison: &Comparison{
+				P:     math.NaN(),
+				Ratio: ratio,
+				Delta: ratio - 1,
+				N1:    n,
+				N2:    n,
+			},
+		}
+	}
+	return t
+}
+
+// CompareResults returns a SpeedupTable comparing the values of unit
+// in old and new. Results are grouped into rows by rowBy, and the
+// values of each row become a Distribution constructed with opts.
+// Results that rowBy filters out or that don't have a value with unit
+// are ignored. The rows are sorted by their Configs.
+func CompareResults(rowBy *benchproc.Schema, unit string, old, new []*benchfmt.Result, opts DistributionOptions) *SpeedupTable {
+	var rows []benchproc.Config
+	vals := make(map[benchproc.Config]*[2][]float64)
+	for side, results := range [][]*benchfmt.Result{old, new} {
+		for _, res := range results {
+			val, ok := res.Value(unit)
+			if !ok {
+				continue
+			}
+			cfg, ok := rowBy.Project(res)
+			if !ok {
+				continue
+			}
+			v := vals[cfg]
+			if v == nil {
+				v = &[2][]float64{}
+				vals[cfg] = v
+				rows = append(rows, cfg)
+			}
+			v[side] = append(v[side], val)
+		}
+	}
+	benchproc.SortConfigs(rows)
+
+	oldDists := make([]*Distribution, len(rows))
+	newDists := make([]*Distribution, len(rows))
+	for i, cfg := range rows {
+		v := vals[cfg]
+		if len(v[0]) > 0 {
+			oldDists[i] = NewDistribution(v[0], opts)
+		}
+		if len(v[1]) > 0 {
+			newDists[i] = NewDistribution(v[1], opts)
+		}
+	}
+	return NewSpeedupTable(unit, rows, oldDists, newDists)
+}
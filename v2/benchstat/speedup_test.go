@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+func TestCompareResults(t *testing.T) {
+	results := func(name string, vals ...float64) []*benchfmt.Result {
+		var out []*benchfmt.Result
+		for _, v := range vals {
+			out = append(out, &benchfmt.Result{FullName: []byte(name), Values: []benchfmt.Value{{Value: v, Unit: "sec/op"}, {Value: 1, Unit: "B/op"}}})
+		}
+		return out
+	}
+	var old, new []*benchfmt.Result
+	old = append(old, results("B", 20, 21, 22, 23, 24)...)
+	old = append(old, results("A", 10, 11, 12, 13, 14)...)
+	old = append(old, results("C", 7)...)
+	new = append(new, results("A", 5, 6, 7, 8, 9)...)
+	new = append(new, results("B", 30, 30, 30)...)
+	new = append(new, results("D", 3)...)
+
+	var p benchproc.ProjectionParser
+	rowBy, err := p.Parse(".name@alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab := CompareResults(rowBy, "sec/op", old, new, DistributionOptions{})
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9*math.Max(math.Abs(a), math.Abs(b))
+	}
+	if len(tab.Rows) != 4 {
+		t.Fatalf("want 4 rows, got %d", len(tab.Rows))
+	}
+	check := func(i int, name string, wantOld, wantNew float64) {
+		t.Helper()
+		row := tab.Rows[i]
+		if got := row.Config.String(); got != ".name:"+name {
+			t.Errorf("row %d: want .name:%s, got %s", i, name, got)
+		}
+		if !(near(row.OldCenter, wantOld) || math.IsNaN(row.OldCenter) && math.IsNaN(wantOld)) ||
+			!(near(row.NewCenter, wantNew) || math.IsNaN(row.NewCenter) && math.IsNaN(wantNew)) {
+			t.Errorf("row %s: want %v -> %v, got %v -> %v", name, wantOld, wantNew, row.OldCenter, row.NewCenter)
+		}
+		if math.IsNaN(wantOld) || math.IsNaN(wantNew) {
+			if row.Comparison != nil {
+				t.Errorf("row %s: want no comparison, got %+v", name, row.Comparison)
+			}
+			return
+		}
+		if row.Comparison == nil {
+			t.Fatalf("row %s: missing comparison", name)
+		}
+		if want := wantNew / wantOld; !near(row.Comparison.Ratio, want) || !near(row.Comparison.Delta, want-1) {
+			t.Errorf("row %s: want ratio %v, got %v (delta %v)", name, want, row.Comparison.Ratio, row.Comparison.Delta)
+		}
+	}
+	// Medians are A: 12 -> 7, B: 22 -> 30, C: 7 -> missing, D:
+	// missing -> 3.
+	check(0, "A", 12, 7)
+	check(1, "B", 22, 30)
+	check(2, "C", 7, math.NaN())
+	check(3, "D", math.NaN(), 3)
+	// Completely separated samples of 5 have an exact p-value of
+	// 2/C(10,5).
+	if c := tab.Rows[0].Comparison; !near(c.P, 2.0/252) || c.N1 != 5 || c.N2 != 5 {
+		t.Errorf("row A: want p=%v with n=5,5, got p=%v with n=%d,%d", 2.0/252, c.P, c.N1, c.N2)
+	}
+
+	// The geomean only summarizes A and B.
+	if tab.Skipped != 2 {
+		t.Errorf("want 2 skipped rows, got %d", tab.Skipped)
+	}
+	g := tab.Geomean
+	if g == nil {
+		t.Fatal("missing geomean row")
+	}
+	wantOld, wantNew := math.Sqrt(12*22), math.Sqrt(7*30)
+	if !near(g.OldCenter, wantOld) || !near(g.NewCenter, wantNew) {
+		t.Errorf("geomean: want %v -> %v, got %v -> %v", wantOld, wantNew, g.OldCenter, g.NewCenter)
+	}
+	if want := math.Sqrt(7.0 / 12 * 30 / 22); !near(g.Comparison.Ratio, want) || !near(g.Comparison.Delta, want-1) {
+		t.Errorf("geomean: want ratio %v, got %v (delta %v)", want, g.Comparison.Ratio, g.Comparison.Delta)
+	}
+	if !math.IsNaN(g.Comparison.P) || g.Comparison.N1 != 2 {
+		t.Errorf("geomean: want p=NaN with n=2, got p=%v with n=%d", g.Comparison.P, g.Comparison.N1)
+	}
+
+	// With no complete rows, there's no geomean.
+	tab = CompareResults(rowBy, "sec/op", old, nil, DistributionOptions{})
+	if tab.Geomean != nil || tab.Skipped != 3 {
+		t.Errorf("want no geomean and 3 skipped rows, got %+v and %d", tab.Geomean, tab.Skipped)
+	}
+}
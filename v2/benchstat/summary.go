@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/aclements/go-moremath/stats"
+)
+
+// A Summary is a reduction used to summarize the columns of a table
+// of Distributions.
+type Summary int
+
+const (
+	// SummaryNone indicates no summary. Every summary it computes
+	// is NaN.
+	SummaryNone Summary = iota
+	// SummaryGeomean summarizes by the geometric mean. This is
+	// usually the right choice for benchmark results, since it
+	// is insensitive to the differing magnitudes of benchmarks.
+	// It is NaN if any value is not positive.
+	SummaryGeomean
+	// SummaryMean summarizes by the arithmetic mean.
+	SummaryMean
+	// SummarySum summarizes by the sum.
+	SummarySum
+)
+
+func (s Summary) String() string {
+	switch s {
+	case SummaryNone:
+		return "none"
+	case SummaryGeomean:
+		return "geomean"
+	case SummaryMean:
+		return "mean"
+	case SummarySum:
+		return "sum"
+	}
+	return fmt.Sprintf("Summary(%d)", int(s))
+}
+
+// reduce applies s to xs. It returns NaN for SummaryNone or if xs
+// is empty.
+func (s Summary) reduce(xs []float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	switch s {
+	case SummaryNone:
+		return math.NaN()
+	case SummaryGeomean:
+		return stats.GeoMean(xs)
+	case SummaryMean:
+		return stats.Mean(xs)
+	case SummarySum:
+		var sum float64
+		for _, x := range xs {
+			sum += x
+		}
+		return sum
+	}
+	panic(fmt.Sprintf("bad Summary %v", s))
+}
+
+// SummarizeColumns reduces the centers of each column of cells, which
+// is indexed by row and then column, using s. It returns the summary
+// of each column and a grand total that reduces all of the summarized
+// cells together.
+//
+// A nil cell is missing. So that the column summaries are comparable,
+// only rows with all cells present contribute to the summaries;
+// skipped is the number of rows omitted because they were incomplete.
+// If no rows are complete, every summary is NaN.
+func SummarizeColumns(cells [][]*Distribution, s Summary) (cols []float64, total float64, skipped int) {
+	nCols := 0
+	for _, row := range cells {
+		if len(row) > nCols {
+			nCols = len(row)
+		}
+	}
+
+	colVals := make([][]float64, nCols)
+	var all []float64
+rows:
+	for _, row := range cells {
+		if len(row) < nCols {
+			skipped++
+			continue
+		}
+		for _, cell := range row {
+			if cell == nil {
+				skipped++
+				continue rows
+			}
+		}
+		for i, cell := range row {
+			colVals[i] = append(colVals[i], cell.Center)
+			all = append(all, cell.Center)
+		}
+	}
+
+	cols = make([]float64, nCols)
+	for i, vals := range colVals {
+		cols[i] = s.reduce(vals)
+	}
+	return cols, s.reduce(all), skipped
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarizeColumns(t *testing.T) {
+	d := func(x float64) *Distribution {
+		return NewDistribution([]float64{x}, DistributionOptions{})
+	}
+	// Rows have very different magnitudes, and the last row is
+	// missing a cell.
+	cells := [][]*Distribution{
+		{d(1), d(2)},
+		{d(1000), d(500)},
+		{d(4), nil},
+	}
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9*math.Max(math.Abs(a), math.Abs(b))
+	}
+	check := func(s Summary, wantCols []float64, wantTotal float64) {
+		t.Helper()
+		cols, total, skipped := SummarizeColumns(cells, s)
+		if skipped != 1 {
+			t.Errorf("%v: want 1 skipped row, got %d", s, skipped)
+		}
+		if len(cols) != len(wantCols) {
+			t.Fatalf("%v: want %d columns, got %v", s, len(wantCols), cols)
+		}
+		for i := range cols {
+			if !near(cols[i], wantCols[i]) {
+				t.Errorf("%v: column %d: want %v, got %v", s, i, wantCols[i], cols[i])
+			}
+		}
+		if !near(total, wantTotal) {
+			t.Errorf("%v: total: want %v, got %v", s, wantTotal, total)
+		}
+	}
+	check(SummaryGeomean, []float64{math.Sqrt(1000), math.Sqrt(1000)}, math.Sqrt(1000))
+	check(SummaryMean, []float64{500.5, 251}, 375.75)
+	check(SummarySum, []float64{1001, 502}, 1503)
+
+	// With no complete rows, summaries are NaN.
+	cols, total, skipped := SummarizeColumns([][]*Distribution{{d(1), nil}}, SummaryGeomean)
+	if skipped != 1 || !math.IsNaN(cols[0]) || !math.IsNaN(cols[1]) || !math.IsNaN(total) {
+		t.Errorf("no complete rows: want NaN summaries, got %v %v (%d skipped)", cols, total, skipped)
+	}
+	cols, total, _ = SummarizeColumns([][]*Distribution{{d(1), nil}}, SummarySum)
+	if !math.IsNaN(cols[0]) || !math.IsNaN(total) {
+		t.Errorf("no complete rows: want NaN sums, got %v %v", cols, total)
+	}
+
+	// SummaryNone summarizes nothing.
+	cols, total, _ = SummarizeColumns(cells, SummaryNone)
+	if len(cols) != 2 || !math.IsNaN(cols[0]) || !math.IsNaN(cols[1]) || !math.IsNaN(total) {
+		t.Errorf("SummaryNone: want NaN summaries, got %v %v", cols, total)
+	}
+}
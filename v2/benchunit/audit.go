@@ -0,0 +1,84 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// A UnitAudit finds benchmarks whose results report different sets of
+// units. Since grouping results by unit silently separates these,
+// they usually indicate an inconsistency in the benchmark harness,
+// such as one run reporting "ns/op" and another "sec/op" for the same
+// benchmark after some units were renamed.
+//
+// Units are compared after tidying, so a result reporting "ns/op" is
+// consistent with one reporting "sec/op".
+type UnitAudit struct {
+	benchmarks map[string]*auditEntry
+	order      []string
+}
+
+type auditEntry struct {
+	// sets is the distinct unit sets of this benchmark in the
+	// order they were first observed. seen is sets joined by
+	// spaces, for deduplication.
+	sets [][]string
+	seen map[string]bool
+}
+
+// A UnitInconsistency is a benchmark whose results report different
+// sets of units.
+type UnitInconsistency struct {
+	// Name is the full name of the benchmark.
+	Name string
+
+	// UnitSets is each distinct set of tidied units reported by
+	// results of this benchmark, in the order they were first
+	// observed. Each set is sorted.
+	UnitSets [][]string
+}
+
+// NewUnitAudit returns a new, empty UnitAudit.
+func NewUnitAudit() *UnitAudit {
+	return &UnitAudit{benchmarks: make(map[string]*auditEntry)}
+}
+
+// Add records the units of res. It does not modify or retain res.
+func (a *UnitAudit) Add(res *benchfmt.Result) {
+	units := make([]string, 0, len(res.Values))
+	for _, val := range res.Values {
+		tidied, _ := TidyUnit(val.Unit)
+		units = append(units, tidied)
+	}
+	sort.Strings(units)
+	key := strings.Join(units, " ")
+
+	e := a.benchmarks[string(res.FullName)]
+	if e == nil {
+		e = &auditEntry{seen: make(map[string]bool)}
+		a.benchmarks[string(res.FullName)] = e
+		a.order = append(a.order, string(res.FullName))
+	}
+	if !e.seen[key] {
+		e.seen[key] = true
+		e.sets = append(e.sets, units)
+	}
+}
+
+// Inconsistencies returns the benchmarks added to a that have more
+// than one set of units, in the order they were first added.
+func (a *UnitAudit) Inconsistencies() []UnitInconsistency {
+	var out []UnitInconsistency
+	for _, name := range a.order {
+		if e := a.benchmarks[name]; len(e.sets) > 1 {
+			out = append(out, UnitInconsistency{name, e.sets})
+		}
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestUnitAudit(t *testing.T) {
+	const input = `BenchmarkA 1 1 ns/op 2 B/op
+BenchmarkB 1 1 ns/op
+BenchmarkC/n=1 1 1 ns/op
+BenchmarkA 1 1 sec/op 2 B/op
+BenchmarkB 1 1 ns/op 1 allocs/op
+BenchmarkC/n=2 1 1 B/op
+BenchmarkA 1 1 sec/op
+BenchmarkB 1 1 allocs/op 1 ns/op
+`
+	a := NewUnitAudit()
+	r := benchfmt.NewReader(strings.NewReader(input), "test")
+	for r.Scan() {
+		res, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.Add(res)
+	}
+
+	var got []string
+	for _, inc := range a.Inconsistencies() {
+		got = append(got, fmt.Sprintf("%s:%q", inc.Name, inc.UnitSets))
+	}
+	// A's ns/op and sec/op are the same after tidying, but its
+	// last result is missing B/op. B's unit order doesn't matter.
+	// C's results are different benchmarks.
+	want := []string{
+		`A:[["B/op" "sec/op"] ["sec/op"]]`,
+		`B:[["sec/op"] ["allocs/op" "sec/op"]]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import (
+	"math"
+	"sync"
+)
+
+var integralUnits sync.Map // unit string -> bool
+
+func init() {
+	for _, unit := range []string{"allocs/op", "B/op"} {
+		integralUnits.Store(unit, true)
+	}
+}
+
+// RegisterIntegral registers unit as integer-valued. Values in
+// integral units are formatted without a fractional part when they
+// are not scaled by a prefix and are all whole numbers.
+//
+// By default, "allocs/op" and "B/op" are integral.
+func RegisterIntegral(unit string) {
+	integralUnits.Store(unit, true)
+}
+
+// IsIntegral reports whether unit has been registered as
+// integer-valued.
+func IsIntegral(unit string) bool {
+	_, ok := integralUnits.Load(unit)
+	return ok
+}
+
+// CommonScaleUnit is like CommonScale, but takes the unit of vals
+// rather than its UnitClass. If unit is integral, vals are all whole
+// numbers, and the common scale has no prefix, the returned Scaler
+// formats values without a decimal point. For example, 4 allocs/op
+// is formatted as "4" rather than "4.00".
+func CommonScaleUnit(vals []float64, unit string) Scaler {
+	s := CommonScale(vals, UnitClassOf(unit))
+	if s.Factor != 1 || !IsIntegral(unit) {
+		return s
+	}
+	for _, v := range vals {
+		if v != math.Trunc(v) {
+			return s
+		}
+	}
+	s.Prec = 0
+	return s
+}
+
+// ScaleUnit formats val in unit using at least three significant
+// digits, appending an SI or binary prefix as appropriate for unit.
+// Whole numbers in integral units are formatted without a decimal
+// point.
+func ScaleUnit(val float64, unit string) string {
+	return CommonScaleUnit([]float64{val}, unit).Format(val)
+}
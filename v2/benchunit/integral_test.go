@@ -0,0 +1,45 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import "testing"
+
+func TestScaleUnit(t *testing.T) {
+	test := func(val float64, unit, want string) {
+		t.Helper()
+		if got := ScaleUnit(val, unit); got != want {
+			t.Errorf("for %v %s, got %s, want %s", val, unit, got, want)
+		}
+	}
+
+	// Integral units format whole numbers without a decimal.
+	test(4, "allocs/op", "4")
+	test(0, "allocs/op", "0")
+	test(512, "B/op", "512")
+	// But keep precision if the value isn't whole or is scaled.
+	test(4.5, "allocs/op", "4.50")
+	test(2048, "B/op", "2.00Ki")
+	test(12345, "allocs/op", "12.3k")
+	// Non-integral units keep their precision.
+	test(4, "sec/op", "4.00")
+	test(4, "B/sec", "4.00")
+
+	if IsIntegral("widgets/op") {
+		t.Fatalf("widgets/op unexpectedly integral")
+	}
+	test(4, "widgets/op", "4.00")
+	RegisterIntegral("widgets/op")
+	// The registry is global, so undo this for later runs.
+	defer integralUnits.Delete("widgets/op")
+	test(4, "widgets/op", "4")
+
+	// A common scale is only integral if all values are whole.
+	if got := CommonScaleUnit([]float64{1, 2, 3}, "allocs/op").Format(2); got != "2" {
+		t.Errorf("whole common scale: got %s, want 2", got)
+	}
+	if got := CommonScaleUnit([]float64{1, 2.5, 3}, "allocs/op").Format(2); got != "2.00" {
+		t.Errorf("fractional common scale: got %s, want 2.00", got)
+	}
+}
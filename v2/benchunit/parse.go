@@ -5,7 +5,10 @@
 // Package benchunit works with benchmark units.
 //
 // It provides functions for parsing and interpreting units and for
-// printing numbers in those units.
+// printing numbers in those units. Functions such as Tidy and
+// Reciprocal also apply these to the values of a benchfmt.Result, so
+// benchunit builds on benchfmt, and benchfmt must not import
+// benchunit.
 package benchunit
 
 import (
@@ -0,0 +1,80 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import (
+	"strings"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+// Reciprocal rewrites every value in result measured in unit to its
+// reciprocal, and changes its unit to ReciprocalUnit(unit). For
+// example, this converts a latency measured in "sec/op" to a
+// throughput measured in "op/sec", and vice versa.
+//
+// The reciprocal of 0 is +Inf, following IEEE 754 division. An
+// uncertainty in Value.Err is propagated to first order, as
+// Err/Value².
+func Reciprocal(result *benchfmt.Result, unit string) {
+	var runit string
+	for i, v := range result.Values {
+		if v.Unit != unit {
+			continue
+		}
+		if runit == "" {
+			runit = ReciprocalUnit(unit)
+		}
+		var err float64
+		if v.Err != 0 {
+			err = v.Err / (v.Value * v.Value)
+		}
+		result.Values[i] = benchfmt.Value{Value: 1 / v.Value, Unit: runit, Err: err}
+	}
+}
+
+// ReciprocalUnit returns the reciprocal of unit by swapping its
+// numerator and denominator. For example, the reciprocal of "sec/op"
+// is "op/sec" and the reciprocal of "B/sec/op" is "sec*op/B". If unit
+// has no denominator, the result has a numerator of "1", as in
+// "1/B".
+func ReciprocalUnit(unit string) string {
+	// Split unit into factors at "*" and "/", following the same
+	// numerator/denominator rules as parser. We don't split at
+	// "-" because that separates words within a single factor,
+	// as in "disk-B".
+	var num, denom []string
+	denomNext := false
+	start := 0
+	for i := 0; i <= len(unit); i++ {
+		if i < len(unit) && unit[i] != '*' && unit[i] != '/' {
+			continue
+		}
+		if f := strings.TrimSpace(unit[start:i]); f != "" {
+			if denomNext {
+				denom = append(denom, f)
+			} else {
+				num = append(num, f)
+			}
+		}
+		if i < len(unit) {
+			denomNext = unit[i] == '/'
+		}
+		start = i + 1
+	}
+
+	// Reassemble with the factors swapped.
+	var buf strings.Builder
+	if len(denom) == 0 {
+		buf.WriteString("1")
+	} else {
+		buf.WriteString(strings.Join(denom, "*"))
+	}
+	for _, f := range num {
+		buf.WriteByte('/')
+		buf.WriteString(f)
+	}
+	return buf.String()
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchunit
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
+
+func TestReciprocalUnit(t *testing.T) {
+	test := func(unit, want string) {
+		t.Helper()
+		got := ReciprocalUnit(unit)
+		if got != want {
+			t.Errorf("for %s, want %s, got %s", unit, want, got)
+		}
+	}
+	test("sec/op", "op/sec")
+	test("op/sec", "sec/op")
+	test("ns/op", "op/ns")
+	test("disk-B/sec", "sec/disk-B")
+	test("B/sec/op", "sec*op/B")
+	test("B*B/sec", "sec/B/B")
+	test("B", "1/B")
+}
+
+func TestReciprocal(t *testing.T) {
+	res := &benchfmt.Result{
+		Values: []benchfmt.Value{{Value: 0.25, Unit: "sec/op"}, {Value: 10, Unit: "B/op"}},
+	}
+	Reciprocal(res, "sec/op")
+	want := []benchfmt.Value{{Value: 4, Unit: "op/sec"}, {Value: 10, Unit: "B/op"}}
+	for i := range want {
+		if res.Values[i] != want[i] {
+			t.Errorf("value %d: want %v, got %v", i, want[i], res.Values[i])
+		}
+	}
+
+	// Converting back restores the original.
+	Reciprocal(res, "op/sec")
+	if v := res.Values[0]; v.Value != 0.25 || v.Unit != "sec/op" {
+		t.Errorf("round trip: want {0.25 sec/op}, got %v", v)
+	}
+
+	// Uncertainties propagate to first order.
+	res = &benchfmt.Result{
+		Values: []benchfmt.Value{{Value: 0.25, Unit: "sec/op", Err: 0.125}},
+	}
+	Reciprocal(res, "sec/op")
+	if v := res.Values[0]; v.Value != 4 || v.Err != 2 {
+		t.Errorf("with uncertainty: want {4 op/sec 2}, got %v", v)
+	}
+
+	// Zero becomes +Inf.
+	res = &benchfmt.Result{
+		Values: []benchfmt.Value{{Value: 0, Unit: "sec/op"}},
+	}
+	Reciprocal(res, "sec/op")
+	if v := res.Values[0]; !math.IsInf(v.Value, 1) || v.Unit != "op/sec" {
+		t.Errorf("zero: want {+Inf op/sec}, got %v", v)
+	}
+}
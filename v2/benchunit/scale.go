@@ -7,7 +7,13 @@ package benchunit
 import (
 	"fmt"
 	"math"
+	"math/big"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/perf/v2/benchfmt"
 )
 
 // Scaler represents a scaling factor for a number and its scientific
@@ -16,53 +22,163 @@ type Scaler struct {
 	Prec   int     // Digits after the decimal point
 	Factor float64 // Unscaled value of 1 Prefix (e.g., 1 k => 1000)
 	Prefix string  // Unit prefix (SI or binary)
+
+	// Rounding is how Format rounds values to Prec digits.
+	Rounding Rounding
 }
 
+// Rounding selects how a Scaler rounds a value that is exactly halfway
+// between two values with the Scaler's precision.
+type Rounding int
+
+const (
+	// RoundHalfEven rounds halfway values to the nearest even
+	// digit, as strconv does. For example, 2.5 rounds to 2 and 3.5
+	// rounds to 4. This is the default.
+	RoundHalfEven Rounding = iota
+
+	// RoundHalfUp rounds halfway values away from zero, as many
+	// spreadsheets and reporting tools do. For example, 2.5 rounds
+	// to 3 and -2.5 rounds to -3.
+	RoundHalfUp
+)
+
 // Format formats val and appends the unit prefix according to the
 // given scale.
 func (s Scaler) Format(val float64) string {
 	buf := make([]byte, 0, 20)
-	buf = strconv.AppendFloat(buf, val/s.Factor, 'f', s.Prec, 64)
+	scaled := val / s.Factor
+	if s.Rounding == RoundHalfUp && s.Prec >= 0 && scaled != 0 && !math.IsInf(scaled, 0) && !math.IsNaN(scaled) {
+		// big.Rat rounds the exact value of scaled, with
+		// halves rounded away from zero.
+		buf = append(buf, new(big.Rat).SetFloat64(scaled).FloatString(s.Prec)...)
+	} else {
+		buf = strconv.AppendFloat(buf, scaled, 'f', s.Prec, 64)
+	}
 	buf = append(buf, s.Prefix...)
 	return string(buf)
 }
 
+// ParseScaled parses a number formatted by a Scaler. That is, a
+// number optionally followed by an SI prefix such as "k" or "µ", or a
+// binary prefix such as "Ki" or "/Ki". For example, it parses "1Ki"
+// as 1024 and "1.5k" as 1500.
+func ParseScaled(s string) (float64, error) {
+	num, scale := s, 1.0
+	for _, factors := range [][]factor{siFactors, iecFactors} {
+		for _, f := range factors {
+			// Take the longest matching prefix. For
+			// example, "/Ki" over "Ki".
+			if f.prefix != "" && strings.HasSuffix(s, f.prefix) && len(s)-len(f.prefix) < len(num) {
+				num, scale = s[:len(s)-len(f.prefix)], f.factor
+			}
+		}
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, &strconv.NumError{Func: "ParseScaled", Num: s, Err: err.(*strconv.NumError).Err}
+	}
+	return val * scale, nil
+}
+
 // NoOpScaler is a Scaler that formats numbers with the smallest
 // number of digits necessary to capture the exact value, and no
 // prefix. This is intended for when the output will be consumed by
 // another program, such as when producing CSV format.
-var NoOpScaler = Scaler{-1, 1, ""}
+var NoOpScaler = Scaler{Prec: -1, Factor: 1}
 
 type factor struct {
 	factor float64
 	prefix string
-	// Thresholds for 100, 10.0, 1.00.
-	t100, t10, t1 float64
+	// thresh[p] is the smallest value that is formatted with p
+	// digits after the decimal point. For three significant
+	// digits, these are the thresholds for 100, 10.0, and 1.00.
+	thresh []float64
 }
 
-var siFactors = mkSIFactors()
-var iecFactors = mkIECFactors()
+var siFactors = mkSIFactors(3, RoundHalfEven)
+var iecFactors = mkIECFactors(3, RoundHalfEven)
 
-func mkSIFactors() []factor {
+// sigFactors caches factors for other numbers of significant digits
+// and other rounding modes.
+var sigFactors sync.Map // sigKey -> []factor
+
+type sigKey struct {
+	cls      UnitClass
+	sigFigs  int
+	rounding Rounding
+}
+
+// threshMantissas returns the decimal representations of the
+// thresholds for sigFigs significant digits, unscaled. For example,
+// for 3 significant digits, these are "99.95", "9.995", and ".9995":
+// the values at which rounding to fewer digits after the decimal
+// point would produce one more digit before it.
+func threshMantissas(sigFigs int) []string {
+	out := make([]string, sigFigs)
+	for p := range out {
+		out[p] = strings.Repeat("9", sigFigs-1-p) + "." + strings.Repeat("9", p+1) + "5"
+	}
+	return out
+}
+
+// halfUpThresh returns the smallest value v such that v/factor,
+// rounded half-up, is at least the decimal mantissa m. approx is an
+// approximation of this value.
+//
+// With half-even rounding, parsing the printed threshold is close
+// enough, but with half-up rounding, a value just below the exact
+// decimal threshold must round down, so this finds the threshold to
+// the last bit, taking into account the rounding of the division in
+// Scaler.Format.
+func halfUpThresh(approx, factor float64, m string) float64 {
+	target, ok := new(big.Rat).SetString(m)
+	if !ok {
+		panic("bad threshold mantissa " + m)
+	}
+	reaches := func(v float64) bool {
+		return new(big.Rat).SetFloat64(v/factor).Cmp(target) >= 0
+	}
+	t := approx
+	for !reaches(t) {
+		t = math.Nextafter(t, math.Inf(1))
+	}
+	for {
+		prev := math.Nextafter(t, 0)
+		if !reaches(prev) {
+			return t
+		}
+		t = prev
+	}
+}
+
+func mkSIFactors(sigFigs int, rounding Rounding) []factor {
 	// To ensure that the thresholds for printing values with
 	// various factors exactly match how printing itself will
 	// round, we construct the thresholds by parsing the printed
 	// representation.
 	var factors []factor
 	exp := 12
+	mantissas := threshMantissas(sigFigs)
 	for _, p := range []string{"T", "G", "M", "k", "", "m", "µ", "n"} {
-		t100, _ := strconv.ParseFloat(fmt.Sprintf("99.95e%d", exp), 64)
-		t10, _ := strconv.ParseFloat(fmt.Sprintf("9.995e%d", exp), 64)
-		t1, _ := strconv.ParseFloat(fmt.Sprintf(".9995e%d", exp), 64)
-		factors = append(factors, factor{math.Pow(10, float64(exp)), p, t100, t10, t1})
+		f := math.Pow(10, float64(exp))
+		thresh := make([]float64, sigFigs)
+		for i, m := range mantissas {
+			thresh[i], _ = strconv.ParseFloat(fmt.Sprintf("%se%d", m, exp), 64)
+			if rounding == RoundHalfUp {
+				thresh[i] = halfUpThresh(thresh[i], f, m)
+			}
+		}
+		factors = append(factors, factor{f, p, thresh})
 		exp -= 3
 	}
 	return factors
 }
 
-func mkIECFactors() []factor {
+func mkIECFactors(sigFigs int, rounding Rounding) []factor {
 	var factors []factor
 	exp := 40
+	mantissas := threshMantissas(sigFigs)
 	// ISO/IEC 80000 doesn't specify fractional prefixes, but
 	// they're still meaningful for rates like B/sec. Hence, we
 	// use the convention of adding a slash as in "X per unit".
@@ -70,15 +186,51 @@ func mkIECFactors() []factor {
 	// Maybe we should instead stop at 1 and format with more
 	// precision?
 	for _, p := range []string{"Ti", "Gi", "Mi", "Ki", "", "/Ki", "/Mi", "/Gi", "/Ti"} {
-		t100, _ := strconv.ParseFloat(fmt.Sprintf("0x1.8fccccccccccdp%d", 6+exp), 64) // 99.95
-		t10, _ := strconv.ParseFloat(fmt.Sprintf("0x1.3fd70a3d70a3dp%d", 3+exp), 64)  // 9.995
-		t1, _ := strconv.ParseFloat(fmt.Sprintf("0x1.ffbe76c8b4396p%d", -1+exp), 64)  // .9995
-		factors = append(factors, factor{math.Pow(2, float64(exp)), p, t100, t10, t1})
+		// Scaling by a power of two is exact, so this
+		// exactly matches how printing will round.
+		f := math.Pow(2, float64(exp))
+		thresh := make([]float64, sigFigs)
+		for i, m := range mantissas {
+			t, _ := strconv.ParseFloat(m, 64)
+			thresh[i] = math.Ldexp(t, exp)
+			if rounding == RoundHalfUp {
+				thresh[i] = halfUpThresh(thresh[i], f, m)
+			}
+		}
+		factors = append(factors, factor{f, p, thresh})
 		exp -= 10
 	}
 	return factors
 }
 
+// factorsFor returns the factors for cls and sigFigs significant
+// digits, with thresholds matching the given rounding mode.
+func factorsFor(cls UnitClass, sigFigs int, rounding Rounding) []factor {
+	if sigFigs == 3 && rounding == RoundHalfEven {
+		switch cls {
+		case UnitClassSI:
+			return siFactors
+		case UnitClassIEC:
+			return iecFactors
+		}
+	}
+	key := sigKey{cls, sigFigs, rounding}
+	if f, ok := sigFactors.Load(key); ok {
+		return f.([]factor)
+	}
+	var factors []factor
+	switch cls {
+	default:
+		panic(fmt.Sprintf("bad UnitClass %v", cls))
+	case UnitClassSI:
+		factors = mkSIFactors(sigFigs, rounding)
+	case UnitClassIEC:
+		factors = mkIECFactors(sigFigs, rounding)
+	}
+	sigFactors.Store(key, factors)
+	return factors
+}
+
 // Scale formats val using at least three significant digits,
 // appending an SI or binary prefix.
 func Scale(val float64, cls UnitClass) string {
@@ -89,6 +241,145 @@ func Scale(val float64, cls UnitClass) string {
 // This scale will show at least three significant digits for every
 // value.
 func CommonScale(vals []float64, cls UnitClass) Scaler {
+	scaler, _ := commonScale(vals, cls, 3, RoundHalfEven)
+	return scaler
+}
+
+// ScaleSig is like Scale, but formats val using at least sigFigs
+// significant digits rather than three. sigFigs less than 1 is
+// treated as 1.
+func ScaleSig(val float64, cls UnitClass, sigFigs int) string {
+	return CommonScaleSig([]float64{val}, cls, sigFigs).Format(val)
+}
+
+// CommonScaleSig is like CommonScale, but the returned Scaler will
+// show at least sigFigs significant digits for every value, rather
+// than three. sigFigs less than 1 is treated as 1.
+func CommonScaleSig(vals []float64, cls UnitClass, sigFigs int) Scaler {
+	scaler, _ := commonScale(vals, cls, sigFigs, RoundHalfEven)
+	return scaler
+}
+
+// CommonScaleRounding is like CommonScaleSig, but the returned Scaler
+// rounds values using the given rounding mode. The choice of prefix
+// and precision accounts for the rounding mode, so that, for
+// example, a value that rounds up to 1000 is shown as "1.00k" rather
+// than "1000".
+func CommonScaleRounding(vals []float64, cls UnitClass, sigFigs int, rounding Rounding) Scaler {
+	scaler, _ := commonScale(vals, cls, sigFigs, rounding)
+	return scaler
+}
+
+// TableScale returns a single Scaler to apply to every value in a
+// table, so all cells share a prefix and line up.
+//
+// Unlike CommonScale, which picks the prefix from the smallest value
+// and can leave large values with many digits before the decimal
+// point, TableScale picks the prefix that Scale would use for the
+// median non-zero magnitude in allValues, so typical values read
+// naturally. It then uses enough digits after the decimal point to
+// show at least three significant digits of the smallest non-zero
+// value.
+func TableScale(allValues []float64, cls UnitClass) Scaler {
+	const sigFigs = 3
+
+	var mags []float64
+	for _, v := range allValues {
+		if v != 0 && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			mags = append(mags, math.Abs(v))
+		}
+	}
+	if len(mags) == 0 {
+		return CommonScale(nil, cls)
+	}
+	sort.Float64s(mags)
+
+	// Use the lower median so the prefix comes from a real value.
+	s := CommonScale(mags[(len(mags)-1)/2:][:1], cls)
+
+	// Show the smallest value with at least sigFigs significant
+	// digits. This is at least the precision for the median.
+	s.Prec = sigPrec(mags[0]/s.Factor, s.Prec, sigFigs)
+	return s
+}
+
+// sigPrec returns the smallest precision of at least prec that
+// formats val with at least sigFigs significant digits.
+func sigPrec(val float64, prec, sigFigs int) int {
+	for ; prec < 20; prec++ {
+		if sigDigits(strconv.FormatFloat(val, 'f', prec, 64)) >= sigFigs {
+			break
+		}
+	}
+	return prec
+}
+
+// sigDigits returns the number of significant digits in the decimal
+// number str, which must not have an exponent.
+func sigDigits(str string) int {
+	n := 0
+	for _, c := range str {
+		if c >= '1' && c <= '9' || c == '0' && n > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// HintedScale is like CommonScale, but honors a display scale declared
+// for unit in md, which is unit metadata from benchfmt.Reader. If md
+// declares a benchfmt.UnitScaleKey for unit, the returned Scaler uses
+// that prefix, with enough digits after the decimal point to show at
+// least three significant digits of every value. If there is no hint,
+// or its prefix isn't recognized, this is the same as CommonScale.
+func HintedScale(md map[benchfmt.UnitMetadataKey]string, unit string, vals []float64, cls UnitClass) Scaler {
+	hint, ok := md[benchfmt.UnitMetadataKey{Unit: unit, Key: benchfmt.UnitScaleKey}]
+	if !ok {
+		return CommonScale(vals, cls)
+	}
+	s := Scaler{Factor: 1}
+	if hint != "1" {
+		found := false
+		for _, factors := range [][]factor{siFactors, iecFactors} {
+			for _, f := range factors {
+				if f.prefix == hint && f.prefix != "" {
+					s.Factor, s.Prefix, found = f.factor, f.prefix, true
+				}
+			}
+		}
+		if !found {
+			return CommonScale(vals, cls)
+		}
+	}
+
+	var min float64
+	for _, v := range vals {
+		v = math.Abs(v)
+		if v != 0 && (min == 0 || v < min) {
+			min = v
+		}
+	}
+	const sigFigs = 3
+	if min == 0 {
+		s.Prec = sigFigs - 1
+	} else {
+		s.Prec = sigPrec(min/s.Factor, 0, sigFigs)
+	}
+	return s
+}
+
+// ScaleExplain returns the Scaler that Scale would use to format val,
+// along with a human-readable explanation of why that Scaler was
+// chosen. This is intended for debugging surprising formatting.
+func ScaleExplain(val float64, cls UnitClass) (Scaler, string) {
+	return commonScale([]float64{val}, cls, 3, RoundHalfEven)
+}
+
+func commonScale(vals []float64, cls UnitClass, sigFigs int, rounding Rounding) (Scaler, string) {
+	if sigFigs < 1 {
+		sigFigs = 1
+	}
+
 	// The common scale is determined by the non-zero value
 	// closest to zero.
 	var min float64
@@ -99,29 +390,24 @@ func CommonScale(vals []float64, cls UnitClass) Scaler {
 		}
 	}
 	if min == 0 {
-		return Scaler{2, 1, ""}
+		s := Scaler{sigFigs - 1, 1, "", rounding}
+		return s, fmt.Sprintf("all values are zero; using factor 1 with %d digits after the decimal point", s.Prec)
 	}
 
-	var factors []factor
-	switch cls {
-	default:
-		panic(fmt.Sprintf("bad UnitClass %v", cls))
-	case UnitClassSI:
-		factors = siFactors
-	case UnitClassIEC:
-		factors = iecFactors
-	}
-
-	for i, factor := range factors {
-		last := i == len(factors)-1
-		switch {
-		case min >= factor.t100:
-			return Scaler{0, factor.factor, factor.prefix}
-		case min >= factor.t10:
-			return Scaler{1, factor.factor, factor.prefix}
-		case min >= factor.t1 || last:
-			return Scaler{2, factor.factor, factor.prefix}
+	factors := factorsFor(cls, sigFigs, rounding)
+
+	explain := func(s Scaler, thresh float64) string {
+		return fmt.Sprintf("smallest non-zero magnitude %v >= threshold %v; using factor %v (prefix %q) with %d digits after the decimal point", min, thresh, s.Factor, s.Prefix, s.Prec)
+	}
+	for _, factor := range factors {
+		for prec, thresh := range factor.thresh {
+			if min >= thresh {
+				s := Scaler{prec, factor.factor, factor.prefix, rounding}
+				return s, explain(s, thresh)
+			}
 		}
 	}
-	panic("not reachable")
+	factor := factors[len(factors)-1]
+	s := Scaler{sigFigs - 1, factor.factor, factor.prefix, rounding}
+	return s, fmt.Sprintf("smallest non-zero magnitude %v < smallest threshold %v; using smallest factor %v (prefix %q) with %d digits after the decimal point", min, factor.thresh[sigFigs-1], s.Factor, s.Prefix, s.Prec)
 }
@@ -5,8 +5,12 @@
 package benchunit
 
 import (
+	"fmt"
 	"math"
+	"strings"
 	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
 )
 
 func TestScale(t *testing.T) {
@@ -121,3 +125,277 @@ func TestNoOpScaler(t *testing.T) {
 	test(123456789, "123456789")
 	test(123.456789, "123.456789")
 }
+
+func TestScaleExplain(t *testing.T) {
+	test := func(val float64, cls UnitClass, want Scaler, wantWhy string) {
+		t.Helper()
+		got, why := ScaleExplain(val, cls)
+		if got != want {
+			t.Errorf("for %v, got %+v, want %+v", val, got, want)
+		}
+		if why != wantWhy {
+			t.Errorf("for %v, got explanation:\n\t%s\nwant:\n\t%s", val, why, wantWhy)
+		}
+		if s := CommonScale([]float64{val}, cls); s != got {
+			t.Errorf("for %v, CommonScale %+v disagrees with ScaleExplain %+v", val, s, got)
+		}
+	}
+
+	// Exactly on the boundary between "1.00" and "999m".
+	test(.9995, UnitClassSI, Scaler{2, 1, "", RoundHalfEven},
+		`smallest non-zero magnitude 0.9995 >= threshold 0.9995; using factor 1 (prefix "") with 2 digits after the decimal point`)
+	test(math.Nextafter(.9995, 0), UnitClassSI, Scaler{0, 1e-3, "m", RoundHalfEven},
+		`smallest non-zero magnitude 0.9994999999999999 >= threshold 0.09995; using factor 0.001 (prefix "m") with 0 digits after the decimal point`)
+	test(9.995*(1<<10), UnitClassIEC, Scaler{1, 1 << 10, "Ki", RoundHalfEven},
+		`smallest non-zero magnitude 10234.88 >= threshold 10234.88; using factor 1024 (prefix "Ki") with 1 digits after the decimal point`)
+	// Below the smallest threshold.
+	test(.00000000001, UnitClassSI, Scaler{2, 1e-9, "n", RoundHalfEven},
+		`smallest non-zero magnitude 1e-11 < smallest threshold 9.995e-10; using smallest factor 1e-09 (prefix "n") with 2 digits after the decimal point`)
+	test(0, UnitClassSI, Scaler{2, 1, "", RoundHalfEven},
+		`all values are zero; using factor 1 with 2 digits after the decimal point`)
+}
+
+func TestTableScale(t *testing.T) {
+	check := func(vals []float64, cls UnitClass, want ...string) {
+		t.Helper()
+		s := TableScale(vals, cls)
+		var got []string
+		for _, v := range vals {
+			got = append(got, s.Format(v))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("for %v, want %v, got %v", vals, want, got)
+		}
+	}
+
+	// Values spanning k to M use the prefix of the median, with
+	// enough precision for the smallest value.
+	check([]float64{950e3, 1.2e6, 3.4e6}, UnitClassSI, "0.950M", "1.200M", "3.400M")
+	check([]float64{1500, 250e3, 480e3, 12e6}, UnitClassSI, "1.50k", "250.00k", "480.00k", "12000.00k")
+	// CommonScale, by contrast, picks the prefix from the smallest
+	// value.
+	if s := CommonScale([]float64{950e3, 1.2e6, 3.4e6}, UnitClassSI); s.Prefix != "k" {
+		t.Errorf("want CommonScale prefix k, got %q", s.Prefix)
+	}
+	// Zeros, signs, and binary prefixes.
+	check([]float64{0, -2048, 4096, 8192}, UnitClassIEC, "0.00Ki", "-2.00Ki", "4.00Ki", "8.00Ki")
+	check([]float64{0, 0}, UnitClassSI, "0.00", "0.00")
+}
+
+func TestHintedScale(t *testing.T) {
+	r := benchfmt.NewReader(strings.NewReader("Unit sec/op scale=m\nUnit B/op scale=bogus\n"), "test")
+	for r.Scan() {
+	}
+	md := r.UnitMetadata()
+
+	check := func(unit string, vals []float64, cls UnitClass, want ...string) {
+		t.Helper()
+		s := HintedScale(md, unit, vals, cls)
+		var got []string
+		for _, v := range vals {
+			got = append(got, s.Format(v))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("for %s %v, want %v, got %v", unit, vals, want, got)
+		}
+	}
+
+	// The hint overrides the prefix CommonScale would choose.
+	check("sec/op", []float64{2.5, 0.0012}, UnitClassSI, "2500.00m", "1.20m")
+	check("sec/op", []float64{0.0000015}, UnitClassSI, "0.00150m")
+	check("sec/op", []float64{0}, UnitClassSI, "0.00m")
+	// Without a usable hint, this is CommonScale.
+	check("B/op", []float64{2048, 4096}, UnitClassIEC, "2.00Ki", "4.00Ki")
+	check("allocs/op", []float64{1500}, UnitClassSI, "1.50k")
+}
+
+func TestScaleSig(t *testing.T) {
+	var cls UnitClass
+	var sig int
+	test := func(num float64, want, wantPred string) {
+		t.Helper()
+
+		got := ScaleSig(num, cls, sig)
+		if got != want {
+			t.Errorf("for %v with %d digits, got %s, want %s", num, sig, got, want)
+		}
+
+		// As in TestScale, check the crux between two scale
+		// factors or precisions.
+		pred := math.Nextafter(num, 0)
+		got = ScaleSig(pred, cls, sig)
+		if got != wantPred {
+			t.Errorf("for %v-ε with %d digits, got %s, want %s", num, sig, got, wantPred)
+		}
+	}
+
+	cls = UnitClassSI
+	sig = 2
+	test(0, "0.0", "0.0")
+	test(1, "1.0", "1.0")
+	test(995, "1.0k", "995")
+	test(99.5, "100", "99")
+	test(9.95, "10", "9.9")
+	test(.995, "1.0", "995m")
+	test(9950, "10k", "9.9k")
+	test(.00000000995, "10n", "9.9n")
+	test(.000000000995, "1.0n", "1.0n")
+
+	sig = 4
+	test(0, "0.000", "0.000")
+	test(1, "1.000", "1.000")
+	test(999.95, "1.000k", "999.9")
+	test(99.995, "100.0", "99.99")
+	test(9.9995, "10.00", "9.999")
+	test(.99995, "1.000", "999.9m")
+	test(9999.5, "10.00k", "9.999k")
+	test(.00000000099995, "1.000n", "1.000n")
+
+	sig = 1
+	test(9.5, "10", "9")
+	test(.95, "1", "950m")
+
+	cls = UnitClassIEC
+	sig = 2
+	test(9.95*(1<<10), "10Ki", "9.9Ki")
+	test(.995*(1<<20), "1.0Mi", "1019Ki")
+	sig = 4
+	test(9.9995*(1<<10), "10.00Ki", "9.999Ki")
+	test(.99995*(1<<20), "1.000Mi", "1024Ki")
+
+	// Three digits is the same as Scale.
+	for _, cls := range []UnitClass{UnitClassSI, UnitClassIEC} {
+		for _, v := range []float64{0, 1, .9995, 99.95, 12345, 1.5e-10, 9.995 * (1 << 10)} {
+			for _, v := range []float64{v, math.Nextafter(v, 0)} {
+				if got, want := ScaleSig(v, cls, 3), Scale(v, cls); got != want {
+					t.Errorf("for %v, ScaleSig with 3 digits got %s, Scale got %s", v, got, want)
+				}
+			}
+		}
+	}
+
+	// Values less than 1 are treated as 1.
+	if got, want := ScaleSig(12, UnitClassSI, 0), ScaleSig(12, UnitClassSI, 1); got != want {
+		t.Errorf("with 0 digits, got %s, want %s", got, want)
+	}
+}
+
+func TestScaleRounding(t *testing.T) {
+	format := func(val float64, prec int, rounding Rounding, want string) {
+		t.Helper()
+		s := Scaler{Prec: prec, Factor: 1, Rounding: rounding}
+		if got := s.Format(val); got != want {
+			t.Errorf("for %v with rounding %d, got %s, want %s", val, rounding, got, want)
+		}
+	}
+	// Exact halfway values.
+	format(2.5, 0, RoundHalfEven, "2")
+	format(2.5, 0, RoundHalfUp, "3")
+	format(-2.5, 0, RoundHalfUp, "-3")
+	format(3.5, 0, RoundHalfUp, "4")
+	format(0.125, 2, RoundHalfEven, "0.12")
+	format(0.125, 2, RoundHalfUp, "0.13")
+	// 1.005 is slightly less than halfway in binary.
+	format(1.005, 2, RoundHalfUp, "1.00")
+	// Special values.
+	format(0, 2, RoundHalfUp, "0.00")
+	format(math.Inf(1), 2, RoundHalfUp, "+Inf")
+	format(math.NaN(), 2, RoundHalfUp, "NaN")
+	format(1.25, -1, RoundHalfUp, "1.25")
+
+	var cls UnitClass
+	var sig int
+	test := func(num float64, wantPred, want, wantSucc string) {
+		t.Helper()
+		for i, v := range []float64{math.Nextafter(num, 0), num, math.Nextafter(num, math.Inf(1))} {
+			got := CommonScaleRounding([]float64{v}, cls, sig, RoundHalfUp).Format(v)
+			if want := []string{wantPred, want, wantSucc}[i]; got != want {
+				t.Errorf("for %v with %d digits, got %s, want %s", v, sig, got, want)
+			}
+		}
+	}
+
+	cls = UnitClassSI
+	sig = 3
+	// 999.5 is exactly on the crux. 99.95 is slightly more than
+	// the decimal threshold in binary.
+	test(999.5, "999", "1.00k", "1.00k")
+	test(99.95, "99.9", "100", "100")
+	// These values are slightly less than the decimal threshold
+	// in binary, so they round down, where half-even rounding
+	// switches precision early.
+	test(9.995, "9.99", "9.99", "10.0")
+	test(9995, "9.99k", "9.99k", "10.0k")
+	if got := Scale(9.995, cls); got != "10.0" {
+		t.Errorf("for 9.995 with half-even rounding, got %s, want 10.0", got)
+	}
+	// Halfway values away from a threshold.
+	sig = 2
+	test(2.25, "2.2", "2.3", "2.3")
+	test(-2.25, "-2.2", "-2.3", "-2.2")
+	test(995, "995", "995", "1.0k")
+	test(.995, "995m", "995m", "1.0")
+
+	cls = UnitClassIEC
+	sig = 3
+	test(9.995*(1<<10), "9.99Ki", "9.99Ki", "10.0Ki")
+	test(.9995*(1<<20), "1023Ki", "1.00Mi", "1.00Mi")
+
+	// Every value shows exactly the requested number of
+	// significant digits.
+	for _, cls := range []UnitClass{UnitClassSI, UnitClassIEC} {
+		for _, sig := range []int{1, 2, 3, 4} {
+			for exp := -6; exp <= 9; exp++ {
+				for _, m := range []float64{.95, .995, .9995, .99995} {
+					num := m * math.Pow(10, float64(exp))
+					for _, v := range []float64{math.Nextafter(num, 0), num, math.Nextafter(num, math.Inf(1))} {
+						got := CommonScaleRounding([]float64{v}, cls, sig, RoundHalfUp).Format(v)
+						digits := strings.TrimRight(got, "TGMkmµn/Ki")
+						if n := sigDigits(digits); n < sig || n > sig && strings.Contains(digits, ".") {
+							t.Errorf("for %v with %d digits, got %s with %d significant digits", v, sig, got, n)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseScaled(t *testing.T) {
+	check := func(s string, want float64) {
+		t.Helper()
+		got, err := ParseScaled(s)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", s, err)
+		} else if got != want {
+			t.Errorf("%s: got %v, want %v", s, got, want)
+		}
+	}
+	check("0", 0)
+	check("512", 512)
+	check("-1.5", -1.5)
+	check("1.5k", 1500)
+	check("2.00M", 2e6)
+	check("10m", .01)
+	check("3µ", 3e-6)
+	check("1Ki", 1024)
+	check("2Mi", 2<<20)
+	check("1/Ki", 1.0/1024)
+
+	// Round trip through Format.
+	for _, cls := range []UnitClass{UnitClassSI, UnitClassIEC} {
+		for _, v := range []float64{1, 1024, 123456789, 0.000125} {
+			s := CommonScale([]float64{v}, cls)
+			s.Prec = -1
+			if got, err := ParseScaled(s.Format(v)); err != nil || math.Abs(got-v) > v*1e-12 {
+				t.Errorf("%s: got %v, %v, want %v", s.Format(v), got, err, v)
+			}
+		}
+	}
+
+	for _, s := range []string{"", "k", "Ki", "1x", "1 k"} {
+		if _, err := ParseScaled(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
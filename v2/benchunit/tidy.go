@@ -7,6 +7,7 @@ package benchunit
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/perf/v2/benchfmt"
 )
@@ -16,7 +17,104 @@ type tidyEntry struct {
 	factor float64
 }
 
-var tidyCache sync.Map // unit string -> *tidyCache
+// tidyCache caches tidied units. Registering an alias replaces the
+// whole cache rather than clearing it, so a TidyUnit that raced with
+// the registration can only populate the discarded cache.
+var tidyCache atomic.Value // *sync.Map: unit string -> *tidyEntry
+
+func init() {
+	tidyCache.Store(new(sync.Map))
+}
+
+var (
+	aliasLock sync.Mutex
+	aliases   atomic.Value // map[string]string, copy on write
+)
+
+// RegisterAlias registers from as a synonym for the unit name to.
+// Tidy and TidyUnit rewrite each occurrence of from in a unit to to
+// before normalizing the unit. For example, after
+//
+//	RegisterAlias("nanoseconds", "ns")
+//
+// the unit "nanoseconds/op" tidies to "sec/op", just like "ns/op".
+//
+// Aliases apply to whole words of a unit, as delimited by "*", "/",
+// "-", and spaces, in both the numerator and denominator.
+func RegisterAlias(from, to string) {
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+
+	old, _ := aliases.Load().(map[string]string)
+	m := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[from] = to
+	setAliases(m)
+}
+
+// UnregisterAlias removes the alias from registered by RegisterAlias,
+// if any. Since aliases are global, this is mostly useful for tests
+// that need to undo their registrations.
+func UnregisterAlias(from string) {
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+
+	old, _ := aliases.Load().(map[string]string)
+	m := make(map[string]string, len(old))
+	for k, v := range old {
+		if k != from {
+			m[k] = v
+		}
+	}
+	setAliases(m)
+}
+
+// setAliases installs m as the alias map. The caller must hold
+// aliasLock.
+func setAliases(m map[string]string) {
+	aliases.Store(m)
+	// Previously tidied units may be affected by the change. The
+	// new aliases must be visible before the new cache, so any
+	// TidyUnit that sees the new cache also sees the new aliases.
+	tidyCache.Store(new(sync.Map))
+}
+
+func loadAliases() map[string]string {
+	m, _ := aliases.Load().(map[string]string)
+	return m
+}
+
+// CanonicalUnit returns unit with each registered alias replaced by
+// the unit name it is a synonym for. Unlike TidyUnit, it never
+// rescales the unit, so values in unit and in the returned unit are
+// directly comparable. This is useful for grouping values whose units
+// are spelled differently but mean the same thing, such as "bytes/op"
+// and "B/op" after
+//
+//	RegisterAlias("bytes", "B")
+func CanonicalUnit(unit string) string {
+	aliases := loadAliases()
+	if len(aliases) == 0 {
+		return unit
+	}
+	var buf strings.Builder
+	p := newParser(unit)
+	last := 0
+	for p.next() {
+		if alias, ok := aliases[p.tok]; ok {
+			buf.WriteString(unit[last:p.pos])
+			buf.WriteString(alias)
+			last = p.pos + len(p.tok)
+		}
+	}
+	if last == 0 {
+		return unit
+	}
+	buf.WriteString(unit[last:])
+	return buf.String()
+}
 
 // Tidy rewrites units and values in result to normalize them to base
 // units, specifically normalizing common pre-scaled units like "ns"
@@ -26,39 +124,65 @@ var tidyCache sync.Map // unit string -> *tidyCache
 func Tidy(result *benchfmt.Result) {
 	for i := range result.Values {
 		tidied, factor := TidyUnit(result.Values[i].Unit)
-		if factor != 1 {
-			result.Values[i] = benchfmt.Value{Value: result.Values[i].Value * factor, Unit: tidied}
+		if factor != 1 || tidied != result.Values[i].Unit {
+			result.Values[i] = benchfmt.Value{Value: result.Values[i].Value * factor, Unit: tidied, Err: result.Values[i].Err * factor}
 		}
 	}
 }
 
+// Value returns the measurement in result of the metric measured by
+// unit, converted to unit. If result doesn't have a value in exactly
+// unit, Value looks for a value in any unit that tidies to the same
+// unit as unit, such as "ns/op" for "sec/op" or vice versa. This way,
+// callers find a metric regardless of how it was scaled or spelled,
+// and whether or not result has been tidied.
+func Value(result *benchfmt.Result, unit string) (float64, bool) {
+	if val, ok := result.Value(unit); ok {
+		return val, true
+	}
+	tidied, factor := TidyUnit(unit)
+	for _, v := range result.Values {
+		vTidied, vFactor := TidyUnit(v.Unit)
+		if vTidied == tidied {
+			return v.Value * vFactor / factor, true
+		}
+	}
+	return 0, false
+}
+
 // TidyUnit returns the tidied version of unit and the multiplicative
 // factor to convert a value in unit "unit" to a value in unit
 // "tidied".
 func TidyUnit(unit string) (tidied string, factor float64) {
-	// Fast path for units from testing package.
-	switch unit {
-	case "ns/op":
-		return "sec/op", 1e-9
-	case "MB/s":
-		return "B/s", 1e6
-	case "B/op", "allocs/op":
-		return unit, 1
-	}
-	// Fast path for units with no normalization.
-	if !(strings.Contains(unit, "ns") || strings.Contains(unit, "MB")) {
-		return unit, 1
+	// The fast paths don't consider aliases, so we can only use
+	// them if there are no aliases.
+	if len(loadAliases()) == 0 {
+		// Fast path for units from testing package.
+		switch unit {
+		case "ns/op":
+			return "sec/op", 1e-9
+		case "MB/s":
+			return "B/s", 1e6
+		case "B/op", "allocs/op":
+			return unit, 1
+		}
+		// Fast path for units with no normalization.
+		if !(strings.Contains(unit, "ns") || strings.Contains(unit, "MB")) {
+			return unit, 1
+		}
 	}
 
-	// Check the cache.
-	if tc, ok := tidyCache.Load(unit); ok {
+	// Check the cache. This must load the cache before tidy
+	// loads the aliases. See setAliases.
+	cache := tidyCache.Load().(*sync.Map)
+	if tc, ok := cache.Load(unit); ok {
 		tc := tc.(*tidyEntry)
 		return tc.tidied, tc.factor
 	}
 
 	// Do the hard work and cache it.
 	tidied, factor = tidy(unit)
-	tidyCache.Store(unit, &tidyEntry{tidied, factor})
+	cache.Store(unit, &tidyEntry{tidied, factor})
 	return
 }
 
@@ -70,20 +194,28 @@ func tidy(unit string) (tidied string, factor float64) {
 
 	// The caller has handled the fast paths. Parse the unit.
 	factor = 1
+	aliases := loadAliases()
 	p := newParser(unit)
 	edits := make([]edit, 0, 4)
 	for p.next() {
-		if p.denom {
-			// Don't edit in the denominator.
-			continue
+		tok := p.tok
+		alias, isAlias := aliases[tok]
+		if isAlias {
+			tok = alias
+		}
+		if !p.denom {
+			// Don't normalize in the denominator.
+			switch tok {
+			case "ns":
+				tok = "sec"
+				factor /= 1e9
+			case "MB":
+				tok = "B"
+				factor *= 1e6
+			}
 		}
-		switch p.tok {
-		case "ns":
-			edits = append(edits, edit{p.pos, len("ns"), "sec"})
-			factor /= 1e9
-		case "MB":
-			edits = append(edits, edit{p.pos, len("MB"), "B"})
-			factor *= 1e6
+		if tok != p.tok {
+			edits = append(edits, edit{p.pos, len(p.tok), tok})
 		}
 	}
 	// Apply edits.
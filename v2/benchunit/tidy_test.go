@@ -4,7 +4,12 @@
 
 package benchunit
 
-import "testing"
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+)
 
 func TestTidy(t *testing.T) {
 	test := func(unit, tidied string, factor float64) {
@@ -27,3 +32,95 @@ func TestTidy(t *testing.T) {
 	test("MB*MB/s", "B*B/s", 1e6*1e6)
 	test("MB/MB", "B/MB", 1e6)
 }
+
+func TestTidyAlias(t *testing.T) {
+	RegisterAlias("nanoseconds", "ns")
+	RegisterAlias("megabytes", "MB")
+	defer UnregisterAlias("nanoseconds")
+	defer UnregisterAlias("megabytes")
+
+	test := func(unit, tidied string, factor float64) {
+		t.Helper()
+		got, gotFactor := TidyUnit(unit)
+		if got != tidied || gotFactor != factor {
+			t.Errorf("for %s, want *%f %s, got *%f %s", unit, factor, tidied, gotFactor, got)
+		}
+	}
+
+	// Aliased units tidy to the same canonical unit as their
+	// targets.
+	test("nanoseconds/op", "sec/op", 1e-9)
+	test("ns/op", "sec/op", 1e-9)
+	test("megabytes/s", "B/s", 1e6)
+	test("x-nanoseconds/op", "x-sec/op", 1e-9)
+	// Aliases apply in the denominator, but normalization does not.
+	test("op/nanoseconds", "op/ns", 1)
+	// Aliases only match whole words.
+	test("nanosecondsx/op", "nanosecondsx/op", 1)
+	// Unaliased units are unaffected.
+	test("B/op", "B/op", 1)
+	test("MB*MB/s", "B*B/s", 1e6*1e6)
+
+	// Tidy applies aliases to results, too.
+	res := &benchfmt.Result{Values: []benchfmt.Value{{Value: 2, Unit: "nanoseconds/op"}, {Value: 2, Unit: "ns/op"}}}
+	Tidy(res)
+	if res.Values[0] != res.Values[1] {
+		t.Errorf("want aliased values to tidy identically, got %v and %v", res.Values[0], res.Values[1])
+	}
+
+	// CanonicalUnit applies aliases without normalizing.
+	for unit, want := range map[string]string{
+		"nanoseconds/op":      "ns/op",
+		"op/nanoseconds":      "op/ns",
+		"megabytes*megabytes": "MB*MB",
+		"ns/op":               "ns/op",
+		"nanosecondsx/op":     "nanosecondsx/op",
+	} {
+		if got := CanonicalUnit(unit); got != want {
+			t.Errorf("for %s, want canonical unit %s, got %s", unit, want, got)
+		}
+	}
+}
+
+func TestUnregisterAlias(t *testing.T) {
+	RegisterAlias("nanos", "ns")
+	if got, _ := TidyUnit("nanos/op"); got != "sec/op" {
+		t.Errorf("with alias, want sec/op, got %s", got)
+	}
+	UnregisterAlias("nanos")
+	// This must not use the tidied unit cached under the alias.
+	if got, _ := TidyUnit("nanos/op"); got != "nanos/op" {
+		t.Errorf("after unregistering, want nanos/op, got %s", got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	check := func(res *benchfmt.Result, unit string, want float64) {
+		t.Helper()
+		got, ok := Value(res, unit)
+		if math.IsNaN(want) {
+			if ok {
+				t.Errorf("%s: want no value, got %v", unit, got)
+			}
+			return
+		}
+		if !ok || math.Abs(got-want) > 1e-9*math.Abs(want) {
+			t.Errorf("%s: want %v, got %v (ok=%v)", unit, want, got, ok)
+		}
+	}
+	res := &benchfmt.Result{Values: []benchfmt.Value{{Value: 42, Unit: "ns/op"}, {Value: 2, Unit: "MB/s"}}}
+	check(res, "ns/op", 42)
+	check(res, "sec/op", 42e-9)
+	check(res, "B/s", 2e6)
+	check(res, "B/op", math.NaN())
+
+	// After tidying, the original units are still found.
+	Tidy(res)
+	check(res, "ns/op", 42)
+	check(res, "sec/op", 42e-9)
+	check(res, "MB/s", 2)
+
+	// An exact match is preferred.
+	res = &benchfmt.Result{Values: []benchfmt.Value{{Value: 1, Unit: "sec/op"}, {Value: 42, Unit: "ns/op"}}}
+	check(res, "ns/op", 42)
+}
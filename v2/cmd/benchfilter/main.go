@@ -9,6 +9,9 @@
 // It supports the following query syntax:
 //
 // 	key:regexp    - Test if key matches regexp. Key and value can be quoted.
+// 	key:~regexp   - Test if key contains a match of regexp
+// 	key:@>=num    - Test if key is numerically >= num (also <, <=, >, ==, !=)
+// 	key:@since(d) - Test if key is a date at or after d (a date or an age like 30d)
 // 	key:(x y ...) - Test if key matches any of x, y, etc.
 // 	x y ...       - Test if x, y, etc. are all true
 // 	x AND y       - Same as x y
@@ -22,11 +25,28 @@
 // 	.fullname     - The full name of a benchmark (including configuration)
 // 	.unit         - The name of a unit for a particular metric
 // 	.file         - The name of the input file
+// 	.role         - "baseline" or "experiment" (with -roles)
+// 	.gomaxprocs   - The GOMAXPROCS of a benchmark (1 if not specified)
+// 	.parallel     - "true" if a benchmark ran with GOMAXPROCS > 1
+// 	.suspicious   - "true" if a benchmark ran for implausibly little time
+// 	.totaltime    - Total running time of a benchmark in seconds
 // 	/name-key     - Per-benchmark name configuration key
+// 	/*            - Any per-benchmark name configuration key
 // 	file-key      - File-level configuration key
 //
 // Regexp matching is anchored at the beginning and end, so a literal
-// string without any regexp operators must match exactly.
+// string without any regexp operators must match exactly. Prefixing
+// the regexp with "~" disables this anchoring, so it may match any
+// substring of the key's value.
+//
+// Numeric comparisons accept numbers with SI or binary prefixes, such as
+// "1.5k" or "1Ki", on either side, so "size:@>=1Ki" matches a size of
+// "2Mi" but not "512".
+//
+// Date comparisons accept dates such as "2024-01-01" or RFC 3339 times,
+// and ages such as "30d", "2w", or "12h", relative to the current time.
+// Values that are not dates never match, so "commit-date:@since(30d)"
+// matches commits from the last 30 days.
 //
 // For example, the query
 //
@@ -35,16 +55,52 @@
 // matches benchmarks called "Lookup" with file-level configuration
 // "goos" equal to "linux" and extracts just the "ns/op" and "B/op"
 // measurements.
+//
+// benchfilter can also rewrite the results that match the query. The
+// -tidy flag normalizes units (for example, converting "ns/op" to
+// "sec/op"), and the -set and -unset flags add, change, or remove
+// file-level configuration keys. These are applied after filtering,
+// so the query always sees the input as written.
+//
+// The -exclude-file flag reads a list of benchmark name patterns, one
+// per line, and excludes results whose base name or full name matches
+// any of them, in addition to applying the query. The full name is
+// matched without its GOMAXPROCS suffix, so "Sub/size=1" matches
+// "Sub/size=1-8". Patterns are regexps that are anchored at the
+// beginning and end, like query values. Blank lines and lines starting
+// with "#" are ignored. This is useful for maintaining a list of
+// known-flaky benchmarks.
+//
+// The -sort flag writes matching results sorted by a projection, such
+// as ".name@alpha,/size@numeric", rather than in input order. Keys
+// without an explicit order are sorted in order of first appearance,
+// which groups results without otherwise reordering them. Results
+// with the same projected values stay in input order, and results the
+// projection filters out are written last. This is useful for
+// normalizing results into a stable order that's easy to diff, but it
+// requires buffering all matching results in memory.
+//
+// The -roles flag sets the ".role" key of results from the first input
+// file to "baseline" and of results from the remaining input files to
+// "experiment". This makes it easy to select one side of an ad hoc
+// comparison, for example, with the query ".role:experiment".
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"unicode"
 
 	"golang.org/x/perf/v2/benchfmt"
 	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchunit"
 )
 
 func main() {
@@ -62,6 +118,9 @@ provided, it reads from stdin.
 It supports the following query syntax:
 
 	key:regexp    - Test if key matches regexp. Key and value can be quoted.
+	key:~regexp   - Test if key contains a match of regexp
+	key:@>=num    - Test if key is numerically >= num (also <, <=, >, ==, !=)
+	key:@since(d) - Test if key is a date at or after d (a date or an age like 30d)
 	key:(x y ...) - Test if key matches any of x, y, etc.
 	x y ...       - Test if x, y, etc. are all true
 	x AND y       - Same as x y
@@ -75,11 +134,28 @@ Keys may be one of the following:
 	.fullname     - The full name of a benchmark (including configuration)
 	.unit         - The name of a unit for a particular metric
 	.file         - The name of the input file
+	.role         - "baseline" or "experiment" (with -roles)
+	.gomaxprocs   - The GOMAXPROCS of a benchmark (1 if not specified)
+	.parallel     - "true" if a benchmark ran with GOMAXPROCS > 1
+	.suspicious   - "true" if a benchmark ran for implausibly little time
+	.totaltime    - Total running time of a benchmark in seconds
 	/name-key     - Per-benchmark name configuration key
+	/*            - Any per-benchmark name configuration key
 	file-key      - File-level configuration key
 
 Regexp matching is anchored at the beginning and end, so a literal
-string without any regexp operators must match exactly.
+string without any regexp operators must match exactly. Prefixing
+the regexp with "~" disables this anchoring, so it may match any
+substring of the key's value.
+
+Numeric comparisons accept numbers with SI or binary prefixes, such as
+"1.5k" or "1Ki", on either side, so "size:@>=1Ki" matches a size of
+"2Mi" but not "512".
+
+Date comparisons accept dates such as "2024-01-01" or RFC 3339 times,
+and ages such as "30d", "2w", or "12h", relative to the current time.
+Values that are not dates never match, so "commit-date:@since(30d)"
+matches commits from the last 30 days.
 
 For example, the query
 
@@ -88,9 +164,46 @@ For example, the query
 matches benchmarks called "Lookup" with file-level configuration
 "goos" equal to "linux" and extracts just the "ns/op" and "B/op"
 measurements.
+
+benchfilter can also rewrite the results that match the query. The
+-tidy flag normalizes units (for example, converting "ns/op" to
+"sec/op"), and the -set and -unset flags add, change, or remove
+file-level configuration keys. These are applied after filtering,
+so the query always sees the input as written.
+
+The -exclude-file flag reads a list of benchmark name patterns, one
+per line, and excludes results whose base name or full name matches
+any of them, in addition to applying the query. The full name is
+matched without its GOMAXPROCS suffix, so "Sub/size=1" matches
+"Sub/size=1-8". Patterns are regexps that are anchored at the
+beginning and end, like query values. Blank lines and lines starting
+with "#" are ignored. This is useful for maintaining a list of
+known-flaky benchmarks.
+
+The -sort flag writes matching results sorted by a projection, such
+as ".name@alpha,/size@numeric", rather than in input order. Keys
+without an explicit order are sorted in order of first appearance,
+which groups results without otherwise reordering them. Results
+with the same projected values stay in input order, and results the
+projection filters out are written last. This is useful for
+normalizing results into a stable order that's easy to diff, but it
+requires buffering all matching results in memory.
+
+The -roles flag sets the ".role" key of results from the first input
+file to "baseline" and of results from the remaining input files to
+"experiment". This makes it easy to select one side of an ad hoc
+comparison, for example, with the query ".role:experiment".
+
+Flags:
 `, os.Args[0])
 		flag.PrintDefaults()
 	}
+	var rw rewriter
+	rw.registerFlags(flag.CommandLine)
+	flagExclude := flag.String("exclude-file", "", "exclude benchmarks whose names match any pattern in `file`, one regexp per line")
+	flagSort := flag.String("sort", "", "buffer matching results and write them sorted by `projection`")
+	flagRoles := flag.Bool("roles", false, "set .role to baseline for results from the first input and experiment for the rest")
+	flagStats := flag.String("stats", "", "write a JSON summary of results read, filtered, and errors to `file` (\"-\" for stderr)")
 	flag.Parse()
 	if flag.NArg() < 1 {
 		flag.Usage()
@@ -104,28 +217,334 @@ measurements.
 		log.Fatal(err)
 	}
 
-	writer := benchfmt.NewWriter(os.Stdout)
+	var exclude *excludeList
+	if *flagExclude != "" {
+		exclude, err = readExcludeList(*flagExclude)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var writer resultWriter = benchfmt.NewWriter(os.Stdout)
+	var sorter *sortWriter
+	if *flagSort != "" {
+		var parser benchproc.ProjectionParser
+		sortBy, err := parser.Parse(*flagSort)
+		if err != nil {
+			log.Fatalf("-sort: %s", err)
+		}
+		sorter = newSortWriter(sortBy, benchfmt.NewWriter(os.Stdout))
+		writer = sorter
+	}
+
 	files := benchfmt.Files{Paths: flag.Args()[1:], AllowStdin: true}
+	if *flagRoles {
+		files.RoleKey = ".role"
+	}
+	var st stats
+	err = filterResults(&files, filter, exclude, &rw, writer, os.Stderr, &st)
+	if err == nil && sorter != nil {
+		err = sorter.flush()
+	}
+
+	if *flagStats != "" {
+		if err := writeStats(*flagStats, &st); err != nil {
+			log.Fatal("writing stats: ", err)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// stats summarizes the results processed by benchfilter.
+type stats struct {
+	Results     int `json:"results"`     // Results read without error
+	Matched     int `json:"matched"`     // Results written to the output
+	Filtered    int `json:"filtered"`    // Results removed by the filter
+	ParseErrors int `json:"parseErrors"` // Malformed results
+	IOErrors    int `json:"ioErrors"`    // Errors reading input files
+
+	// ErrorKinds counts errors by kind. For parse errors, this
+	// is the error message without the file location.
+	ErrorKinds map[string]int `json:"errorKinds"`
+}
+
+func (st *stats) addError(kind string) {
+	if st.ErrorKinds == nil {
+		st.ErrorKinds = make(map[string]int)
+	}
+	st.ErrorKinds[kind]++
+}
+
+// filterResults reads results from files, rewrites those that match
+// filter and are not excluded by exclude using rw, writes them to w,
+// and accumulates statistics in st. exclude may be nil.
+// Parse errors are non-fatal: filterResults prints them to warn and
+// keeps going. It returns an error if reading the input or writing
+// the output fails.
+func filterResults(files *benchfmt.Files, filter *benchproc.Filter, exclude *excludeList, rw *rewriter, w resultWriter, warn io.Writer, st *stats) error {
 	for files.Scan() {
 		res, err := files.Result()
 		if err != nil {
 			// Non-fatal result parse error. Warn
 			// but keep going.
-			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(warn, err)
+			st.ParseErrors++
+			if se, ok := err.(*benchfmt.SyntaxError); ok {
+				st.addError(se.Msg)
+			} else {
+				st.addError(err.Error())
+			}
 			continue
 		}
+		st.Results++
 
+		if exclude.match(res) {
+			st.Filtered++
+			continue
+		}
 		match := filter.Match(res)
 		if !match.Apply(res) {
+			st.Filtered++
 			continue
 		}
+		rw.apply(res)
 
-		err = writer.Write(res)
+		err = w.Write(res)
 		if err != nil {
-			log.Fatal("writing output: ", err)
+			return fmt.Errorf("writing output: %w", err)
 		}
+		st.Matched++
 	}
 	if err := files.Err(); err != nil {
-		log.Fatal(err)
+		st.IOErrors++
+		st.addError("I/O error")
+		return err
+	}
+	return nil
+}
+
+// A resultWriter writes benchmark results. It is implemented by
+// *benchfmt.Writer and *sortWriter.
+type resultWriter interface {
+	Write(res *benchfmt.Result) error
+}
+
+// A sortWriter buffers results and writes them sorted by a
+// projection when flushed. Results with equal projections are written
+// in the order they were buffered.
+type sortWriter struct {
+	by *benchproc.Schema
+	w  *benchfmt.Writer
+
+	groups map[benchproc.Config][]*benchfmt.Result
+	keys   []benchproc.Config
+	// rest is the results filtered out by the projection, which
+	// are written last.
+	rest []*benchfmt.Result
+}
+
+func newSortWriter(by *benchproc.Schema, w *benchfmt.Writer) *sortWriter {
+	return &sortWriter{by: by, w: w, groups: make(map[benchproc.Config][]*benchfmt.Result)}
+}
+
+// Write buffers a copy of res.
+func (s *sortWriter) Write(res *benchfmt.Result) error {
+	res = res.Clone()
+	cfg, ok := s.by.Project(res)
+	if !ok {
+		s.rest = append(s.rest, res)
+		return nil
+	}
+	if _, ok := s.groups[cfg]; !ok {
+		s.keys = append(s.keys, cfg)
+	}
+	s.groups[cfg] = append(s.groups[cfg], res)
+	return nil
+}
+
+// flush writes all buffered results in sorted order.
+func (s *sortWriter) flush() error {
+	benchproc.SortConfigs(s.keys)
+	for _, cfg := range s.keys {
+		for _, res := range s.groups[cfg] {
+			if err := s.w.Write(res); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+	}
+	for _, res := range s.rest {
+		if err := s.w.Write(res); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeStats writes st as JSON to the file at path, or to stderr if
+// path is "-".
+func writeStats(path string, st *stats) error {
+	data, err := json.MarshalIndent(st, "", "\t")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stderr.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+// An excludeList matches benchmark names against a list of patterns.
+type excludeList struct {
+	re *regexp.Regexp
+}
+
+// readExcludeList reads a list of benchmark name patterns from the
+// file at path.
+func readExcludeList(path string) (*excludeList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseExcludeList(path, string(data))
+}
+
+// parseExcludeList parses data, which is a list of regexps, one per
+// line, and compiles them into a single regexp. Blank lines and lines
+// starting with "#" are ignored. path is used only in errors.
+func parseExcludeList(path, data string) (*excludeList, error) {
+	var pats []string
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Check each pattern separately so we can report
+		// where an error is.
+		if _, err := regexp.Compile(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		pats = append(pats, "(?:"+line+")")
+	}
+	if len(pats) == 0 {
+		return &excludeList{}, nil
+	}
+	re := regexp.MustCompile("^(?:" + strings.Join(pats, "|") + ")$")
+	return &excludeList{re}, nil
+}
+
+// match returns whether the base name or full name of res matches
+// any pattern in l. The full name is matched without any GOMAXPROCS
+// suffix, since patterns shouldn't depend on the machine. A nil
+// excludeList matches nothing.
+func (l *excludeList) match(res *benchfmt.Result) bool {
+	if l == nil || l.re == nil {
+		return false
+	}
+	baseName, parts := benchfmt.NameParts(res.FullName)
+	if l.re.Match(baseName) {
+		return true
+	}
+	fullName := res.FullName
+	if n := len(parts); n > 0 && parts[n-1][0] == '-' {
+		// Strip GOMAXPROCS. NameParts returns sub-slices of
+		// FullName, so this is a prefix of it.
+		fullName = fullName[:len(fullName)-len(parts[n-1])]
+	}
+	return l.re.Match(fullName)
+}
+
+// A rewriter modifies results before they are written.
+type rewriter struct {
+	tidy  bool
+	set   []benchfmt.Config
+	unset []string
+}
+
+// registerFlags registers the -tidy, -set, and -unset flags that
+// configure rw in fs.
+func (rw *rewriter) registerFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&rw.tidy, "tidy", false, "normalize units of matching results")
+	fs.Var((*setFlag)(&rw.set), "set", "set file configuration `key=value` in matching results (may be repeated)")
+	fs.Var((*unsetFlag)(&rw.unset), "unset", "remove file configuration `key` from matching results (may be repeated)")
+}
+
+// apply rewrites res in place.
+func (rw *rewriter) apply(res *benchfmt.Result) {
+	if rw.tidy {
+		benchunit.Tidy(res)
+	}
+	for _, cfg := range rw.set {
+		res.SetFileConfig(cfg.Key, string(cfg.Value))
+	}
+	for _, key := range rw.unset {
+		res.SetFileConfig(key, "")
+	}
+}
+
+// setFlag is a flag.Value that accumulates "key=value" arguments.
+type setFlag []benchfmt.Config
+
+func (f *setFlag) String() string {
+	var buf strings.Builder
+	for i, cfg := range *f {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%s=%s", cfg.Key, cfg.Value)
+	}
+	return buf.String()
+}
+
+func (f *setFlag) Set(arg string) error {
+	i := strings.Index(arg, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected key=value, got %q", arg)
+	}
+	key, val := arg[:i], arg[i+1:]
+	if err := checkConfigKey(key); err != nil {
+		return err
+	}
+	if val == "" {
+		return fmt.Errorf("empty value for key %q; use -unset to remove a key", key)
+	}
+	if strings.ContainsAny(val, "\r\n") {
+		return fmt.Errorf("value for key %q contains a newline", key)
+	}
+	*f = append(*f, benchfmt.Config{Key: key, Value: []byte(val)})
+	return nil
+}
+
+// checkConfigKey returns an error if key can't be written as a file
+// configuration key. These are the rules benchfmt.Reader uses to
+// recognize a configuration line: a key starts with a lower case
+// letter and contains no spaces, upper case letters, or colons.
+func checkConfigKey(key string) error {
+	for i, r := range key {
+		if i == 0 && !unicode.IsLower(r) {
+			return fmt.Errorf("key %q must start with a lower case letter", key)
+		}
+		if unicode.IsSpace(r) || unicode.IsUpper(r) || r == ':' {
+			return fmt.Errorf("key %q must not contain spaces, upper case letters, or colons", key)
+		}
+	}
+	return nil
+}
+
+// unsetFlag is a flag.Value that accumulates key arguments.
+type unsetFlag []string
+
+func (f *unsetFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *unsetFlag) Set(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("empty key")
 	}
+	*f = append(*f, arg)
+	return nil
 }
@@ -0,0 +1,301 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+func TestFilterStats(t *testing.T) {
+	const input = `goos: linux
+BenchmarkOne 1 1 ns/op
+BenchmarkTwo 1 2 ns/op
+BenchmarkBad1 1
+BenchmarkOne 1 3 ns/op
+BenchmarkBad2 abc
+BenchmarkBad3 1 1
+BenchmarkThree 1 4 ns/op
+`
+	dir, err := ioutil.TempDir("", "benchfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte(input), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := benchproc.NewFilter(".name:One")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, warn := new(strings.Builder), new(strings.Builder)
+	files := benchfmt.Files{Paths: []string{path}}
+	var st stats
+	if err := filterResults(&files, filter, nil, &rewriter{}, benchfmt.NewWriter(out), warn, &st); err != nil {
+		t.Fatal(err)
+	}
+
+	want := stats{
+		Results:     4,
+		Matched:     2,
+		Filtered:    2,
+		ParseErrors: 3,
+		ErrorKinds: map[string]int{
+			"missing measurements":                    1,
+			"parsing iteration count: invalid syntax": 1,
+			"missing units":                           1,
+		},
+	}
+	if !reflect.DeepEqual(want, st) {
+		t.Errorf("want stats %+v, got %+v", want, st)
+	}
+	if n := strings.Count(warn.String(), "\n"); n != 3 {
+		t.Errorf("want 3 warnings, got:\n%s", warn)
+	}
+	if n := strings.Count(out.String(), "BenchmarkOne"); n != 2 {
+		t.Errorf("want 2 results in output, got:\n%s", out)
+	}
+
+	// Missing files are I/O errors.
+	files = benchfmt.Files{Paths: []string{filepath.Join(dir, "missing.txt")}}
+	st = stats{}
+	if err := filterResults(&files, filter, nil, &rewriter{}, benchfmt.NewWriter(out), warn, &st); err == nil {
+		t.Errorf("want error for missing file")
+	}
+	if st.IOErrors != 1 || st.ErrorKinds["I/O error"] != 1 {
+		t.Errorf("want 1 I/O error, got %+v", st)
+	}
+}
+
+func TestFilterRewrite(t *testing.T) {
+	const input = `goos: linux
+commit: abc
+BenchmarkOne 1 1000 ns/op 16 B/op
+goos: darwin
+BenchmarkTwo 1 2000 ns/op
+`
+	dir, err := ioutil.TempDir("", "benchfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte(input), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(query string, args ...string) string {
+		t.Helper()
+		fs := flag.NewFlagSet("benchfilter", flag.ContinueOnError)
+		var rw rewriter
+		rw.registerFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		filter, err := benchproc.NewFilter(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := benchfmt.Files{Paths: []string{path}}
+		out := new(strings.Builder)
+		var st stats
+		if err := filterResults(&files, filter, nil, &rw, benchfmt.NewWriter(out), out, &st); err != nil {
+			t.Fatal(err)
+		}
+		// Drop the .file key added by Files.
+		return strings.TrimPrefix(out.String(), ".file: "+path+"\n")
+	}
+
+	out := run("*", "-tidy")
+	if strings.Contains(out, "ns/op") || strings.Count(out, " sec/op") != 2 || !strings.Contains(out, " 16 B/op") {
+		t.Errorf("-tidy: want ns/op converted to sec/op, got:\n%s", out)
+	}
+	// The query sees the input as written, before -tidy.
+	out = run(".unit:ns/op", "-tidy")
+	if strings.Contains(out, "B/op") || strings.Count(out, " sec/op") != 2 {
+		t.Errorf(".unit:ns/op -tidy: want only sec/op, got:\n%s", out)
+	}
+
+	out = run(".name:One", "-set", "goarch=amd64", "-set", "goos=windows", "-unset", "commit")
+	want := `goos: windows
+goarch: amd64
+
+BenchmarkOne 1 1000 ns/op 16 B/op
+`
+	if out != want {
+		t.Errorf("-set/-unset: want:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestFilterSort(t *testing.T) {
+	const input = `BenchmarkB/size=10 1 1 ns/op
+BenchmarkA/size=2 1 2 ns/op
+BenchmarkB/size=2 1 3 ns/op
+BenchmarkA/size=10 1 4 ns/op
+BenchmarkA/size=2 1 5 ns/op
+BenchmarkC 1 6 ns/op
+BenchmarkA/size=2 1 7 ns/op
+`
+	dir, err := ioutil.TempDir("", "benchfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte(input), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(proj string) string {
+		t.Helper()
+		var parser benchproc.ProjectionParser
+		by, err := parser.Parse(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter, err := benchproc.NewFilter("*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := benchfmt.Files{Paths: []string{path}}
+		out := new(strings.Builder)
+		sorter := newSortWriter(by, benchfmt.NewWriter(out))
+		var st stats
+		if err := filterResults(&files, filter, nil, &rewriter{}, sorter, out, &st); err != nil {
+			t.Fatal(err)
+		}
+		if err := sorter.flush(); err != nil {
+			t.Fatal(err)
+		}
+		// Drop the .file key added by Files.
+		return strings.TrimPrefix(out.String(), ".file: "+path+"\n\n")
+	}
+
+	// Results with equal keys stay in input order.
+	got := run(".name@alpha,/size@numeric")
+	want := `BenchmarkA/size=2 1 2 ns/op
+BenchmarkA/size=2 1 5 ns/op
+BenchmarkA/size=2 1 7 ns/op
+BenchmarkA/size=10 1 4 ns/op
+BenchmarkB/size=2 1 3 ns/op
+BenchmarkB/size=10 1 1 ns/op
+BenchmarkC 1 6 ns/op
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	// Results filtered by the projection come last.
+	got = run("/size:(10 2)")
+	want = `BenchmarkB/size=10 1 1 ns/op
+BenchmarkA/size=10 1 4 ns/op
+BenchmarkA/size=2 1 2 ns/op
+BenchmarkB/size=2 1 3 ns/op
+BenchmarkA/size=2 1 5 ns/op
+BenchmarkA/size=2 1 7 ns/op
+BenchmarkC 1 6 ns/op
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestSetFlag(t *testing.T) {
+	var f setFlag
+	for _, bad := range []string{"", "key", "=value", "key=", ".file=x", "a b=c", "Goos=x", "a:b=c", "1a=x", "a=b\nc"} {
+		if err := f.Set(bad); err == nil {
+			t.Errorf("-set %q: want error", bad)
+		}
+	}
+	if err := f.Set("a=b=c"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 1 || f[0].Key != "a" || string(f[0].Value) != "b=c" {
+		t.Errorf("-set a=b=c: got %s", f.String())
+	}
+}
+
+func TestExcludeList(t *testing.T) {
+	const list = `# Known flaky benchmarks.
+Flaky
+
+  Sub/size=(1|2)
+# Also
+Pre.*
+`
+	exclude, err := parseExcludeList("list", list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"Flaky":         true,
+		"Flaky/size=1":  true, // Matches the base name
+		"NotFlaky":      false,
+		"Flakyish":      false,
+		"Sub/size=1":    true,
+		"Sub/size=3":    false,
+		"Prefix":        true,
+		"# Also":        false,
+		"Other":         false,
+		"Sub/size=1-16": true, // GOMAXPROCS is ignored
+		"Sub/size=3-16": false,
+	} {
+		res := &benchfmt.Result{FullName: []byte(name)}
+		if got := exclude.match(res); got != want {
+			t.Errorf("%s: want excluded %v, got %v", name, want, got)
+		}
+	}
+
+	// Comments and blank lines alone exclude nothing.
+	exclude, err = parseExcludeList("list", "# nothing\n\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exclude.match(&benchfmt.Result{FullName: []byte("")}) {
+		t.Errorf("empty list: want no match")
+	}
+
+	if _, err := parseExcludeList("list", "Good\nBad(\n"); err == nil || !strings.HasPrefix(err.Error(), "list:2: ") {
+		t.Errorf("want error at list:2, got %v", err)
+	}
+
+	// Exclusion combines with the query.
+	dir, err := ioutil.TempDir("", "benchfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+	if err := ioutil.WriteFile(path, []byte("BenchmarkFlaky 1 1 ns/op\nBenchmarkOK 1 1 ns/op 1 B/op\nBenchmarkPrefix 1 1 ns/op\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	exclude, _ = parseExcludeList("list", list)
+	filter, err := benchproc.NewFilter(".unit:ns/op")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := benchfmt.Files{Paths: []string{path}}
+	out := new(strings.Builder)
+	var st stats
+	if err := filterResults(&files, filter, exclude, &rewriter{}, benchfmt.NewWriter(out), out, &st); err != nil {
+		t.Fatal(err)
+	}
+	want := ".file: " + path + "\n\nBenchmarkOK 1 1 ns/op\n"
+	if out.String() != want || st.Filtered != 2 {
+		t.Errorf("want:\n%s\ngot:\n%s\nstats %+v", want, out, st)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+
+	"golang.org/x/perf/v2/benchunit"
+)
+
+// minLabelSpacing is the minimum room in pixels that a label needs in
+// compact mode. Labels with less room than this are omitted.
+const minLabelSpacing = labelFontHeight
+
+// formatValue formats val for a value label. In compact mode, if val
+// is scaled by a prefix, this omits the fractional part, since the
+// prefix already conveys the magnitude.
+func (s *Scales) formatValue(val float64, cls benchunit.UnitClass) string {
+	if !s.Compact {
+		return benchunit.Scale(val, cls)
+	}
+	scaler := benchunit.CommonScale([]float64{val}, cls)
+	if scaler.Prefix != "" {
+		scaler.Prec = 0
+	}
+	return scaler.Format(val)
+}
+
+// labelFits returns whether a label with size pixels of room should
+// be shown. This is always true unless in compact mode.
+func (s *Scales) labelFits(size float64) bool {
+	return !s.Compact || math.Abs(size) >= minLabelSpacing
+}
+
+// dropIntervalOverlaps is like removeIntervalOverlaps, but rather than
+// spreading out overlapping intervals, it omits any interval that
+// starts less than minLabelSpacing after the end of the previous
+// retained interval. It returns the retained intervals, sorted by
+// their midpoints.
+func dropIntervalOverlaps(ints []interval) []interval {
+	sort.Slice(ints, func(i, j int) bool {
+		return ints[i].mid() < ints[j].mid()
+	})
+	out := ints[:0]
+	for _, in := range ints {
+		if len(out) > 0 && in.mid()-out[len(out)-1].mid() < minLabelSpacing {
+			continue
+		}
+		out = append(out, in)
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestFormatValueCompact(t *testing.T) {
+	check := func(compact bool, val float64, want string) {
+		t.Helper()
+		s := Scales{Compact: compact}
+		if got := s.formatValue(val, benchunit.UnitClassSI); got != want {
+			t.Errorf("compact=%v, %v: got %s, want %s", compact, val, got, want)
+		}
+	}
+	check(false, 12345, "12.3k")
+	check(true, 12345, "12k")
+	check(true, 123456, "123k")
+	// With no prefix, keep the usual precision.
+	check(true, 1.5, "1.50")
+}
+
+func TestCompactLabels(t *testing.T) {
+	nc := newNameConfigs()
+	var phases OMap
+	for _, phase := range []struct {
+		name string
+		val  float64
+	}{{"a", 1000}, {"b", 1}, {"c", 999}} {
+		dist := benchstat.NewDistribution([]float64{phase.val}, benchstat.DistributionOptions{})
+		phases.Store(nc.new(phase.name), dist)
+	}
+
+	render := func(cell Cell, compact bool, width float64) string {
+		var ext Extents
+		cell.Extents(&ext)
+		scales := Scales{
+			Outer:      Box{0, width, 100, 0},
+			Colors:     map[benchproc.Config]color.Color{},
+			PhaseField: nc.s.Fields()[0],
+			Compact:    compact,
+		}
+		assignColors(scales.Colors, &ext.TopPhases, topPal)
+		assignColors(scales.Colors, &ext.OtherPhases, otherPal)
+		xOut := scale.Linear{Min: 0, Max: width}
+		yOut := scale.Linear{Min: 0, Max: 100}
+		scales.X = scale.QQ{Src: &ext.X, Dest: &xOut}
+		scales.Y = scale.QQ{Src: &ext.Y, Dest: &yOut}
+
+		var buf bytes.Buffer
+		cell.Render(&SVG{w: &buf}, &scales, nil, 0)
+		return buf.String()
+	}
+	check := func(svg, label string, want bool) {
+		t.Helper()
+		if got := strings.Contains(svg, ">"+label+"</text>"); got != want {
+			t.Errorf("label %s: got %v, want %v in:\n%s", label, got, want, svg)
+		}
+	}
+
+	stack := NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0]
+	svg := render(stack, false, 100)
+	check(svg, "1.00k (50%)", true)
+	check(svg, "1.00 (0%)", true)
+	check(svg, "2.00k", true)
+	// Phase "b" is far too short for a label in compact mode.
+	svg = render(stack, true, 100)
+	check(svg, "1k (50%)", true)
+	check(svg, "1.00 (0%)", false)
+	check(svg, "999 (50%)", true)
+	check(svg, "2k", true)
+
+	// Make the delta bars too narrow for their labels. The peak
+	// label must still render.
+	deltas := NewDeltaCells([]*OMap{&phases}, benchunit.UnitClassSI)[0]
+	svg = render(deltas, false, 20)
+	check(svg, "+1.00k", true)
+	check(svg, "-999", true)
+	check(svg, "1.00k", true)
+	svg = render(deltas, true, 20)
+	check(svg, "+1k", false)
+	check(svg, "-999", false)
+	check(svg, "+998", false)
+	check(svg, "1k", true)
+}
+
+func TestDropIntervalOverlaps(t *testing.T) {
+	ints := []interval{
+		{20, 30, "c"},
+		{0, 10, "a"},
+		{5, 15, "b"},
+		{40, 50, "d"},
+	}
+	var got []string
+	for _, in := range dropIntervalOverlaps(ints) {
+		got = append(got, in.data.(string))
+	}
+	if want := "a c d"; strings.Join(got, " ") != want {
+		t.Errorf("want %s, got %s", want, strings.Join(got, " "))
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// formatDates sets a compact display format for each field of cfgs
+// that uses the "date" sort order. The format shows only as much of
+// each date as is needed to tell the dates in cfgs apart: the month
+// and day if they are all in the same year, plus the time of day if
+// some day has more than one date, and so on, rather than the full
+// timestamp. The day is always shown, even if all dates are on the
+// same day, and a lone date is shown in full. Values that aren't
+// dates are displayed as they are.
+func formatDates(fields []benchproc.Field, cfgs []benchproc.Config) {
+	for _, field := range fields {
+		if field.OrderName() != "date" {
+			continue
+		}
+		var times []time.Time
+		for _, cfg := range cfgs {
+			if t, ok := benchproc.ParseDate(cfg.Get(field)); ok {
+				times = append(times, t)
+			}
+		}
+		layout := dateLayout(times)
+		field.SetFormat(func(val string) string {
+			if t, ok := benchproc.ParseDate(val); ok {
+				return t.Format(layout)
+			}
+			return val
+		})
+	}
+}
+
+// dateLayout returns the most compact layout that distinguishes the
+// dates of times and always includes the day. Equal times are
+// counted once. If the layout would still format distinct times the
+// same, for example, times within the same minute, it falls back to
+// including seconds, then to the full timestamp.
+func dateLayout(times []time.Time) string {
+	// Configs often share a date, for example, across rows, so
+	// consider each distinct time once.
+	var uniq []time.Time
+	seen := make(map[int64]bool)
+	for _, t := range times {
+		if !seen[t.UnixNano()] {
+			seen[t.UnixNano()] = true
+			uniq = append(uniq, t)
+		}
+	}
+	if len(uniq) <= 1 {
+		// With nothing to compare against, show the whole date.
+		return "2006-01-02"
+	}
+
+	sameYear, dayTwice := true, false
+	days := make(map[string]bool)
+	for _, t := range uniq {
+		day := t.Format("2006-01-02")
+		if days[day] {
+			dayTwice = true
+		}
+		days[day] = true
+		if t.Year() != uniq[0].Year() {
+			sameYear = false
+		}
+	}
+
+	var layout string
+	switch {
+	case sameYear && dayTwice:
+		layout = "Jan 2 15:04"
+	case sameYear:
+		layout = "Jan 2"
+	case dayTwice:
+		layout = "2006-01-02 15:04"
+	default:
+		layout = "2006-01-02"
+	}
+	if !dayTwice {
+		return layout
+	}
+	for _, l := range []string{layout, layout + ":05", time.RFC3339Nano} {
+		if distinctFormats(uniq, l) {
+			return l
+		}
+	}
+	return time.RFC3339Nano
+}
+
+// distinctFormats returns whether layout formats each of times
+// differently.
+func distinctFormats(times []time.Time, layout string) bool {
+	labels := make(map[string]bool)
+	for _, t := range times {
+		label := t.Format(layout)
+		if labels[label] {
+			return false
+		}
+		labels[label] = true
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+func TestFormatDates(t *testing.T) {
+	header := func(proj string, dates ...string) string {
+		t.Helper()
+		var p benchproc.ProjectionParser
+		colBy, err := p.Parse(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cols []benchproc.Config
+		for _, date := range dates {
+			res := &benchfmt.Result{FullName: []byte("Name")}
+			res.SetFileConfig("commit-date", date)
+			cfg, _ := colBy.Project(res)
+			cols = append(cols, cfg)
+		}
+		benchproc.SortConfigs(cols)
+		formatDates(colBy.Fields(), cols)
+		var labels []string
+		for _, cell := range benchproc.NewConfigHeader(cols)[0] {
+			labels = append(labels, cell.Value)
+		}
+		return strings.Join(labels, "|")
+	}
+	check := func(got, want string) {
+		t.Helper()
+		if got != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	}
+
+	check(header("commit-date@date", "2020-11-05T14:23:01-05:00", "2020-11-03T09:00:00-05:00", "2020-12-25T00:00:00Z"),
+		"Nov 3|Nov 5|Dec 25")
+	check(header("commit-date@date", "2020-11-05T14:23:01-05:00", "2021-01-02T09:00:00Z"),
+		"2020-11-05|2021-01-02")
+	check(header("commit-date@date", "2020-11-05T14:23:01-05:00", "2020-11-05T09:00:00-05:00"),
+		"Nov 5 09:00|Nov 5 14:23")
+	// Times within the same minute fall back to seconds.
+	check(header("commit-date@date", "2020-11-05T14:23:01Z", "2020-11-05T14:23:30Z"),
+		"Nov 5 14:23:01|Nov 5 14:23:30")
+	// A time repeated across configs, such as in several rows,
+	// counts once.
+	t1, _ := benchproc.ParseDate("2020-11-05T14:23:01Z")
+	t2, _ := benchproc.ParseDate("2020-11-06T09:00:00Z")
+	if got := dateLayout([]time.Time{t1, t1, t2}); got != "Jan 2" {
+		t.Errorf("repeated time: want layout Jan 2, got %s", got)
+	}
+	// A lone date is shown in full.
+	check(header("commit-date@date", "2020-11-05T14:23:01-05:00"),
+		"2020-11-05")
+	check(header("commit-date@date", "2020-11-05T14:23:01Z", "2020-11-05T09:00:00Z", "2020-11-07T09:00:00Z"),
+		"Nov 5 09:00|Nov 5 14:23|Nov 7 09:00")
+	// Values that aren't dates are left alone.
+	check(header("commit-date@date", "2020-11-05T14:23:01Z", "2020-11-06T14:23:01Z", "unknown"),
+		"Nov 5|Nov 6|unknown")
+	// Without the date order, dates are shown as they are.
+	check(header("commit-date", "2020-11-05T14:23:01-05:00", "2020-11-03T09:00:00-05:00"),
+		"2020-11-05T14:23:01-05:00|2020-11-03T09:00:00-05:00")
+}
@@ -23,6 +23,7 @@ type DeltaCell struct {
 	phases []benchproc.Config
 	info   map[benchproc.Config]deltaInfo
 	layout map[benchproc.Config]deltaBar
+	stats  cellStats
 
 	maxVal float64
 }
@@ -35,6 +36,9 @@ type deltaRow struct {
 
 type deltaInfo struct {
 	start, end, delta float64
+
+	// dist is the distribution of the metric at end.
+	dist *benchstat.Distribution
 }
 
 type deltaBar struct {
@@ -54,7 +58,7 @@ func NewDeltaCells(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
 		var cellMax float64
 		for _, phaseCfg := range phases.Keys {
 			dist := phases.Load(phaseCfg).(*benchstat.Distribution)
-			info[phaseCfg] = deltaInfo{prev, dist.Center, dist.Center - prev}
+			info[phaseCfg] = deltaInfo{prev, dist.Center, dist.Center - prev, dist}
 			prev = dist.Center
 			cellMax = math.Max(cellMax, math.Abs(dist.Center))
 		}
@@ -64,6 +68,7 @@ func NewDeltaCells(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
 			unitClass: unitClass,
 			phases:    phases.Keys,
 			info:      info,
+			stats:     newCellStats(phases),
 			maxVal:    cellMax,
 		}
 
@@ -118,7 +123,18 @@ func (c *DeltaCell) Extents(ext *Extents) {
 	}
 }
 
+func (c *DeltaCell) manifest(phaseField benchproc.Field) ([]ManifestPhase, cellStats) {
+	var phases []ManifestPhase
+	for _, phaseCfg := range c.phases {
+		phases = append(phases, ManifestPhase{phaseCfg.Get(phaseField), c.info[phaseCfg].end})
+	}
+	return phases, c.stats
+}
+
 func (c *DeltaCell) Render(svg *SVG, scales *Scales, prev0 Cell, prevRight float64) {
+	renderCellStart(svg, scales, "peak "+benchunit.Scale(c.maxVal, c.unitClass), c.stats)
+	defer renderCellEnd(svg)
+
 	x, y := scales.X, scales.Y
 	prev, _ := prev0.(*DeltaCell)
 
@@ -199,7 +215,11 @@ func (c *DeltaCell) Render(svg *SVG, scales *Scales, prev0 Cell, prevRight float
 		fmt.Fprintf(svg, `  <path d="%s" fill="none" stroke="%s" stroke-width="2px" stroke-opacity="0.5" />`+"\n", path, bar.fill)
 	}
 	if len(cross) != 0 {
-		removeIntervalOverlaps(cross)
+		if scales.Compact {
+			cross = dropIntervalOverlaps(cross)
+		} else {
+			removeIntervalOverlaps(cross)
+		}
 		x := mid(prevRight, scales.Outer.Left)
 		for _, int := range cross {
 			info := int.data.(crossInfo)
@@ -216,12 +236,9 @@ func (c *DeltaCell) Render(svg *SVG, scales *Scales, prev0 Cell, prevRight float
 		info := c.info[phaseCfg]
 		bar := layout[phaseCfg]
 
-		deltaLabel := benchunit.Scale(info.delta, c.unitClass)
-		if !strings.HasPrefix(deltaLabel, "-") {
-			// Make it clearer this is a delta by always
-			// putting a + or -.
-			deltaLabel = "+" + deltaLabel
-		}
+		// Make it clearer these are deltas by always putting
+		// a + or -.
+		deltaLabel := signed(benchunit.Scale(info.delta, c.unitClass))
 		barLabel := fmt.Sprintf("%s (%s)", phaseCfg.Get(scales.PhaseField), deltaLabel)
 
 		path := svgPathRect(bar.l, bar.t, bar.r, bar.b)
@@ -230,17 +247,23 @@ func (c *DeltaCell) Render(svg *SVG, scales *Scales, prev0 Cell, prevRight float
 		} else {
 			fmt.Fprintf(svg, `  <path d="%s" fill="%s"><title>%s</title></path>`+"\n", path, bar.fill, barLabel)
 		}
+		renderErrorBar(svg, scales, mid(bar.l, bar.r), info.end, info.dist, c.unitClass)
 
-		// Show delta at the end of the bar
+		// Show delta at the end of the bar. The label is rotated,
+		// so it needs room across the bar.
+		if !scales.labelFits(bar.r - bar.l) {
+			continue
+		}
 		ly, anchor := bar.b+2, "end"
 		if bar.neg {
 			ly, anchor = bar.t-2, "start"
 		}
-		fmt.Fprintf(svg, `  <text transform="translate(%f %f) rotate(-90)" font-size="%d" text-anchor="%s" dominant-baseline="mathematical">%s</text>`+"\n", mid(bar.l, bar.r), ly, labelFontSize, anchor, deltaLabel)
+		fmt.Fprintf(svg, `  <text transform="translate(%f %f) rotate(-90)" font-size="%d" text-anchor="%s" dominant-baseline="mathematical">%s</text>`+"\n", mid(bar.l, bar.r), ly, labelFontSize, anchor, signed(scales.formatValue(info.delta, c.unitClass)))
 	}
 
-	// Show the peak at the very bottom.
-	label := benchunit.Scale(c.maxVal, c.unitClass)
+	// Show the peak at the very bottom. This is always shown, even
+	// in compact mode.
+	label := scales.formatValue(c.maxVal, c.unitClass)
 	totalY := scales.Outer.Bottom - labelFontHeight + labelFontSize
 	fmt.Fprintf(svg, `  <text x="%f" y="%f" font-size="%d" text-anchor="middle">%s</text>`+"\n", mid(scales.Outer.Left, scales.Outer.Right), totalY, labelFontSize, label)
 	if prev != nil {
@@ -249,6 +272,15 @@ func (c *DeltaCell) Render(svg *SVG, scales *Scales, prev0 Cell, prevRight float
 	}
 }
 
+// signed prefixes label with "+" if it doesn't already start with
+// "-".
+func signed(label string) string {
+	if !strings.HasPrefix(label, "-") {
+		return "+" + label
+	}
+	return label
+}
+
 func (c *DeltaCell) RenderKey(svg *SVG, x float64, lastScales *Scales) (right, bot float64) {
 	y := lastScales.Y
 	lastRight := lastScales.Outer.Right
@@ -0,0 +1,41 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+// errorBarCap is the half-width of the caps at the ends of an error
+// bar.
+const errorBarCap = 3
+
+// renderErrorBar draws an error bar at x showing the confidence
+// interval of the median of dist at confidence level
+// scales.ErrorBars. at is the value on the Y scale where the cell
+// drew the center of dist, so the error bar spans the interval offset
+// to at. If error bars are disabled or dist has too few values for a
+// confidence interval, it draws nothing. Since the interval is of the
+// median, it also draws nothing if dist's center is another
+// reduction.
+func renderErrorBar(svg *SVG, scales *Scales, x, at float64, dist *benchstat.Distribution, unitClass benchunit.UnitClass) {
+	if !(scales.ErrorBars > 0) || dist == nil || dist.Reduction() != benchstat.ReduceMedian {
+		return
+	}
+	lo, hi := dist.MedianCI(scales.ErrorBars)
+	if math.IsNaN(lo) || math.IsNaN(hi) {
+		return
+	}
+	y1, y2 := scales.Y.Map(at+lo-dist.Center), scales.Y.Map(at+hi-dist.Center)
+	path := fmt.Sprintf("M%f %fV%fM%f %fH%fM%f %fH%f",
+		x, y1, y2,
+		x-errorBarCap, y1, x+errorBarCap,
+		x-errorBarCap, y2, x+errorBarCap)
+	fmt.Fprintf(svg, `  <path d="%s" fill="none" stroke="black" stroke-width="1px"><title>%.0f%% CI [%s, %s]</title></path>`+"\n", path, 100*scales.ErrorBars, benchunit.Scale(lo, unitClass), benchunit.Scale(hi, unitClass))
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"regexp"
+	"testing"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestErrorBars(t *testing.T) {
+	nc := newNameConfigs()
+	// Phase a has a 95% confidence interval of [2, 9] around its
+	// median of 5.5. Phase b has too few values for one.
+	var phases OMap
+	phases.Store(nc.new("a"), benchstat.NewDistribution([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, benchstat.DistributionOptions{}))
+	phases.Store(nc.new("b"), benchstat.NewDistribution([]float64{20, 20}, benchstat.DistributionOptions{}))
+
+	errorBarRe := regexp.MustCompile(`<path d="([^"]*)" fill="none" stroke="black" stroke-width="1px"><title>([^<]*)</title>`)
+	render := func(cell Cell, errorBars float64) [][]string {
+		var ext Extents
+		cell.Extents(&ext)
+		scales := Scales{
+			Colors:     map[benchproc.Config]color.Color{},
+			PhaseField: nc.s.Fields()[0],
+			ErrorBars:  errorBars,
+		}
+		assignColors(scales.Colors, &ext.TopPhases, topPal)
+		assignColors(scales.Colors, &ext.OtherPhases, otherPal)
+		// Map each unit of value to 10 units of output.
+		xOut := scale.Linear{Min: 0, Max: 100 * ext.X.Max}
+		yOut := scale.Linear{Min: 0, Max: 10 * ext.Y.Max}
+		scales.X = scale.QQ{Src: &ext.X, Dest: &xOut}
+		scales.Y = scale.QQ{Src: &ext.Y, Dest: &yOut}
+		var buf bytes.Buffer
+		cell.Render(&SVG{w: &buf}, &scales, nil, 0)
+		return errorBarRe.FindAllStringSubmatch(buf.String(), -1)
+	}
+	check := func(bars [][]string, x float64) {
+		t.Helper()
+		if len(bars) != 1 {
+			t.Fatalf("want 1 error bar, got %d: %v", len(bars), bars)
+		}
+		// Phase a starts at 0, so its interval spans 2 to 9.
+		want := fmt.Sprintf("M%f %fV%fM%f %fH%fM%f %fH%f", x, 20.0, 90.0, x-errorBarCap, 20.0, x+errorBarCap, x-errorBarCap, 90.0, x+errorBarCap)
+		if bars[0][1] != want {
+			t.Errorf("want error bar %s, got %s", want, bars[0][1])
+		}
+		if want := "95% CI [2.00, 9.00]"; bars[0][2] != want {
+			t.Errorf("want title %s, got %s", want, bars[0][2])
+		}
+	}
+
+	stack := NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0]
+	check(render(stack, 0.95), 50)
+	if bars := render(stack, 0); len(bars) != 0 {
+		t.Errorf("want no error bars when disabled, got %v", bars)
+	}
+
+	// DeltaCells draw the error bar at the end of each bar, which
+	// is the absolute value of the phase.
+	delta := NewDeltaCells([]*OMap{&phases}, benchunit.UnitClassSI)[0]
+	check(render(delta, 0.95), 50)
+
+	// The interval is of the median, so it isn't drawn for
+	// other reductions.
+	var means OMap
+	means.Store(nc.new("a"), benchstat.NewDistribution([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, benchstat.DistributionOptions{Center: benchstat.ReduceMean}))
+	stack = NewStacks([]*OMap{&means}, benchunit.UnitClassSI, PhaseOrderInput)[0]
+	if bars := render(stack, 0.95); len(bars) != 0 {
+		t.Errorf("want no error bars for a mean, got %v", bars)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// focusWidth and focusHeight are the size of the plot area of a
+// focused cell.
+const (
+	focusWidth  = 600
+	focusHeight = 800
+)
+
+// parseFocus parses a -focus argument of the form "row,col", where
+// row and col are 0-based indexes into the grid.
+func parseFocus(arg string) (row, col int, err error) {
+	i := strings.Index(arg, ",")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("expected row,col, got %q", arg)
+	}
+	row, err1 := strconv.Atoi(arg[:i])
+	col, err2 := strconv.Atoi(arg[i+1:])
+	if err1 != nil || err2 != nil || row < 0 || col < 0 {
+		return 0, 0, fmt.Errorf("expected row,col, got %q", arg)
+	}
+	return row, col, nil
+}
+
+// renderFocus renders cell by itself, scaled to fill a focusWidth by
+// focusHeight plot area, followed by its key. Unlike in the grid,
+// all labels are shown. label is the full configuration of the cell,
+// which is rendered as a heading above it. row is all of the cells in
+// cell's grid row; phases are colored by the whole row, so they get
+// the same colors as in the grid. renderFocus returns the right and
+// bottom edges of everything it rendered.
+func renderFocus(svg *SVG, cell Cell, row []Cell, label string, phaseField benchproc.Field) (right, bot float64) {
+	// Heading.
+	lines := strings.Split(label, "\n")
+	for i, line := range lines {
+		var buf strings.Builder
+		xml.EscapeText(&buf, []byte(line))
+		fmt.Fprintf(svg, `  <text x="0" y="%f" font-size="%d">%s</text>`+"\n", float64(i)*keyFontHeight+keyFontSize, keyFontSize, buf.String())
+	}
+	top := float64(len(lines)) * keyFontHeight
+
+	var ext Extents
+	cell.Extents(&ext)
+	scales := Scales{
+		Outer:      Box{top, focusWidth, top + focusHeight, 0},
+		Margins:    ext.Margins,
+		Colors:     make(map[benchproc.Config]color.Color),
+		PhaseField: phaseField,
+		Label:      label,
+	}
+	var rowExt Extents
+	for _, c := range row {
+		c.Extents(&rowExt)
+	}
+	assignColors(scales.Colors, &rowExt.TopPhases, topPal)
+	assignColors(scales.Colors, &rowExt.OtherPhases, otherPal)
+	xOut := scale.Linear{Min: ext.Margins.Left, Max: focusWidth - ext.Margins.Right}
+	yOut := scale.Linear{Min: top + ext.Margins.Top, Max: top + focusHeight - ext.Margins.Bottom}
+	scales.X = scale.QQ{Src: &ext.X, Dest: &xOut}
+	scales.X2 = scale.QQ{Src: &ext.X2, Dest: &xOut}
+	scales.Y = scale.QQ{Src: &ext.Y, Dest: &yOut}
+	cell.Render(svg, &scales, nil, 0)
+
+	// Leave the same gap before the key as between grid columns.
+	const keyGap = 30
+	right, bot = cell.RenderKey(svg, focusWidth+keyGap, &scales)
+	if right < focusWidth {
+		right = focusWidth
+	}
+	if bot < scales.Outer.Bottom {
+		bot = scales.Outer.Bottom
+	}
+	return right, bot
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestParseFocus(t *testing.T) {
+	row, col, err := parseFocus("2,13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row != 2 || col != 13 {
+		t.Errorf("want 2,13, got %d,%d", row, col)
+	}
+	for _, arg := range []string{"", "1", "1,", ",1", "a,1", "-1,0"} {
+		if _, _, err := parseFocus(arg); err == nil {
+			t.Errorf("%q: want error", arg)
+		}
+	}
+}
+
+func TestRenderFocus(t *testing.T) {
+	nc := newNameConfigs()
+	var cells []*OMap
+	for _, scale := range []float64{1, 10} {
+		var phases OMap
+		for i, phase := range []string{"a", "b"} {
+			dist := benchstat.NewDistribution([]float64{scale * float64(i+1)}, benchstat.DistributionOptions{})
+			phases.Store(nc.new(phase), dist)
+		}
+		cells = append(cells, &phases)
+	}
+	stacks := NewStacks(cells, benchunit.UnitClassSI, PhaseOrderInput)
+
+	// Focus on the smaller cell. It should be scaled to the
+	// full canvas on its own, not relative to its row.
+	var buf bytes.Buffer
+	right, bot := renderFocus(&SVG{w: &buf}, stacks[0], stacks, "row:x\ncol:y<1>", nc.s.Fields()[0])
+	svg := buf.String()
+
+	if n := strings.Count(svg, "<g><title>"); n != 1 {
+		t.Errorf("want 1 cell, got %d:\n%s", n, svg)
+	}
+	for _, want := range []string{">row:x</text>", ">col:y&lt;1&gt;</text>", ">a</text>", ">b</text>"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("want %q in output:\n%s", want, svg)
+		}
+	}
+
+	// Find the extent of the phase rectangles.
+	top := 2 * float64(keyFontHeight)
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	re := regexp.MustCompile(`<path d="(M[^"]*z)" fill=`)
+	for _, m := range re.FindAllStringSubmatch(svg, -1) {
+		var x1, y1, x2, y2, x3 float64
+		if _, err := fmt.Sscanf(m[1], "M%f %fH%fV%fH%fz", &x1, &y1, &x2, &y2, &x3); err != nil {
+			t.Fatalf("parsing path %q: %s", m[1], err)
+		}
+		minX, maxX = math.Min(minX, x1), math.Max(maxX, x2)
+		minY, maxY = math.Min(minY, math.Min(y1, y2)), math.Max(maxY, math.Max(y1, y2))
+	}
+	approx := func(what string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-6 {
+			t.Errorf("%s: want %v, got %v", what, want, got)
+		}
+	}
+	approx("left", minX, 0)
+	approx("right", maxX, focusWidth)
+	approx("top", minY, top)
+	approx("bottom", maxY, top+focusHeight-labelFontHeight)
+
+	if right <= focusWidth {
+		t.Errorf("want key right of the cell, got right edge %v", right)
+	}
+	approx("image bottom", bot, top+focusHeight)
+}
+
+func TestRenderFocusColors(t *testing.T) {
+	// Phase b is the only phase of the first cell, but shares
+	// the row with phase a. It should get the same color whichever
+	// cell is focused.
+	nc := newNameConfigs()
+	var cells []*OMap
+	for _, phases := range [][]string{{"b"}, {"a", "b"}} {
+		var m OMap
+		for _, phase := range phases {
+			m.Store(nc.new(phase), benchstat.NewDistribution([]float64{1}, benchstat.DistributionOptions{}))
+		}
+		cells = append(cells, &m)
+	}
+	stacks := NewStacks(cells, benchunit.UnitClassSI, PhaseOrderInput)
+
+	re := regexp.MustCompile(`fill="([^"]*)"><title>b `)
+	fill := func(cell Cell) string {
+		t.Helper()
+		var buf bytes.Buffer
+		renderFocus(&SVG{w: &buf}, cell, stacks, "row:x", nc.s.Fields()[0])
+		m := re.FindStringSubmatch(buf.String())
+		if m == nil {
+			t.Fatalf("no phase b in output:\n%s", buf.String())
+		}
+		return m[1]
+	}
+	if c0, c1 := fill(stacks[0]), fill(stacks[1]); c0 != c1 {
+		t.Errorf("want phase b to have the same color in both cells, got %s and %s", c0, c1)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// A cellKey identifies a cell in the grid.
+type cellKey struct {
+	row benchproc.Config
+	col benchproc.Config
+}
+
+// A Grid is a layout of cells in rows and columns.
+type Grid struct {
+	Rows, Cols []benchproc.Config
+	Cells      map[cellKey]Cell
+
+	// X and Y return the left and right edges of column col and
+	// the top and bottom edges of row row.
+	X func(col int) (left, right float64)
+	Y func(row int) (top, bottom float64)
+
+	PhaseField benchproc.Field
+	Compact    bool
+
+	// Heatmap tints each cell by how its total compares to the
+	// cell in the Baseline column of the same row. Baseline is
+	// only used by Heatmap and BaselineDelta, and is ignored if
+	// it is out of range.
+	Heatmap  bool
+	Baseline int
+
+	// BaselineDelta labels each top phase of each cell with its
+	// change from the same phase in the Baseline column.
+	BaselineDelta bool
+
+	// OneKey renders a single key for the whole grid, instead of
+	// a key for each row. Phases are colored consistently across
+	// rows so they can share this key.
+	OneKey bool
+
+	// Summary indicates that the last column in Cols is a
+	// synthetic column summarizing the others, such as from
+	// geomeanPhases. Its Config is the zero Config. It isn't
+	// connected to the column before it, since it isn't part of
+	// the sequence of columns.
+	Summary bool
+
+	// MaxCV, if positive, de-emphasizes cells where any phase has
+	// a coefficient of variation above MaxCV. See Scales.MaxCV.
+	MaxCV float64
+
+	// ErrorBars, if positive, draws error bars at this confidence
+	// level. See Scales.ErrorBars.
+	ErrorBars float64
+
+	// Manifest, if non-nil, records each rendered cell.
+	Manifest *Manifest
+}
+
+// Render renders the cells of g and their keys and returns the right
+// and bottom edges of everything it rendered.
+func (g *Grid) Render(svg *SVG) (maxRight, maxBot float64) {
+	_, maxRight = g.X(len(g.Cols) - 1)
+	_, maxBot = g.Y(len(g.Rows) - 1)
+
+	var gridColors map[benchproc.Config]color.Color
+	var gridPhases []benchproc.Config
+	if g.OneKey {
+		gridColors, gridPhases = g.colorPhases()
+	}
+
+	// Cell rows
+	for rowI, rowCfg := range g.Rows {
+		top, bot := g.Y(rowI)
+		if bot > maxBot {
+			maxBot = bot
+		}
+
+		// Construct scalers for this row.
+		var ext Extents
+		var scales Scales
+		for _, colCfg := range g.Cols {
+			cell, ok := g.Cells[cellKey{rowCfg, colCfg}]
+			if !ok {
+				continue
+			}
+			cell.Extents(&ext)
+		}
+		scales.Margins = ext.Margins
+		scales.Outer.Top = top
+		scales.Outer.Bottom = bot
+		yOut := scale.Linear{Min: top + ext.Margins.Top, Max: bot - ext.Margins.Bottom}
+		scales.Y = scale.QQ{&ext.Y, &yOut}
+		scales.PhaseField = g.PhaseField
+		scales.Compact = g.Compact
+		scales.MaxCV = g.MaxCV
+		scales.ErrorBars = g.ErrorBars
+
+		// Color phases.
+		if gridColors != nil {
+			scales.Colors = gridColors
+		} else {
+			scales.Colors = make(map[benchproc.Config]color.Color)
+			assignColors(scales.Colors, &ext.TopPhases, topPal)
+			assignColors(scales.Colors, &ext.OtherPhases, otherPal)
+		}
+
+		// Render cells.
+		var baseCell Cell
+		haveBase := false
+		if (g.Heatmap || g.BaselineDelta) && 0 <= g.Baseline && g.Baseline < len(g.Cols) {
+			baseCell, haveBase = g.Cells[cellKey{rowCfg, g.Cols[g.Baseline]}]
+		}
+		if g.BaselineDelta && haveBase {
+			scales.Baseline = baseCell
+		}
+		var prev Cell
+		var prevRight float64
+		for i, colCfg := range g.Cols {
+			cell, ok := g.Cells[cellKey{rowCfg, colCfg}]
+			if !ok {
+				continue
+			}
+
+			l, r := g.X(i)
+			scales.Outer.Left = l
+			scales.Outer.Right = r
+			if g.Heatmap && haveBase {
+				renderHeatmap(svg, &scales, cell, baseCell)
+			}
+			xOut := scale.Linear{Min: l + ext.Margins.Left, Max: r - ext.Margins.Right}
+			scales.X = scale.QQ{&ext.X, &xOut}
+			scales.X2 = scale.QQ{&ext.X2, &xOut}
+			scales.Label = cellLabel(rowCfg, colCfg)
+			if g.Summary && i == len(g.Cols)-1 {
+				prev = nil
+				// The summary's distributions are of
+				// the other columns, not of
+				// measurements, so their intervals
+				// aren't measurement uncertainty.
+				scales.ErrorBars = 0
+			}
+			cell.Render(svg, &scales, prev, prevRight)
+			if g.Manifest != nil {
+				g.Manifest.add(rowI, i, rowCfg, colCfg, scales.Outer, cell, g.PhaseField)
+			}
+			prev, prevRight = cell, r
+		}
+
+		// Render key.
+		if g.OneKey {
+			continue
+		}
+		keyLeft, _ := g.X(len(g.Cols))
+		keyRight, keyBot := prev.RenderKey(svg, keyLeft, &scales)
+		if keyRight > maxRight {
+			maxRight = keyRight
+		}
+		if keyBot > maxBot {
+			maxBot = keyBot
+		}
+	}
+
+	if g.OneKey {
+		keyLeft, _ := g.X(len(g.Cols))
+		keyTop, _ := g.Y(0)
+		keyRight, keyBot := renderGridKey(svg, keyLeft, keyTop, gridPhases, gridColors, g.PhaseField)
+		if keyRight > maxRight {
+			maxRight = keyRight
+		}
+		if keyBot > maxBot {
+			maxBot = keyBot
+		}
+	}
+	return
+}
+
+// colorPhases assigns colors to the phases of all cells in g, so
+// phases have the same color in every row. It returns the colors and
+// all of the phases, with the phases that are prominent in any cell
+// first.
+func (g *Grid) colorPhases() (map[benchproc.Config]color.Color, []benchproc.Config) {
+	var ext Extents
+	for _, rowCfg := range g.Rows {
+		for _, colCfg := range g.Cols {
+			if cell, ok := g.Cells[cellKey{rowCfg, colCfg}]; ok {
+				cell.Extents(&ext)
+			}
+		}
+	}
+	colors := make(map[benchproc.Config]color.Color)
+	// A phase may be prominent in some rows but not others.
+	// Prefer the prominent palette for these.
+	assignColors(colors, &ext.OtherPhases, otherPal)
+	assignColors(colors, &ext.TopPhases, topPal)
+
+	phases := append([]benchproc.Config(nil), ext.TopPhases.nodes...)
+	for _, phase := range ext.OtherPhases.nodes {
+		if _, ok := ext.TopPhases.edges[phase]; !ok {
+			phases = append(phases, phase)
+		}
+	}
+	return colors, phases
+}
+
+// renderGridKey renders a key listing phases with their colors,
+// starting at x, top. It returns the right and bottom edges of the
+// key.
+func renderGridKey(svg *SVG, x, top float64, phases []benchproc.Config, colors map[benchproc.Config]color.Color, phaseField benchproc.Field) (right, bot float64) {
+	const swatch = keyFontSize
+	bot = top
+	for _, phaseCfg := range phases {
+		fmt.Fprintf(svg, `  <path d="%s" fill="%s" />`+"\n", svgPathRect(x, bot+(keyFontHeight-swatch)/2, x+swatch, bot+(keyFontHeight+swatch)/2), svgColor(colors[phaseCfg]))
+		fmt.Fprintf(svg, `  <text x="%f" y="%f" font-size="%d" dominant-baseline="central">%s</text>`+"\n", x+swatch+keyFontSize/2, bot+keyFontHeight/2, keyFontSize, phaseCfg.Get(phaseField))
+		bot += keyFontHeight
+	}
+	return x + keyWidth, bot
+}
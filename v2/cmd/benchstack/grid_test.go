@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestGridOneKey(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	cfg := func(s *benchproc.Schema, key, val string) benchproc.Config {
+		res := new(benchfmt.Result)
+		res.SetFileConfig(key, val)
+		c, _ := s.Project(res)
+		return c
+	}
+
+	// Two rows with overlapping phases.
+	cells := make(map[cellKey]Cell)
+	var rows []benchproc.Config
+	col := cfg(colBy, "col", "c1")
+	for i, rowPhases := range [][]string{{"a", "b"}, {"b", "c"}} {
+		var phases OMap
+		for _, phase := range rowPhases {
+			dist := benchstat.NewDistribution([]float64{1}, benchstat.DistributionOptions{})
+			phases.Store(nc.new(phase), dist)
+		}
+		row := cfg(rowBy, "row", string(rune('1'+i)))
+		rows = append(rows, row)
+		cells[cellKey{row, col}] = NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0]
+	}
+
+	render := func(oneKey bool) string {
+		g := Grid{
+			Rows:       rows,
+			Cols:       []benchproc.Config{col},
+			Cells:      cells,
+			X:          func(col int) (float64, float64) { return float64(col) * 130, float64(col)*130 + 100 },
+			Y:          func(row int) (float64, float64) { return float64(row) * 310, float64(row)*310 + 300 },
+			PhaseField: nc.s.Fields()[0],
+			OneKey:     oneKey,
+		}
+		var buf bytes.Buffer
+		g.Render(&SVG{w: &buf})
+		return buf.String()
+	}
+
+	// Per-row keys connect each phase to its key entry.
+	if svg := render(false); strings.Count(svg, `fill="none"`) != 4 {
+		t.Errorf("want 4 per-row key entries, got:\n%s", svg)
+	}
+
+	svg := render(true)
+	if strings.Contains(svg, `fill="none"`) {
+		t.Errorf("want no per-row keys, got:\n%s", svg)
+	}
+	// The key lists every phase once, with a swatch in the
+	// phase's color.
+	keyRe := regexp.MustCompile(`<path d="[^"]*" fill="([^"]*)" />\n  <text [^>]*>([^<]*)</text>`)
+	keys := keyRe.FindAllStringSubmatch(svg, -1)
+	var names []string
+	for _, key := range keys {
+		names = append(names, key[2])
+		// Every rectangle for this phase has the key color.
+		rectRe := regexp.MustCompile(`fill="([^"]*)"><title>` + key[2] + ` `)
+		for _, m := range rectRe.FindAllStringSubmatch(svg, -1) {
+			if m[1] != key[1] {
+				t.Errorf("phase %s: key color %s, but rendered with %s", key[2], key[1], m[1])
+			}
+		}
+	}
+	if got := strings.Join(names, " "); got != "a b c" {
+		t.Errorf("want key for phases a b c, got %s", got)
+	}
+}
+
+func TestGridBaselineRange(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	row, _ := rowBy.Project(new(benchfmt.Result))
+	col, _ := colBy.Project(new(benchfmt.Result))
+	var phases OMap
+	phases.Store(nc.new("a"), benchstat.NewDistribution([]float64{1}, benchstat.DistributionOptions{}))
+	cells := map[cellKey]Cell{
+		{row, col}: NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0],
+	}
+
+	// An out-of-range Baseline is ignored, whether or not a
+	// baseline feature is enabled.
+	for _, heatmap := range []bool{false, true} {
+		g := Grid{
+			Rows:       []benchproc.Config{row},
+			Cols:       []benchproc.Config{col},
+			Cells:      cells,
+			X:          func(col int) (float64, float64) { return float64(col) * 130, float64(col)*130 + 100 },
+			Y:          func(row int) (float64, float64) { return float64(row) * 310, float64(row)*310 + 300 },
+			PhaseField: nc.s.Fields()[0],
+			Heatmap:    heatmap,
+			Baseline:   3,
+		}
+		var buf bytes.Buffer
+		g.Render(&SVG{w: &buf})
+	}
+}
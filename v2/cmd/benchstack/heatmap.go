@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// A totaler is a Cell that has a single overall value that can be
+// compared against a baseline cell in the same row.
+type totaler interface {
+	total() float64
+}
+
+// Diverging palette endpoints from Color Brewer's RdYlGn.
+var (
+	heatmapWorse  color.Color = color.RGBA{215, 48, 39, 255}
+	heatmapBetter color.Color = color.RGBA{26, 152, 80, 255}
+)
+
+// heatmapMaxRatio is the ratio from the baseline at which the heatmap
+// tint reaches full intensity. Ratios beyond this are clamped.
+const heatmapMaxRatio = 2
+
+// heatmapMaxTint is the blend factor for a full-intensity tint. This
+// is kept light so cell content remains legible.
+const heatmapMaxTint = 0.5
+
+// heatmapTint returns the signed intensity of the heatmap tint for a
+// cell with total val in a row whose baseline total is base. The
+// result is in [-1, 1], where negative values are better than the
+// baseline. The intensity scales with the log of the ratio, so halving
+// and doubling are tinted equally strongly.
+func heatmapTint(val, base float64) float64 {
+	if base <= 0 || val <= 0 {
+		return 0
+	}
+	t := math.Log(val/base) / math.Log(heatmapMaxRatio)
+	return math.Max(-1, math.Min(1, t))
+}
+
+// heatmapColor returns the background color for a cell with total val
+// in a row whose baseline total is base. Smaller values are better, so
+// values below base are tinted green and values above base are tinted
+// red.
+func heatmapColor(val, base float64) color.Color {
+	t := heatmapTint(val, base)
+	if t > 0 {
+		return colorBlend(color.White, heatmapWorse, t*heatmapMaxTint)
+	} else if t < 0 {
+		return colorBlend(color.White, heatmapBetter, -t*heatmapMaxTint)
+	}
+	return color.White
+}
+
+// renderHeatmap emits a background rectangle for cell covering the
+// outer bounds in scales, tinted by how cell compares to base. It does
+// nothing if either cell doesn't have a total.
+func renderHeatmap(svg *SVG, scales *Scales, cell, base Cell) {
+	c, ok1 := cell.(totaler)
+	b, ok2 := base.(totaler)
+	if !ok1 || !ok2 {
+		return
+	}
+	o := scales.Outer
+	fill := svgColor(heatmapColor(c.total(), b.total()))
+	fmt.Fprintf(svg, `  <path d="%s" fill="%s" />`+"\n", svgPathRect(o.Left, o.Top, o.Right, o.Bottom), fill)
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHeatmapColor(t *testing.T) {
+	rgb := func(c color.Color) (r, g, b int) {
+		c2 := color.NRGBAModel.Convert(c).(color.NRGBA)
+		return int(c2.R), int(c2.G), int(c2.B)
+	}
+	// redness and greenness measure how far a tint has moved
+	// from white toward red or green.
+	redness := func(c color.Color) int {
+		_, g, b := rgb(c)
+		return 255 - (g+b)/2
+	}
+	greenness := func(c color.Color) int {
+		r, _, b := rgb(c)
+		return 255 - (r+b)/2
+	}
+
+	if got := heatmapColor(1, 1); got != color.White {
+		t.Errorf("equal to baseline: got %v, want white", got)
+	}
+	if got := heatmapColor(1, 0); got != color.White {
+		t.Errorf("zero baseline: got %v, want white", got)
+	}
+
+	// Worse than baseline tints toward red.
+	worse := heatmapColor(1.5, 1)
+	if r, g, _ := rgb(worse); r <= g {
+		t.Errorf("worse than baseline: got %v, want red-ward tint", worse)
+	}
+	// Better than baseline tints toward green.
+	better := heatmapColor(1/1.5, 1)
+	if r, g, _ := rgb(better); g <= r {
+		t.Errorf("better than baseline: got %v, want green-ward tint", better)
+	}
+
+	// The tint scales with the magnitude of the change.
+	if a, b := redness(heatmapColor(1.1, 1)), redness(worse); a >= b {
+		t.Errorf("1.1x is as red as 1.5x: %d >= %d", a, b)
+	}
+	if a, b := greenness(heatmapColor(1/1.1, 1)), greenness(better); a >= b {
+		t.Errorf("1/1.1x is as green as 1/1.5x: %d >= %d", a, b)
+	}
+	// And is symmetric in the log of the ratio.
+	if a, b := heatmapTint(1.5, 1), heatmapTint(1/1.5, 1); math.Abs(a+b) > 1e-9 {
+		t.Errorf("1.5x and 1/1.5x have different intensity: %v, %v", a, b)
+	}
+	// And clamps beyond heatmapMaxRatio.
+	if a, b := heatmapColor(heatmapMaxRatio, 1), heatmapColor(10*heatmapMaxRatio, 1); a != b {
+		t.Errorf("tint not clamped: %v != %v", a, b)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "fmt"
+
+// A Layout gives the dimensions of the cells in a grid. Text is
+// always drawn at the same size regardless of the layout, so small
+// cells may have more labels than fit; -compact omits these.
+type Layout struct {
+	// ColWidth and RowHeight are the size of each cell.
+	ColWidth, RowHeight float64
+
+	// ColSpace is the space between columns, where cells draw
+	// their connections to and deltas from the previous column.
+	// RowGap is the space between rows.
+	ColSpace, RowGap float64
+}
+
+// defaultLayout is the Layout used if no dimensions are specified.
+// The column space is enough for a "-100%" delta label.
+var defaultLayout = Layout{ColWidth: 100, RowHeight: 300, ColSpace: 30, RowGap: 10}
+
+// check returns an error naming the flag of any of l's dimensions
+// that can't be rendered.
+func (l Layout) check() error {
+	if !(l.ColWidth > 0) {
+		return fmt.Errorf("-col-width must be positive")
+	}
+	if !(l.RowHeight > 0) {
+		return fmt.Errorf("-row-height must be positive")
+	}
+	if !(l.ColSpace >= 0) {
+		return fmt.Errorf("-col-space must not be negative")
+	}
+	if !(l.RowGap >= 0) {
+		return fmt.Errorf("-row-gap must not be negative")
+	}
+	return nil
+}
+
+// cellEdges returns functions that return the left and right edges of
+// column col and the top and bottom edges of row row of a grid laid
+// out by l, where the top left cell starts at left, top. These are
+// suitable for Grid.X and Grid.Y.
+func (l Layout) cellEdges(left, top float64) (x func(col int) (float64, float64), y func(row int) (float64, float64)) {
+	x = func(col int) (float64, float64) {
+		l0 := left + float64(col)*(l.ColWidth+l.ColSpace)
+		return l0, l0 + l.ColWidth
+	}
+	y = func(row int) (float64, float64) {
+		t := top + float64(row)*(l.RowHeight+l.RowGap)
+		return t, t + l.RowHeight
+	}
+	return x, y
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestLayoutCellEdges(t *testing.T) {
+	x, y := Layout{ColWidth: 40, RowHeight: 80, ColSpace: 5, RowGap: 2}.cellEdges(10, 20)
+	check := func(what string, f func(int) (float64, float64), i int, want0, want1 float64) {
+		t.Helper()
+		if got0, got1 := f(i); got0 != want0 || got1 != want1 {
+			t.Errorf("%s(%d): want %v, %v, got %v, %v", what, i, want0, want1, got0, got1)
+		}
+	}
+	check("x", x, 0, 10, 50)
+	check("x", x, 2, 100, 140)
+	check("y", y, 0, 20, 100)
+	check("y", y, 3, 266, 346)
+
+	if err := defaultLayout.check(); err != nil {
+		t.Errorf("default layout: %v", err)
+	}
+	for _, l := range []Layout{{0, 1, 0, 0}, {1, -1, 0, 0}, {1, 1, -1, 0}, {1, 1, 0, -1}} {
+		if err := l.check(); err == nil {
+			t.Errorf("%+v: want error", l)
+		}
+	}
+}
+
+func TestLayoutGrid(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	cfg := func(s *benchproc.Schema, key, val string) benchproc.Config {
+		res := new(benchfmt.Result)
+		res.SetFileConfig(key, val)
+		c, _ := s.Project(res)
+		return c
+	}
+
+	var dists []*OMap
+	for i := 0; i < 2; i++ {
+		var phases OMap
+		dist := benchstat.NewDistribution([]float64{1}, benchstat.DistributionOptions{})
+		phases.Store(nc.new("a"), dist)
+		dists = append(dists, &phases)
+	}
+	stacks := NewStacks(dists, benchunit.UnitClassSI, PhaseOrderInput)
+	row := cfg(rowBy, "row", "1")
+	cols := []benchproc.Config{cfg(colBy, "col", "c1"), cfg(colBy, "col", "c2")}
+	cells := map[cellKey]Cell{{row, cols[0]}: stacks[0], {row, cols[1]}: stacks[1]}
+
+	render := func(l Layout) (string, float64, float64) {
+		x, y := l.cellEdges(0, 0)
+		g := Grid{
+			Rows:       []benchproc.Config{row},
+			Cols:       cols,
+			Cells:      cells,
+			X:          x,
+			Y:          y,
+			PhaseField: nc.s.Fields()[0],
+			OneKey:     true,
+		}
+		var buf bytes.Buffer
+		right, bot := g.Render(&SVG{w: &buf})
+		return buf.String(), right, bot
+	}
+
+	for _, l := range []Layout{defaultLayout, {ColWidth: 20, RowHeight: 50, ColSpace: 4, RowGap: 1}} {
+		svg, right, bot := render(l)
+		// The second column's phase spans the second column,
+		// starting at the top of the row.
+		rect := fmt.Sprintf(`<path d="M%f 0.000000H%f`, l.ColWidth+l.ColSpace, 2*l.ColWidth+l.ColSpace)
+		if !strings.Contains(svg, rect) {
+			t.Errorf("%+v: want rectangle %s, got:\n%s", l, rect, svg)
+		}
+		// The key follows the last column.
+		if want := 2*(l.ColWidth+l.ColSpace) + keyWidth; right != want {
+			t.Errorf("%+v: want right edge %v, got %v", l, want, right)
+		}
+		if bot != l.RowHeight {
+			t.Errorf("%+v: want bottom edge %v, got %v", l, l.RowHeight, bot)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"log"
 	"math"
 	"os"
+	"os/exec"
 	"reflect"
 	"sort"
 	"strings"
@@ -71,6 +72,31 @@ type Scales struct {
 	Colors map[benchproc.Config]color.Color
 
 	PhaseField benchproc.Field
+
+	// Label describes the full configuration of the cell being
+	// rendered. Cells include this in their tooltip.
+	Label string
+
+	// Compact abbreviates value labels and omits labels that don't
+	// have enough room to be legible.
+	Compact bool
+
+	// MaxCV, if positive, is the largest coefficient of variation
+	// of any phase for which a cell is rendered normally. Cells
+	// noisier than this are de-emphasized.
+	MaxCV float64
+
+	// Baseline, if non-nil, is the cell in the baseline column of
+	// the row being rendered. Cells label their phases with the
+	// change from the same phase in Baseline.
+	Baseline Cell
+
+	// ErrorBars, if positive, is a confidence level, such as 0.95.
+	// Cells draw an error bar at the end of each phase showing
+	// the confidence interval of the phase's median at this level.
+	// Phases with too few measurements for a confidence interval
+	// have no error bar.
+	ErrorBars float64
 }
 
 func expandScale(s *scale.Linear, min, max float64) {
@@ -107,35 +133,111 @@ func (s *SVG) GenID(prefix string) string {
 type unitInfo struct {
 	class    benchunit.UnitClass
 	newCells func(dists []*OMap, unitClass benchunit.UnitClass) []Cell
+	// reduce combines the measurements of a phase in a cell.
+	reduce benchstat.Reduction
+}
+
+// reduceFlag is a flag.Value that accumulates "unit=reduction"
+// arguments.
+type reduceFlag map[string]benchstat.Reduction
+
+func (f reduceFlag) String() string {
+	var args []string
+	for unit, r := range f {
+		args = append(args, unit+"="+r.String())
+	}
+	sort.Strings(args)
+	return strings.Join(args, ",")
+}
+
+func (f reduceFlag) Set(arg string) error {
+	i := strings.Index(arg, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected unit=reduction, got %q", arg)
+	}
+	r, err := benchstat.ParseReduction(arg[i+1:])
+	if err != nil {
+		return err
+	}
+	f[arg[:i]] = r
+	return nil
 }
 
 func main() {
-	flagCol := flag.String("col", "branch,commit-date,commit", "split columns by distinct values of `projection`")
-	flagRow := flag.String("row", "benchmark,/kind", "split rows by distinct values of `projection`")
+	var parser benchproc.ProjectionParser
+	flagCol := benchproc.NewProjectionFlag(&parser, "branch,commit-date,commit")
+	flag.Var(flagCol, "col", "split columns by distinct values of `projection`")
+	flagRow := benchproc.NewProjectionFlag(&parser, "benchmark,/kind")
+	flag.Var(flagRow, "row", "split rows by distinct values of `projection`")
 	flagFilter := flag.String("filter", "*", "use only benchmarks matching benchfilter `query`")
+	flagPhaseOrder := flag.String("phase-order", "input", "order phases in each stack by `order`: input, magnitude, or name")
+	flagFormat := flag.String("format", "svg", "output image `format`: svg or png")
+	flagRasterizer := flag.String("rasterizer", "rsvg-convert --format=png", "for -format png, convert SVG to PNG by running `command`, which reads SVG from stdin and writes PNG to stdout")
+	flagBaseline := flag.Int("baseline", 0, "use column `index` as the baseline for -heatmap and -baseline-delta")
+	flagHeatmap := flag.Bool("heatmap", false, "tint each cell by how its total compares to the baseline column")
+	flagBaselineDelta := flag.Bool("baseline-delta", false, "label each top phase with its change from the same phase in the baseline column")
+	flagWidth := flag.String("width", "", "scale the width of each phase in a stack by the metric with `unit`")
+	flagReduce := make(reduceFlag)
+	flag.Var(flagReduce, "reduce", "combine the measurements of each phase with `unit=reduction`, where reduction is median (the default), mean, geomean, sum, min, or max (may be repeated)")
+	flagCompact := flag.Bool("compact", false, "abbreviate value labels and omit labels too crowded to read")
+	flagOneKey := flag.Bool("one-key", false, "render one key for the whole grid instead of one per row")
+	flagFocus := flag.String("focus", "", "render only the cell at `row,col` (0-based indexes), full size with all labels")
+	flagManifest := flag.String("manifest", "", "write a JSON manifest of the rendered cells to `file`")
+	flagRoles := flag.Bool("roles", false, "set .role to baseline for results from the first input and experiment for the rest, for use in -col or -row")
+	flagSummary := flag.Bool("summary", false, "add a column showing the geomean of each phase across all columns")
+	layout := defaultLayout
+	flag.Float64Var(&layout.ColWidth, "col-width", layout.ColWidth, "draw each cell `width` units wide")
+	flag.Float64Var(&layout.RowHeight, "row-height", layout.RowHeight, "draw each cell `height` units high")
+	flag.Float64Var(&layout.ColSpace, "col-space", layout.ColSpace, "leave `width` units between columns for phase connections and deltas")
+	flag.Float64Var(&layout.RowGap, "row-gap", layout.RowGap, "leave `height` units between rows")
+	flagMaxCV := flag.Float64("max-cv", 0, "gray out cells where any phase has a coefficient of variation above `fraction`, such as 0.05 (0 disables)")
+	flagErrorBars := flag.Float64("error-bars", 0, "draw error bars showing the `confidence` interval of the median of each phase, such as 0.95 (0 disables); phases reduced by something other than the median get no error bar")
 	flag.Parse()
 	if flag.NArg() == 0 {
 		flag.Usage()
 		os.Exit(2)
 	}
+	if *flagFormat != "svg" && *flagFormat != "png" {
+		log.Fatalf("unknown output format %q", *flagFormat)
+	}
+	var rasterize func(svg []byte) ([]byte, error)
+	if *flagFormat == "png" {
+		if len(strings.Fields(*flagRasterizer)) == 0 {
+			log.Fatal("-format png requires a -rasterizer command")
+		}
+		rasterize = execRasterizer(*flagRasterizer)
+	}
 
-	// TODO: Put filter arg in a package along with FileArgs.
-	filter, err := benchproc.NewFilter(*flagFilter)
-	if err != nil {
+	if err := layout.check(); err != nil {
 		log.Fatal(err)
 	}
+	if *flagErrorBars != 0 && !(0 < *flagErrorBars && *flagErrorBars < 1) {
+		log.Fatalf("-error-bars %v must be between 0 and 1", *flagErrorBars)
+	}
 
-	var parser benchproc.ProjectionParser
-	colBy, err := parser.Parse(*flagCol)
+	phaseOrder, err := ParsePhaseOrder(*flagPhaseOrder)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "parsing -col: %s", err)
-		os.Exit(1)
+		log.Fatal(err)
 	}
-	rowBy, err := parser.Parse(*flagRow)
+	var focusRow, focusCol int
+	if *flagFocus != "" {
+		if *flagManifest != "" {
+			log.Fatal("-manifest cannot be used with -focus")
+		}
+		focusRow, focusCol, err = parseFocus(*flagFocus)
+		if err != nil {
+			log.Fatalf("-focus: %s", err)
+		}
+	}
+
+	// TODO: Put filter arg in a package along with FileArgs.
+	filter, err := benchproc.NewFilter(*flagFilter)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "parsing -row: %s", err)
-		os.Exit(1)
+		log.Fatal(err)
 	}
+
+	colBy := flagCol.Schema()
+	rowBy := flagRow.Schema()
 	unitField := rowBy.AddValues() // ".unit" is always the tidy unit
 	phaseBy, _ := parser.Parse(".name")
 
@@ -146,18 +248,21 @@ func main() {
 		var newCells func(dists []*OMap, unitClass benchunit.UnitClass) []Cell
 		switch unit {
 		case "sec/op", "B/op":
-			newCells = NewStacks
+			newCells = func(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
+				return NewStacks(dists, unitClass, phaseOrder)
+			}
 		case "live-B", "heap-B":
 			newCells = NewDeltaCells
 		}
-		units[unit] = unitInfo{unitClass, newCells}
+		units[unit] = unitInfo{unitClass, newCells, flagReduce[unit]}
+	}
+	for unit := range flagReduce {
+		if _, ok := units[unit]; !ok {
+			log.Fatalf("-reduce: unknown unit %q", unit)
+		}
 	}
 
 	// Parse measurements into cells.
-	type cellKey struct {
-		row benchproc.Config
-		col benchproc.Config
-	}
 	// TODO: The remaining uses of OMap are pretty uninteresting
 	// at this point. Can I make a Schema track the ordering and
 	// just use a regular map? Part of why that's hard is that
@@ -165,10 +270,21 @@ func main() {
 	// globalOrder. I'm not sure how to make Schema do something
 	// like that.
 	measurements := make(map[cellKey]*OMap) // OMap is phaseCfg -> []float64
+	widths := make(map[cellKey]*OMap)       // For -width; OMap is phaseCfg -> []float64
+	newPhases := func() *OMap {
+		return &OMap{
+			New: func(key benchproc.Config) interface{} {
+				return ([]float64)(nil)
+			},
+		}
+	}
 	rowSet := make(map[benchproc.Config]bool)
 	colSet := make(map[benchproc.Config]bool)
 
 	files := benchfmt.Files{Paths: flag.Args(), AllowStdin: true}
+	if *flagRoles {
+		files.RoleKey = ".role"
+	}
 	for files.Scan() {
 		res, err := files.Result()
 		if err != nil {
@@ -222,16 +338,24 @@ func main() {
 
 			cell := measurements[key]
 			if cell == nil {
-				cell = &OMap{
-					New: func(key benchproc.Config) interface{} {
-						return ([]float64)(nil)
-					},
-				}
+				cell = newPhases()
 				measurements[key] = cell
 			}
 
 			vals := cell.LoadOrNew(phaseCfg).([]float64)
 			cell.Store(phaseCfg, append(vals, value.Value))
+
+			// Record the -width metric from the same
+			// result, if any.
+			if width, ok := res.Value(*flagWidth); ok {
+				cell := widths[key]
+				if cell == nil {
+					cell = newPhases()
+					widths[key] = cell
+				}
+				vals := cell.LoadOrNew(phaseCfg).([]float64)
+				cell.Store(phaseCfg, append(vals, width))
+			}
 		}
 	}
 	if err := files.Err(); err != nil {
@@ -247,52 +371,97 @@ func main() {
 	benchproc.SortConfigs(rows)
 	cols := mapKeys(colSet).([]benchproc.Config)
 	benchproc.SortConfigs(cols)
+	if (*flagHeatmap || *flagBaselineDelta) && len(cols) > 0 && (*flagBaseline < 0 || *flagBaseline >= len(cols)) {
+		log.Fatalf("-baseline %d out of range; there are %d columns", *flagBaseline, len(cols))
+	}
 
 	// Transform distributions into cells by row.
 	cells := make(map[cellKey]Cell)
+	toDists := func(phases *OMap, reduce benchstat.Reduction) *OMap {
+		return phases.Map(func(key benchproc.Config, val interface{}) interface{} {
+			return benchstat.NewDistribution(val.([]float64), benchstat.DistributionOptions{Center: reduce})
+		})
+	}
 	for _, row := range rows {
-		var rowDists []*OMap // OMap is phaseCfg -> *Distribution
+		unit := row.Get(unitField)
+		var rowDists []*OMap  // OMap is phaseCfg -> *Distribution
+		var rowWidths []*OMap // OMap is phaseCfg -> *Distribution
 		for _, col := range cols {
 			if phases, ok := measurements[cellKey{row, col}]; ok {
-				dists := phases.Map(func(key benchproc.Config, val interface{}) interface{} {
-					return benchstat.NewDistribution(val.([]float64), benchstat.DistributionOptions{})
-				})
-				rowDists = append(rowDists, dists)
+				rowDists = append(rowDists, toDists(phases, units[unit].reduce))
+				var w *OMap
+				if phases, ok := widths[cellKey{row, col}]; ok {
+					w = toDists(phases, benchstat.ReduceMedian)
+				}
+				rowWidths = append(rowWidths, w)
 			}
 		}
-		unit := row.Get(unitField)
+		if *flagSummary {
+			rowDists = append(rowDists, geomeanPhases(rowDists))
+			rowWidths = append(rowWidths, nil)
+		}
 		rowCells := units[unit].newCells(rowDists, units[unit].class)
+		if *flagWidth != "" {
+			SetStackWidths(rowCells, rowWidths)
+		}
 		for _, col := range cols {
 			if _, ok := measurements[cellKey{row, col}]; ok {
 				cells[cellKey{row, col}] = rowCells[0]
 				rowCells = rowCells[1:]
 			}
 		}
+		if *flagSummary {
+			cells[cellKey{row, benchproc.Config{}}] = rowCells[0]
+		}
 	}
 
 	// Emit SVG
 	svgBuf := new(bytes.Buffer)
 	svg := &SVG{w: svgBuf}
+	finish := func(maxRight, maxBot float64) {
+		out := new(bytes.Buffer)
+		fmt.Fprintf(out,
+			`<svg version="1.1" width="%f" height="%f" xmlns="http://www.w3.org/2000/svg" font-family="sans-serif">
+%s</svg>`,
+			maxRight,
+			maxBot,
+			svgBuf.Bytes(),
+		)
+		if err := writeOutput(os.Stdout, out.Bytes(), *flagFormat, rasterize); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *flagFocus != "" {
+		if focusRow >= len(rows) || focusCol >= len(cols) {
+			log.Fatalf("-focus %s out of range; there are %d rows and %d columns", *flagFocus, len(rows), len(cols))
+		}
+		rowCfg, colCfg := rows[focusRow], cols[focusCol]
+		cell, ok := cells[cellKey{rowCfg, colCfg}]
+		if !ok {
+			log.Fatalf("-focus %s: cell has no data", *flagFocus)
+		}
+		var row []Cell
+		for _, colCfg := range cols {
+			if c, ok := cells[cellKey{rowCfg, colCfg}]; ok {
+				row = append(row, c)
+			}
+		}
+		finish(renderFocus(svg, cell, row, cellLabel(rowCfg, colCfg), phaseBy.Fields()[0]))
+		return
+	}
+
 	const configFontSize float64 = 12
 	const configFontHeight = configFontSize * 5 / 4
-	const colWidth = 100
-	const colSpace = 30 // Enough for "-100%"
-	const rowHeight = 300
-	const rowGap = 10
 
 	// Column and row labels
+	formatDates(rowBy.Fields(), rows)
+	formatDates(colBy.Fields(), cols)
 	rowHdr := benchproc.NewConfigHeader(rows)
 	colHdr := benchproc.NewConfigHeader(cols)
 	cellTop := float64(len(colBy.Fields())) * configFontHeight
 	cellLeft := float64(len(rowBy.Fields())) * configFontHeight
-	x := func(col int) (float64, float64) {
-		l := cellLeft + float64(col)*(colWidth+colSpace)
-		return l, l + colWidth
-	}
-	y := func(row int) (float64, float64) {
-		t := cellTop + float64(row)*(rowHeight+rowGap)
-		return t, t + rowHeight
-	}
+	x, y := layout.cellEdges(cellLeft, cellTop)
 
 	for _, col := range colHdr {
 		for _, cell := range col {
@@ -306,6 +475,13 @@ func main() {
 		}
 	}
 
+	if *flagSummary {
+		// The summary column is labeled next to the innermost
+		// column labels.
+		l, r := x(len(cols))
+		fmt.Fprintf(svg, `  <text x="%f" y="%f" font-size="%f" text-anchor="middle">%s</text>`+"\n", (l+r)/2, float64(len(colBy.Fields())-1)*configFontHeight+configFontSize, configFontSize, summaryLabel)
+	}
+
 	for _, row := range rowHdr {
 		for _, cell := range row {
 			t, _ := y(cell.Start)
@@ -318,76 +494,83 @@ func main() {
 		}
 	}
 
-	_, maxRight := x(len(cols) - 1)
-	_, maxBot := y(len(rows) - 1)
-
-	// Cell rows
-	for rowI, rowCfg := range rows {
-		top, bot := y(rowI)
-		if bot > maxBot {
-			maxBot = bot
+	gridCols := cols
+	if *flagSummary {
+		gridCols = append(gridCols[:len(gridCols):len(gridCols)], benchproc.Config{})
+	}
+	grid := Grid{
+		Rows:          rows,
+		Cols:          gridCols,
+		Cells:         cells,
+		X:             x,
+		Y:             y,
+		PhaseField:    phaseBy.Fields()[0],
+		Compact:       *flagCompact,
+		Heatmap:       *flagHeatmap,
+		BaselineDelta: *flagBaselineDelta,
+		Baseline:      *flagBaseline,
+		OneKey:        *flagOneKey,
+		Summary:       *flagSummary,
+		MaxCV:         *flagMaxCV,
+		ErrorBars:     *flagErrorBars,
+	}
+	if *flagManifest != "" {
+		grid.Manifest = new(Manifest)
+	}
+	maxRight, maxBot := grid.Render(svg)
+	if grid.Manifest != nil {
+		grid.Manifest.Width, grid.Manifest.Height = maxRight, maxBot
+		if err := writeManifest(*flagManifest, grid.Manifest); err != nil {
+			log.Fatal(err)
 		}
+	}
+	finish(maxRight, maxBot)
+}
 
-		// Construct scalers for this row.
-		var ext Extents
-		var scales Scales
-		for _, colCfg := range cols {
-			cell, ok := cells[cellKey{rowCfg, colCfg}]
-			if !ok {
-				continue
-			}
-			cell.Extents(&ext)
-		}
-		scales.Margins = ext.Margins
-		scales.Outer.Top = top
-		scales.Outer.Bottom = bot
-		yOut := scale.Linear{Min: top + ext.Margins.Top, Max: bot - ext.Margins.Bottom}
-		scales.Y = scale.QQ{&ext.Y, &yOut}
-		scales.PhaseField = phaseBy.Fields()[0]
-
-		// Color phases.
-		scales.Colors = make(map[benchproc.Config]color.Color)
-		assignColors(scales.Colors, &ext.TopPhases, topPal)
-		assignColors(scales.Colors, &ext.OtherPhases, otherPal)
-
-		// Render cells.
-		var prev Cell
-		var prevRight float64
-		for i, colCfg := range cols {
-			cell, ok := cells[cellKey{rowCfg, colCfg}]
-			if !ok {
-				continue
+// execRasterizer returns a function that converts an SVG image to PNG
+// by running command, a program and its space-separated arguments,
+// with the SVG on its standard input. benchstack doesn't bundle a
+// rasterizer, so this relies on an external converter such as
+// rsvg-convert or ImageMagick.
+func execRasterizer(command string) func(svg []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	return func(svg []byte) ([]byte, error) {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(svg)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if stderr.Len() > 0 {
+				return nil, fmt.Errorf("%s: %w\n%s", args[0], err, stderr.Bytes())
 			}
-
-			l, r := x(i)
-			scales.Outer.Left = l
-			scales.Outer.Right = r
-			xOut := scale.Linear{Min: l + ext.Margins.Left, Max: r - ext.Margins.Right}
-			scales.X = scale.QQ{&ext.X, &xOut}
-			scales.X2 = scale.QQ{&ext.X2, &xOut}
-			cell.Render(svg, &scales, prev, prevRight)
-			prev, prevRight = cell, r
+			return nil, fmt.Errorf("%s: %w", args[0], err)
 		}
+		return stdout.Bytes(), nil
+	}
+}
 
-		// Render key.
-		keyLeft, _ := x(len(cols))
-		keyRight, keyBot := prev.RenderKey(svg, keyLeft, &scales)
-		if keyRight > maxRight {
-			maxRight = keyRight
+// writeOutput writes the SVG image svg to w in the given format,
+// which must be "svg" or "png". For "png", it converts svg using
+// rasterize.
+func writeOutput(w io.Writer, svg []byte, format string, rasterize func(svg []byte) ([]byte, error)) error {
+	var out []byte
+	switch format {
+	case "svg":
+		out = svg
+	case "png":
+		if rasterize == nil {
+			return fmt.Errorf("png output requires a rasterizer")
 		}
-		if keyBot > maxBot {
-			maxBot = keyBot
+		var err error
+		out, err = rasterize(svg)
+		if err != nil {
+			return fmt.Errorf("rasterizing SVG: %w", err)
 		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
 	}
-
-	// Finalize SVG.
-	fmt.Printf(
-		`<svg version="1.1" width="%f" height="%f" xmlns="http://www.w3.org/2000/svg" font-family="sans-serif">
-%s</svg>`,
-		maxRight,
-		maxBot,
-		svgBuf.Bytes(),
-	)
+	_, err := w.Write(out)
+	return err
 }
 
 func mapKeys(m interface{}) interface{} {
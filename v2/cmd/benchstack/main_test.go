@@ -0,0 +1,88 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestWriteOutput(t *testing.T) {
+	svg := []byte(`<svg version="1.1"></svg>`)
+
+	// SVG output is passed through.
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, svg, "svg", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), svg) {
+		t.Errorf("svg: got %q, want %q", buf.Bytes(), svg)
+	}
+
+	// PNG output without a rasterizer fails.
+	buf.Reset()
+	if err := writeOutput(&buf, svg, "png", nil); err == nil {
+		t.Errorf("png without rasterizer: want error")
+	}
+
+	// PNG output invokes the rasterizer with the SVG.
+	var gotSVG []byte
+	rasterize := func(in []byte) ([]byte, error) {
+		gotSVG = append([]byte(nil), in...)
+		return []byte("PNG"), nil
+	}
+	buf.Reset()
+	if err := writeOutput(&buf, svg, "png", rasterize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotSVG, svg) {
+		t.Errorf("rasterizer got %q, want %q", gotSVG, svg)
+	}
+	if buf.String() != "PNG" {
+		t.Errorf("png: got %q, want %q", buf.String(), "PNG")
+	}
+
+	if err := writeOutput(&buf, svg, "gif", rasterize); err == nil {
+		t.Errorf("unknown format: want error")
+	}
+}
+
+func TestExecRasterizer(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not found")
+	}
+	// The command gets the SVG on stdin and its stdout is the
+	// image.
+	svg := []byte(`<svg version="1.1"></svg>`)
+	out, err := execRasterizer("cat")(svg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, svg) {
+		t.Errorf("got %q, want %q", out, svg)
+	}
+
+	if _, err := execRasterizer("cat /nonexistent")(svg); err == nil {
+		t.Errorf("failing command: want error")
+	}
+}
+
+func TestReduceFlag(t *testing.T) {
+	f := make(reduceFlag)
+	for _, arg := range []string{"B/op=sum", "live-B=max", "B/op=mean"} {
+		if err := f.Set(arg); err != nil {
+			t.Fatalf("%s: %s", arg, err)
+		}
+	}
+	if got, want := f.String(), "B/op=mean,live-B=max"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	for _, arg := range []string{"sum", "=sum", "B/op=mode"} {
+		if err := f.Set(arg); err == nil {
+			t.Errorf("%s: want error", arg)
+		}
+	}
+}
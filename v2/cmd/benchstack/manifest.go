@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"golang.org/x/perf/v2/benchproc"
+)
+
+// A Manifest is a machine-readable description of a rendered grid.
+// It records where each cell was drawn and the data behind it, so a
+// frontend can make the SVG interactive.
+type Manifest struct {
+	// Width and Height are the dimensions of the SVG image.
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+
+	Cells []ManifestCell `json:"cells"`
+}
+
+// A ManifestCell describes one rendered cell.
+type ManifestCell struct {
+	// Row and Col are the 0-based indexes of the cell in the grid.
+	Row int `json:"row"`
+	Col int `json:"col"`
+
+	// RowConfig and ColConfig map the fields of the cell's row
+	// and column configurations to their values. Empty values
+	// are omitted. ColConfig is empty for the -summary column.
+	RowConfig map[string]string `json:"rowConfig"`
+	ColConfig map[string]string `json:"colConfig"`
+
+	// Box is the bounding box of the cell's plot area.
+	Box ManifestBox `json:"box"`
+
+	// Phases are the phases of the cell in the order they're
+	// rendered.
+	Phases []ManifestPhase `json:"phases"`
+
+	// MinN and MaxN are the smallest and largest sample counts of
+	// any phase. MaxCV is the largest coefficient of variation of
+	// any phase, or 0 if no phase has enough samples.
+	MinN  int     `json:"minN"`
+	MaxN  int     `json:"maxN"`
+	MaxCV float64 `json:"maxCV"`
+}
+
+// A ManifestBox is a bounding box in SVG coordinates.
+type ManifestBox struct {
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+}
+
+// A ManifestPhase is the summarized value of one phase in a cell.
+type ManifestPhase struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// A manifester is a Cell that can describe its phases and their
+// statistics in a Manifest.
+type manifester interface {
+	manifest(phaseField benchproc.Field) ([]ManifestPhase, cellStats)
+}
+
+// add records cell, which was rendered at row and col with outer box
+// box, in m.
+func (m *Manifest) add(row, col int, rowCfg, colCfg benchproc.Config, box Box, cell Cell, phaseField benchproc.Field) {
+	mc := ManifestCell{
+		Row:       row,
+		Col:       col,
+		RowConfig: configMap(rowCfg),
+		ColConfig: configMap(colCfg),
+		Box:       ManifestBox{box.Top, box.Right, box.Bottom, box.Left},
+	}
+	if c, ok := cell.(manifester); ok {
+		var st cellStats
+		mc.Phases, st = c.manifest(phaseField)
+		mc.MinN, mc.MaxN, mc.MaxCV = st.minN, st.maxN, st.maxCV
+	}
+	m.Cells = append(m.Cells, mc)
+}
+
+// configMap returns the non-empty fields of cfg as a map from field
+// name to value.
+func configMap(cfg benchproc.Config) map[string]string {
+	out := make(map[string]string)
+	if cfg.IsZero() {
+		// The summary column.
+		return out
+	}
+	for _, field := range cfg.Schema().Fields() {
+		if val := cfg.Get(field); val != "" {
+			out[field.Name] = val
+		}
+	}
+	return out
+}
+
+// writeManifest writes m as JSON to the file at path.
+func writeManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return ioutil.WriteFile(path, data, 0666)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestGridManifest(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	cfg := func(s *benchproc.Schema, key, val string) benchproc.Config {
+		res := new(benchfmt.Result)
+		res.SetFileConfig(key, val)
+		c, _ := s.Project(res)
+		return c
+	}
+	rows := []benchproc.Config{cfg(rowBy, "row", "r0"), cfg(rowBy, "row", "r1")}
+	cols := []benchproc.Config{cfg(colBy, "col", "c0"), cfg(colBy, "col", "c1")}
+
+	// A 2x2 grid missing the cell at 1,0.
+	cells := make(map[cellKey]Cell)
+	for _, row := range rows {
+		var dists []*OMap
+		var rowCols []benchproc.Config
+		for _, col := range cols {
+			if row == rows[1] && col == cols[0] {
+				continue
+			}
+			var phases OMap
+			phases.Store(nc.new("a"), benchstat.NewDistribution([]float64{1, 2, 3}, benchstat.DistributionOptions{}))
+			phases.Store(nc.new("b"), benchstat.NewDistribution([]float64{5}, benchstat.DistributionOptions{}))
+			dists = append(dists, &phases)
+			rowCols = append(rowCols, col)
+		}
+		for i, cell := range NewStacks(dists, benchunit.UnitClassSI, PhaseOrderInput) {
+			cells[cellKey{row, rowCols[i]}] = cell
+		}
+	}
+
+	g := Grid{
+		Rows:       rows,
+		Cols:       cols,
+		Cells:      cells,
+		X:          func(col int) (float64, float64) { return float64(col) * 130, float64(col)*130 + 100 },
+		Y:          func(row int) (float64, float64) { return float64(row) * 310, float64(row)*310 + 300 },
+		PhaseField: nc.s.Fields()[0],
+		Manifest:   new(Manifest),
+	}
+	var buf bytes.Buffer
+	g.Render(&SVG{w: &buf})
+
+	var got []string
+	for _, c := range g.Manifest.Cells {
+		got = append(got, fmt.Sprintf("%d,%d %v %v %+v %+v n=%d-%d", c.Row, c.Col, c.RowConfig, c.ColConfig, c.Box, c.Phases, c.MinN, c.MaxN))
+	}
+	want := []string{
+		"0,0 map[row:r0] map[col:c0] {Top:0 Right:100 Bottom:300 Left:0} [{Name:a Value:2} {Name:b Value:5}] n=1-3",
+		"0,1 map[row:r0] map[col:c1] {Top:0 Right:230 Bottom:300 Left:130} [{Name:a Value:2} {Name:b Value:5}] n=1-3",
+		"1,1 map[row:r1] map[col:c1] {Top:310 Right:230 Bottom:610 Left:130} [{Name:a Value:2} {Name:b Value:5}] n=1-3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want cells:\n%q\ngot:\n%q", want, got)
+	}
+
+	// The manifest round-trips through JSON.
+	dir, err := ioutil.TempDir("", "benchstack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "manifest.json")
+	if err := writeManifest(path, g.Manifest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&m, g.Manifest) {
+		t.Errorf("want %+v, got %+v", g.Manifest, &m)
+	}
+}
@@ -7,28 +7,50 @@ package main
 import (
 	"testing"
 
+	"golang.org/x/perf/v2/benchfmt"
 	"golang.org/x/perf/v2/benchproc"
 )
 
+// nameConfigs projects benchmark names into Configs of a ".name"
+// Schema.
+type nameConfigs struct {
+	s *benchproc.Schema
+}
+
+func newNameConfigs() *nameConfigs {
+	var p benchproc.ProjectionParser
+	s, _ := p.Parse(".name")
+	return &nameConfigs{s}
+}
+
+func (nc *nameConfigs) new(name string) benchproc.Config {
+	cfg, _ := nc.s.Project(&benchfmt.Result{FullName: []byte(name)})
+	return cfg
+}
+
+func (nc *nameConfigs) name(cfg benchproc.Config) string {
+	return cfg.Get(nc.s.Fields()[0])
+}
+
 func TestGlobalOrder(t *testing.T) {
-	cs := new(benchproc.ConfigSet)
-	strToSeq := func(str string) []*benchproc.Config {
-		var seq []*benchproc.Config
+	nc := newNameConfigs()
+	strToSeq := func(str string) []benchproc.Config {
+		var seq []benchproc.Config
 		for i := 0; i < len(str); i++ {
-			seq = append(seq, cs.KeyVal("", str[i:i+1]))
+			seq = append(seq, nc.new(str[i:i+1]))
 		}
 		return seq
 	}
-	seqToStr := func(seq []*benchproc.Config) string {
+	seqToStr := func(seq []benchproc.Config) string {
 		str := ""
 		for _, cfg := range seq {
-			str += cfg.Val()
+			str += nc.name(cfg)
 		}
 		return str
 	}
 	test := func(local []string, want string) {
 		t.Helper()
-		localCfgs := make([][]*benchproc.Config, len(local))
+		localCfgs := make([][]benchproc.Config, len(local))
 		for i, l := range local {
 			localCfgs[i] = strToSeq(l)
 		}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"sort"
 
 	"golang.org/x/perf/v2/benchproc"
@@ -22,12 +23,21 @@ type Stack struct {
 	unitClass benchunit.UnitClass
 
 	phases OMap // phase config -> stackPhase
+	stats  cellStats
 
 	sum float64
 }
 
 type stackPhase struct {
 	start, end float64
+
+	// width is the width of this phase as a fraction of the
+	// column width. This is 1 unless the Stack's widths encode a
+	// secondary metric.
+	width float64
+
+	// dist is the distribution of this phase's length.
+	dist *benchstat.Distribution
 }
 
 func (p stackPhase) len() float64 {
@@ -39,7 +49,59 @@ type stackRow struct {
 	topPhases  map[benchproc.Config]bool
 }
 
-func NewStacks(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
+// A PhaseOrder specifies the order of phases within each Stack.
+type PhaseOrder int
+
+const (
+	// PhaseOrderInput orders phases in the order they appear in
+	// the input.
+	PhaseOrderInput PhaseOrder = iota
+	// PhaseOrderMagnitude orders phases from smallest to largest,
+	// so the largest phase is at the bottom of the stack.
+	PhaseOrderMagnitude
+	// PhaseOrderName orders phases alphabetically by name.
+	PhaseOrderName
+)
+
+// ParsePhaseOrder parses the name of a PhaseOrder. It accepts
+// "input", "magnitude", and "name".
+func ParsePhaseOrder(name string) (PhaseOrder, error) {
+	switch name {
+	case "input":
+		return PhaseOrderInput, nil
+	case "magnitude":
+		return PhaseOrderMagnitude, nil
+	case "name":
+		return PhaseOrderName, nil
+	}
+	return 0, fmt.Errorf("unknown phase order %q", name)
+}
+
+// sortPhases returns the keys of phases, which map from phase config
+// to *benchstat.Distribution, in the given order.
+func sortPhases(phases *OMap, order PhaseOrder) []benchproc.Config {
+	keys := phases.Keys
+	if order == PhaseOrderInput {
+		return keys
+	}
+	keys = append([]benchproc.Config(nil), keys...)
+	switch order {
+	case PhaseOrderMagnitude:
+		center := func(cfg benchproc.Config) float64 {
+			return phases.Load(cfg).(*benchstat.Distribution).Center
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			return center(keys[i]) < center(keys[j])
+		})
+	case PhaseOrderName:
+		sort.SliceStable(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	return keys
+}
+
+func NewStacks(dists []*OMap, unitClass benchunit.UnitClass, order PhaseOrder) []Cell {
 	// Collect phases and create cells.
 	row := &stackRow{}
 	cells := make([]Cell, len(dists))
@@ -50,12 +112,13 @@ func NewStacks(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
 		stack := &Stack{
 			row:       row,
 			unitClass: unitClass,
+			stats:     newCellStats(phases),
 		}
 		// Accumulate phases.
 		var csum float64
-		for _, phaseCfg := range phases.Keys {
+		for _, phaseCfg := range sortPhases(phases, order) {
 			dist := phases.Load(phaseCfg).(*benchstat.Distribution)
-			stack.phases.Store(phaseCfg, stackPhase{csum, csum + dist.Center})
+			stack.phases.Store(phaseCfg, stackPhase{csum, csum + dist.Center, 1, dist})
 			csum += dist.Center
 
 			if dist.Center > phaseMaxes[phaseCfg] {
@@ -66,7 +129,7 @@ func NewStacks(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
 		if csum > maxSum {
 			maxSum = csum
 		}
-		phaseOrders = append(phaseOrders, phases.Keys)
+		phaseOrders = append(phaseOrders, stack.phases.Keys)
 
 		cells[i] = stack
 	}
@@ -101,6 +164,56 @@ func NewStacks(dists []*OMap, unitClass benchunit.UnitClass) []Cell {
 	return cells
 }
 
+// SetStackWidths makes the width of each phase in the Stacks in cells
+// encode a secondary metric, turning each Stack into a marimekko-style
+// chart. widths corresponds to cells and maps from phase config to
+// *benchstat.Distribution of the secondary metric. The widths are
+// scaled so that the largest phase in the row spans the full column
+// width, so widths are comparable across the row. Phases without a
+// secondary metric, and any cells that aren't Stacks, are left alone.
+func SetStackWidths(cells []Cell, widths []*OMap) {
+	var max float64
+	for _, phases := range widths {
+		if phases == nil {
+			continue
+		}
+		for _, phaseCfg := range phases.Keys {
+			max = math.Max(max, phases.Load(phaseCfg).(*benchstat.Distribution).Center)
+		}
+	}
+	if !(max > 0) {
+		return
+	}
+	for i, cell := range cells {
+		stack, ok := cell.(*Stack)
+		if !ok || widths[i] == nil {
+			continue
+		}
+		for _, phaseCfg := range stack.phases.Keys {
+			dist, ok := widths[i].LoadOK(phaseCfg)
+			if !ok {
+				continue
+			}
+			phase := stack.phases.Load(phaseCfg).(stackPhase)
+			phase.width = math.Max(0, dist.(*benchstat.Distribution).Center/max)
+			stack.phases.Store(phaseCfg, phase)
+		}
+	}
+}
+
+func (s *Stack) total() float64 {
+	return s.sum
+}
+
+func (s *Stack) manifest(phaseField benchproc.Field) ([]ManifestPhase, cellStats) {
+	var phases []ManifestPhase
+	for _, phaseCfg := range s.phases.Keys {
+		phase := s.phases.Load(phaseCfg).(stackPhase)
+		phases = append(phases, ManifestPhase{phaseCfg.Get(phaseField), phase.len()})
+	}
+	return phases, s.stats
+}
+
 func (s *Stack) Extents(ext *Extents) {
 	expandScale(&ext.X, 0, 1)
 	expandScale(&ext.Y, 0, s.sum)
@@ -121,21 +234,52 @@ func (s *Stack) Extents(ext *Extents) {
 	}
 }
 
+// baselineDelta returns the relative change of phaseCfg in s from the
+// same phase in base. It returns false if base isn't another Stack,
+// phaseCfg isn't a top phase, or base doesn't have phaseCfg.
+func (s *Stack) baselineDelta(base Cell, phaseCfg benchproc.Config) (float64, bool) {
+	b, ok := base.(*Stack)
+	if !ok || b == s || !s.row.topPhases[phaseCfg] {
+		return 0, false
+	}
+	phase0, ok := b.phases.LoadOK(phaseCfg)
+	if !ok || phase0.(stackPhase).len() == 0 {
+		return 0, false
+	}
+	return s.phases.Load(phaseCfg).(stackPhase).len()/phase0.(stackPhase).len() - 1, true
+}
+
 func (s *Stack) Render(svg *SVG, scales *Scales, prev Cell, prevRight float64) {
+	renderCellStart(svg, scales, "total "+benchunit.Scale(s.sum, s.unitClass), s.stats)
+	defer renderCellEnd(svg)
+
 	x, y := scales.X, scales.Y
+	// inset returns how far in from each side of the column a
+	// phase's rectangle is. Phases are centered in the column.
+	inset := func(phase stackPhase) float64 {
+		return (1 - phase.width) / 2 * (x.Map(1) - x.Map(0))
+	}
 	for _, phaseCfg := range s.phases.Keys {
 		phase := s.phases.Load(phaseCfg).(stackPhase)
 		fill := svgColor(scales.Colors[phaseCfg])
 		title := phaseCfg.Get(scales.PhaseField)
 
 		// Draw rectangle for this phase.
-		path := svgPathRect(x.Map(0), y.Map(phase.start), x.Map(1), y.Map(phase.end))
+		path := svgPathRect(x.Map(0)+inset(phase), y.Map(phase.start), x.Map(1)-inset(phase), y.Map(phase.end))
 		fmt.Fprintf(svg, `  <path d="%s" fill="%s"><title>%s (%s)</title></path>`+"\n", path, fill, title, benchunit.Scale(phase.len(), s.unitClass))
 
 		// Phase label.
-		clipID := svg.GenID("clip")
-		fmt.Fprintf(svg, `  <clipPath id="%s"><path d="%s" /></clipPath>`+"\n", clipID, path)
-		fmt.Fprintf(svg, `  <text x="%f" y="%f" clip-path="url(#%s)" font-size="%d" text-anchor="middle" dy=".4em">%s (%.0f%%)</text>`+"\n", x.Map(0.5), (y.Map(phase.start)+y.Map(phase.end))/2, clipID, labelFontSize, benchunit.Scale(phase.len(), s.unitClass), 100*phase.len()/s.sum)
+		height := y.Map(phase.end) - y.Map(phase.start)
+		if scales.labelFits(height) {
+			label := fmt.Sprintf("%s (%.0f%%", scales.formatValue(phase.len(), s.unitClass), 100*phase.len()/s.sum)
+			if delta, ok := s.baselineDelta(scales.Baseline, phaseCfg); ok {
+				label += fmt.Sprintf(", Δ%+.0f%%", 100*delta)
+			}
+			label += ")"
+			clipID := svg.GenID("clip")
+			fmt.Fprintf(svg, `  <clipPath id="%s"><path d="%s" /></clipPath>`+"\n", clipID, path)
+			fmt.Fprintf(svg, `  <text x="%f" y="%f" clip-path="url(#%s)" font-size="%d" text-anchor="middle" dy=".4em">%s</text>`+"\n", x.Map(0.5), (y.Map(phase.start)+y.Map(phase.end))/2, clipID, labelFontSize, label)
+		}
 
 		// Connect to phase in previous column.
 		if prev, ok := prev.(*Stack); ok {
@@ -143,9 +287,14 @@ func (s *Stack) Render(svg *SVG, scales *Scales, prev Cell, prevRight float64) {
 			if !ok {
 				continue
 			}
-			path := fmt.Sprintf("M%f %fL%f %fV%fL%f %fz", prevRight, y.Map(phase0.start), x.Map(0), y.Map(phase.start), y.Map(phase.end), prevRight, y.Map(phase0.end))
+			l, r := prevRight-inset(phase0), x.Map(0)+inset(phase)
+			path := fmt.Sprintf("M%f %fL%f %fV%fL%f %fz", l, y.Map(phase0.start), r, y.Map(phase.start), y.Map(phase.end), l, y.Map(phase0.end))
 			fmt.Fprintf(svg, `  <path d="%s" fill="%s" fill-opacity="0.5" />`+"\n", path, fill)
 			// Delta label.
+			height0 := y.Map(phase0.end) - y.Map(phase0.start)
+			if !scales.labelFits(math.Min(math.Abs(height), math.Abs(height0))) {
+				continue
+			}
 			clipID := svg.GenID("clip")
 			fmt.Fprintf(svg, `  <clipPath id="%s"><path d="%s" /></clipPath>`+"\n", clipID, path)
 			x := mid(prevRight, scales.Outer.Left)
@@ -154,8 +303,15 @@ func (s *Stack) Render(svg *SVG, scales *Scales, prev Cell, prevRight float64) {
 		}
 	}
 
-	// Total
-	label := benchunit.Scale(s.sum, s.unitClass)
+	// Error bars at the end of each phase. These go on top of the
+	// phases, since they extend into neighboring phases.
+	for _, phaseCfg := range s.phases.Keys {
+		phase := s.phases.Load(phaseCfg).(stackPhase)
+		renderErrorBar(svg, scales, x.Map(0.5), phase.end, phase.dist, s.unitClass)
+	}
+
+	// Total. This is always shown, even in compact mode.
+	label := scales.formatValue(s.sum, s.unitClass)
 	totalY := scales.Outer.Bottom - labelFontHeight + labelFontSize
 	fmt.Fprintf(svg, `  <text x="%f" y="%f" font-size="%d" text-anchor="middle">%s</text>`+"\n", x.Map(0.5), totalY, labelFontSize, label)
 	if prev, ok := prev.(*Stack); ok {
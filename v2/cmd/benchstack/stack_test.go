@@ -0,0 +1,186 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestStackPhaseOrder(t *testing.T) {
+	nc := newNameConfigs()
+	newDists := func(phases ...interface{}) *OMap {
+		var m OMap
+		for i := 0; i < len(phases); i += 2 {
+			val := phases[i+1].(float64)
+			dist := benchstat.NewDistribution([]float64{val}, benchstat.DistributionOptions{})
+			m.Store(nc.new(phases[i].(string)), dist)
+		}
+		return &m
+	}
+	dists := []*OMap{
+		newDists("c", 2.0, "a", 1.0, "b", 5.0),
+		newDists("c", 1.0, "a", 2.0, "b", 6.0),
+	}
+	order := func(c Cell) string {
+		str := ""
+		for _, cfg := range c.(*Stack).phases.Keys {
+			str += nc.name(cfg)
+		}
+		return str
+	}
+	test := func(po PhaseOrder, want ...string) {
+		t.Helper()
+		cells := NewStacks(dists, benchunit.UnitClassSI, po)
+		for i, cell := range cells {
+			if got := order(cell); got != want[i] {
+				t.Errorf("cell %d: got order %s, want %s", i, got, want[i])
+			}
+		}
+	}
+
+	test(PhaseOrderInput, "cab", "cab")
+	test(PhaseOrderName, "abc", "abc")
+	test(PhaseOrderMagnitude, "acb", "cab")
+
+	// The largest phase should be at the bottom of the stack.
+	cells := NewStacks(dists, benchunit.UnitClassSI, PhaseOrderMagnitude)
+	for i, cell := range cells {
+		stack := cell.(*Stack)
+		last := stack.phases.Keys[len(stack.phases.Keys)-1]
+		if name := nc.name(last); name != "b" {
+			t.Errorf("cell %d: want b at bottom, got %s", i, name)
+		}
+		if phase := stack.phases.Load(last).(stackPhase); phase.end != stack.sum {
+			t.Errorf("cell %d: want bottom phase to end at %v, got %v", i, stack.sum, phase.end)
+		}
+	}
+}
+
+func TestStackWidths(t *testing.T) {
+	nc := newNameConfigs()
+	newDists := func(phases ...interface{}) *OMap {
+		var m OMap
+		for i := 0; i < len(phases); i += 2 {
+			val := phases[i+1].(float64)
+			dist := benchstat.NewDistribution([]float64{val}, benchstat.DistributionOptions{})
+			m.Store(nc.new(phases[i].(string)), dist)
+		}
+		return &m
+	}
+	cells := NewStacks([]*OMap{
+		newDists("a", 1.0, "b", 3.0),
+		newDists("a", 2.0, "b", 2.0),
+	}, benchunit.UnitClassSI, PhaseOrderInput)
+	SetStackWidths(cells, []*OMap{
+		newDists("a", 2.0, "b", 4.0),
+		nil,
+	})
+
+	var ext Extents
+	for _, cell := range cells {
+		cell.Extents(&ext)
+	}
+	scales := Scales{
+		Colors:     map[benchproc.Config]color.Color{},
+		PhaseField: nc.s.Fields()[0],
+	}
+	assignColors(scales.Colors, &ext.TopPhases, topPal)
+	assignColors(scales.Colors, &ext.OtherPhases, otherPal)
+	xOut := scale.Linear{Min: 0, Max: 100}
+	yOut := scale.Linear{Min: 0, Max: 100}
+	scales.X = scale.QQ{Src: &ext.X, Dest: &xOut}
+	scales.Y = scale.QQ{Src: &ext.Y, Dest: &yOut}
+	var buf bytes.Buffer
+	cells[0].Render(&SVG{w: &buf}, &scales, nil, 0)
+	svg := buf.String()
+
+	// Phase a is half as wide as b and centered, and the phases
+	// still stack from 0 to 1 to 4.
+	for _, path := range []string{
+		svgPathRect(25, 0, 75, 25),
+		svgPathRect(0, 25, 100, 100),
+	} {
+		if !strings.Contains(svg, `<path d="`+path+`"`) {
+			t.Errorf("want path %s in:\n%s", path, svg)
+		}
+	}
+
+	// The second cell has no widths, so its phases span the
+	// column.
+	for _, phaseCfg := range cells[1].(*Stack).phases.Keys {
+		if w := cells[1].(*Stack).phases.Load(phaseCfg).(stackPhase).width; w != 1 {
+			t.Errorf("want width 1 without a secondary metric, got %v", w)
+		}
+	}
+}
+
+func TestStackBaselineDelta(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	cfg := func(s *benchproc.Schema, key, val string) benchproc.Config {
+		res := new(benchfmt.Result)
+		res.SetFileConfig(key, val)
+		c, _ := s.Project(res)
+		return c
+	}
+	newDists := func(phases ...interface{}) *OMap {
+		var m OMap
+		for i := 0; i < len(phases); i += 2 {
+			val := phases[i+1].(float64)
+			dist := benchstat.NewDistribution([]float64{val}, benchstat.DistributionOptions{})
+			m.Store(nc.new(phases[i].(string)), dist)
+		}
+		return &m
+	}
+	// Phase z is too small to be a top phase.
+	stacks := NewStacks([]*OMap{
+		newDists("a", 10.0, "b", 20.0, "z", 0.01),
+		newDists("a", 15.0, "b", 10.0, "z", 0.02),
+	}, benchunit.UnitClassSI, PhaseOrderInput)
+	row := cfg(rowBy, "row", "r")
+	cols := []benchproc.Config{cfg(colBy, "col", "base"), cfg(colBy, "col", "new")}
+	cells := map[cellKey]Cell{{row, cols[0]}: stacks[0], {row, cols[1]}: stacks[1]}
+
+	labelRe := regexp.MustCompile(`>([^<]*)</text>`)
+	render := func(baselineDelta bool) (deltas []string) {
+		g := Grid{
+			Rows:          []benchproc.Config{row},
+			Cols:          cols,
+			Cells:         cells,
+			X:             func(col int) (float64, float64) { return float64(col) * 130, float64(col)*130 + 100 },
+			Y:             func(row int) (float64, float64) { return float64(row) * 310, float64(row)*310 + 300 },
+			PhaseField:    nc.s.Fields()[0],
+			BaselineDelta: baselineDelta,
+		}
+		var buf bytes.Buffer
+		g.Render(&SVG{w: &buf})
+		for _, m := range labelRe.FindAllStringSubmatch(buf.String(), -1) {
+			if i := strings.Index(m[1], "Δ"); i >= 0 {
+				deltas = append(deltas, m[1][i:])
+			}
+		}
+		return deltas
+	}
+
+	// Only the top phases of the non-baseline column are labeled.
+	if got, want := strings.Join(render(true), " "), "Δ+50%) Δ-50%)"; got != want {
+		t.Errorf("want baseline deltas %s, got %s", want, got)
+	}
+	if got := render(false); len(got) != 0 {
+		t.Errorf("want no baseline deltas without BaselineDelta, got %v", got)
+	}
+}
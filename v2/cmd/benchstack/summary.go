@@ -0,0 +1,44 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+)
+
+// summaryLabel labels the summary column added by -summary.
+const summaryLabel = "geomean"
+
+// geomeanPhases returns a synthetic cell that summarizes the cells in
+// dists, which map from phase config to *benchstat.Distribution. Each
+// phase in the result is the geometric mean of the centers of that
+// phase in the cells that have it, and its Values are those centers.
+// The phases are in the global order of dists. Phases with a center
+// that isn't positive have no geometric mean and are omitted.
+func geomeanPhases(dists []*OMap) *OMap {
+	var orders [][]benchproc.Config
+	for _, phases := range dists {
+		orders = append(orders, phases.Keys)
+	}
+
+	out := new(OMap)
+	for _, phaseCfg := range globalOrder(orders) {
+		var centers []float64
+		for _, phases := range dists {
+			if dist, ok := phases.LoadOK(phaseCfg); ok {
+				centers = append(centers, dist.(*benchstat.Distribution).Center)
+			}
+		}
+		dist := benchstat.NewDistribution(centers, benchstat.DistributionOptions{Center: benchstat.ReduceGeomean})
+		if math.IsNaN(dist.Center) {
+			continue
+		}
+		out.Store(phaseCfg, dist)
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestGeomeanPhases(t *testing.T) {
+	nc := newNameConfigs()
+	cell := func(phases ...interface{}) *OMap {
+		var m OMap
+		for i := 0; i < len(phases); i += 2 {
+			dist := benchstat.NewDistribution([]float64{phases[i+1].(float64)}, benchstat.DistributionOptions{})
+			m.Store(nc.new(phases[i].(string)), dist)
+		}
+		return &m
+	}
+	dists := []*OMap{
+		cell("a", 1.0, "b", 4.0, "d", 1.0),
+		cell("a", 4.0, "b", 2.0, "c", 3.0, "d", 0.0),
+		cell("a", 2.0, "b", 1.0),
+	}
+	sum := geomeanPhases(dists)
+
+	// Phase c is only in one column, so its geomean is its own
+	// value. Phase d has a zero value, so it has no geomean.
+	want := map[string]float64{"a": 2, "b": 2, "c": 3}
+	var names []string
+	for _, phaseCfg := range sum.Keys {
+		name := nc.name(phaseCfg)
+		names = append(names, name)
+		dist := sum.Load(phaseCfg).(*benchstat.Distribution)
+		if math.Abs(dist.Center-want[name]) > 1e-9 {
+			t.Errorf("phase %s: want geomean %v, got %v", name, want[name], dist.Center)
+		}
+	}
+	if got := strings.Join(names, " "); got != "a b c" {
+		t.Errorf("want phases a b c, got %s", got)
+	}
+	if n := len(sum.Load(nc.new("b")).(*benchstat.Distribution).Values); n != 3 {
+		t.Errorf("phase b: want 3 values, got %d", n)
+	}
+}
+
+func TestGridSummary(t *testing.T) {
+	nc := newNameConfigs()
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("row")
+	colBy, _ := p.Parse("col")
+	cfg := func(s *benchproc.Schema, key, val string) benchproc.Config {
+		res := new(benchfmt.Result)
+		res.SetFileConfig(key, val)
+		c, _ := s.Project(res)
+		return c
+	}
+
+	var dists []*OMap
+	for _, scale := range []float64{1, 4} {
+		var phases OMap
+		for _, phase := range []string{"a", "b"} {
+			dist := benchstat.NewDistribution([]float64{scale}, benchstat.DistributionOptions{})
+			phases.Store(nc.new(phase), dist)
+		}
+		dists = append(dists, &phases)
+	}
+	dists = append(dists, geomeanPhases(dists))
+	stacks := NewStacks(dists, benchunit.UnitClassSI, PhaseOrderInput)
+
+	row := cfg(rowBy, "row", "1")
+	cols := []benchproc.Config{cfg(colBy, "col", "c1"), cfg(colBy, "col", "c2"), {}}
+	cells := make(map[cellKey]Cell)
+	for i, col := range cols {
+		cells[cellKey{row, col}] = stacks[i]
+	}
+	g := Grid{
+		Rows:       []benchproc.Config{row},
+		Cols:       cols,
+		Cells:      cells,
+		X:          func(col int) (float64, float64) { return float64(col) * 130, float64(col)*130 + 100 },
+		Y:          func(row int) (float64, float64) { return float64(row) * 310, float64(row)*310 + 300 },
+		PhaseField: nc.s.Fields()[0],
+		Summary:    true,
+	}
+	var buf bytes.Buffer
+	g.Render(&SVG{w: &buf})
+	svg := buf.String()
+
+	// The summary totals the geomean of each phase.
+	if !strings.Contains(svg, "<title>row:1\n"+summaryLabel+"\n") {
+		t.Errorf("want summary cell title, got:\n%s", svg)
+	}
+	if !strings.Contains(svg, "total 4.00\n") {
+		t.Errorf("want summary total 4, got:\n%s", svg)
+	}
+	// Only c1 and c2 are connected, not c2 and the summary.
+	if n := strings.Count(svg, `fill-opacity="0.5"`); n != 2 {
+		t.Errorf("want 2 phase connections, got %d:\n%s", n, svg)
+	}
+}
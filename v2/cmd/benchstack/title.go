@@ -0,0 +1,120 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/aclements/go-moremath/stats"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+)
+
+// cellStats summarizes the distributions of all of the phases in a
+// cell.
+type cellStats struct {
+	// minN and maxN are the smallest and largest sample counts
+	// of any phase.
+	minN, maxN int
+	// maxCV is the largest coefficient of variation of any phase
+	// with at least two samples, or 0 if there are none.
+	maxCV float64
+}
+
+// newCellStats summarizes phases, which maps from phase config to
+// *benchstat.Distribution.
+func newCellStats(phases *OMap) cellStats {
+	var st cellStats
+	for i, phaseCfg := range phases.Keys {
+		dist := phases.Load(phaseCfg).(*benchstat.Distribution)
+		n := len(dist.Values)
+		if i == 0 || n < st.minN {
+			st.minN = n
+		}
+		if n > st.maxN {
+			st.maxN = n
+		}
+		if n >= 2 {
+			samp := stats.Sample{Xs: dist.Values}
+			if mean := samp.Mean(); mean != 0 {
+				cv := samp.StdDev() / mean
+				if cv < 0 {
+					cv = -cv
+				}
+				if cv > st.maxCV {
+					st.maxCV = cv
+				}
+			}
+		}
+	}
+	return st
+}
+
+func (st cellStats) String() string {
+	var buf strings.Builder
+	if st.minN == st.maxN {
+		fmt.Fprintf(&buf, "n=%d", st.minN)
+	} else {
+		fmt.Fprintf(&buf, "n=%d-%d", st.minN, st.maxN)
+	}
+	if st.maxCV != 0 {
+		fmt.Fprintf(&buf, ", max CV %.1f%%", 100*st.maxCV)
+	}
+	return buf.String()
+}
+
+// noisyOpacity is the opacity of cells that are too noisy to trust.
+// Against the white background, this washes out their colors.
+const noisyOpacity = 0.35
+
+// noisy reports whether st is noisier than maxCV allows. A maxCV of 0
+// disables this check.
+func (st cellStats) noisy(maxCV float64) bool {
+	return maxCV > 0 && st.maxCV > maxCV
+}
+
+// renderCellStart begins an SVG group for a cell whose tooltip gives
+// the full configuration of the cell from scales, a headline value,
+// and st. If st is noisier than scales.MaxCV, the group is grayed out
+// and the tooltip says why. The caller must close the group with
+// renderCellEnd.
+func renderCellStart(svg *SVG, scales *Scales, headline string, st cellStats) {
+	var title strings.Builder
+	lines := append(strings.Split(scales.Label, "\n"), headline, st.String())
+	noisy := st.noisy(scales.MaxCV)
+	if noisy {
+		lines = append(lines, fmt.Sprintf("noisy: CV above %.1f%%", 100*scales.MaxCV))
+	}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if title.Len() > 0 {
+			title.WriteByte('\n')
+		}
+		xml.EscapeText(&title, []byte(line))
+	}
+	if noisy {
+		fmt.Fprintf(svg, "  <g opacity=\"%g\"><title>%s</title>\n", noisyOpacity, title.String())
+	} else {
+		fmt.Fprintf(svg, "  <g><title>%s</title>\n", title.String())
+	}
+}
+
+// renderCellEnd ends the group started by renderCellStart.
+func renderCellEnd(svg *SVG) {
+	fmt.Fprintf(svg, "  </g>\n")
+}
+
+// cellLabel returns the label of the cell at row and col.
+func cellLabel(row, col benchproc.Config) string {
+	if col.IsZero() {
+		// The summary column.
+		return row.String() + "\n" + summaryLabel
+	}
+	return row.String() + "\n" + col.String()
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-moremath/scale"
+	"golang.org/x/perf/v2/benchfmt"
+	"golang.org/x/perf/v2/benchproc"
+	"golang.org/x/perf/v2/benchstat"
+	"golang.org/x/perf/v2/benchunit"
+)
+
+func TestCellTitle(t *testing.T) {
+	nc := newNameConfigs()
+	var phases OMap
+	for _, phase := range []string{"a", "b"} {
+		dist := benchstat.NewDistribution([]float64{1, 2, 3}, benchstat.DistributionOptions{})
+		phases.Store(nc.new(phase), dist)
+	}
+
+	// Construct row and column configs.
+	var p benchproc.ProjectionParser
+	rowBy, _ := p.Parse("/kind")
+	colBy, _ := p.Parse("commit")
+	res := &benchfmt.Result{FullName: []byte("Name/kind=cpu")}
+	res.SetFileConfig("commit", "abc<1>")
+	rowCfg, _ := rowBy.Project(res)
+	colCfg, _ := colBy.Project(res)
+
+	for _, cell := range []Cell{
+		NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0],
+		NewDeltaCells([]*OMap{&phases}, benchunit.UnitClassSI)[0],
+	} {
+		var ext Extents
+		cell.Extents(&ext)
+		scales := Scales{
+			Outer:      Box{0, 100, 100, 0},
+			Colors:     map[benchproc.Config]color.Color{},
+			PhaseField: nc.s.Fields()[0],
+			Label:      cellLabel(rowCfg, colCfg),
+		}
+		assignColors(scales.Colors, &ext.TopPhases, topPal)
+		assignColors(scales.Colors, &ext.OtherPhases, otherPal)
+		out := scale.Linear{Min: 0, Max: 100}
+		scales.X = scale.QQ{Src: &ext.X, Dest: &out}
+		scales.Y = scale.QQ{Src: &ext.Y, Dest: &out}
+
+		var buf bytes.Buffer
+		cell.Render(&SVG{w: &buf}, &scales, nil, 0)
+		svg := buf.String()
+
+		want := "<g><title>/kind:cpu\ncommit:abc&lt;1&gt;\n"
+		if !strings.HasPrefix(svg, "  "+want) {
+			t.Errorf("%T: want cell title starting with %q, got:\n%s", cell, want, svg)
+		}
+		if !strings.Contains(svg, "\nn=3, max CV 50.0%</title>") {
+			t.Errorf("%T: want sample count and CV in title, got:\n%s", cell, svg)
+		}
+		if !strings.HasSuffix(svg, "  </g>\n") {
+			t.Errorf("%T: want cell group to be closed, got:\n%s", cell, svg)
+		}
+	}
+}
+
+func TestCellMaxCV(t *testing.T) {
+	nc := newNameConfigs()
+	render := func(values []float64, maxCV float64) string {
+		t.Helper()
+		var phases OMap
+		phases.Store(nc.new("a"), benchstat.NewDistribution(values, benchstat.DistributionOptions{}))
+		cell := NewStacks([]*OMap{&phases}, benchunit.UnitClassSI, PhaseOrderInput)[0]
+		var ext Extents
+		cell.Extents(&ext)
+		scales := Scales{
+			Outer:      Box{0, 100, 100, 0},
+			Colors:     map[benchproc.Config]color.Color{},
+			PhaseField: nc.s.Fields()[0],
+			MaxCV:      maxCV,
+		}
+		assignColors(scales.Colors, &ext.TopPhases, topPal)
+		out := scale.Linear{Min: 0, Max: 100}
+		scales.X = scale.QQ{Src: &ext.X, Dest: &out}
+		scales.Y = scale.QQ{Src: &ext.Y, Dest: &out}
+		var buf bytes.Buffer
+		cell.Render(&SVG{w: &buf}, &scales, nil, 0)
+		return buf.String()
+	}
+	const noisy = `<g opacity="0.35">`
+
+	// CV 50%.
+	svg := render([]float64{1, 2, 3}, 0.1)
+	if !strings.HasPrefix(svg, "  "+noisy) {
+		t.Errorf("want high-CV cell to be de-emphasized, got:\n%s", svg)
+	}
+	if !strings.Contains(svg, "\nnoisy: CV above 10.0%</title>") {
+		t.Errorf("want high-CV cell title to explain de-emphasis, got:\n%s", svg)
+	}
+
+	// CV 1%.
+	svg = render([]float64{99, 100, 101}, 0.1)
+	if !strings.HasPrefix(svg, "  <g><title>") || strings.Contains(svg, "noisy") {
+		t.Errorf("want low-CV cell to render normally, got:\n%s", svg)
+	}
+
+	// A threshold of 0 disables de-emphasis.
+	svg = render([]float64{1, 2, 3}, 0)
+	if strings.Contains(svg, noisy) {
+		t.Errorf("want -max-cv 0 to render normally, got:\n%s", svg)
+	}
+}